@@ -29,7 +29,8 @@ MODEL_NAME = "product-detection-0001"
 oldFrameDict = {}
 
 
-def create_event_message(source, key, event_type, roi_name, frame_path):
+def create_event_message(source, key, event_type, roi_name, frame_path,
+                         detection=None):
     milliSinceEPOCH = int(round(time.time() * 1000))
     enter_exit_event = {}
     enter_exit_event["source"] = source
@@ -39,6 +40,9 @@ def create_event_message(source, key, event_type, roi_name, frame_path):
     enter_exit_event["roi_name"] = roi_name
     if frame_path:
         enter_exit_event["frame_path"] = frame_path
+    # the detection the object was seen with, for the reconciler to weigh it
+    if detection:
+        enter_exit_event.update(detection)
     return json.dumps(enter_exit_event)
 
 
@@ -54,6 +58,8 @@ def on_subscribe(client, userdata, message, qos):
 def on_message(client, userdata, message):
     print("Receiving message on topic")
     newFrameDict = {}
+    # most confident detection of each label in the frame
+    bestDetections = {}
     '''python_obj = json.loads(message.payload)
     resolution = python_obj["resolution"]
     height = resolution["height"]
@@ -97,6 +103,17 @@ def on_message(client, userdata, message):
             confidence = detection["confidence"]
             label = detection["product"]
 
+            if (label not in bestDetections or
+                    confidence > bestDetections[label]["confidence"]):
+                bestDetections[label] = {
+                    "class_label": label,
+                    "confidence": confidence,
+                    "bounding_box": {"x_min": x_min, "y_min": y_min,
+                                     "x_max": x_max, "y_max": y_max}
+                }
+                if isinstance(frame_id, int):
+                    bestDetections[label]["frame_id"] = frame_id
+
             # For each frame, add the label or increment in dict if seen
             if label in newFrameDict:
                 newFrameDict[label] = newFrameDict[label] + 1
@@ -123,7 +140,7 @@ def on_message(client, userdata, message):
                             )):
                         mqtt_msg = create_event_message(
                             source, key, EDGEX_ENTER_EVENT, roi_name,
-                            frame_path)
+                            frame_path, bestDetections.get(key))
                         client.publish(MQTT_OUTBOUND_TOPIC_NAME, mqtt_msg)
                 elif (newFrameDict[key] < oldFrameDict[roi_name][key]):
                     for i in range(
@@ -140,7 +157,8 @@ def on_message(client, userdata, message):
                 # new enter since it was not in the prev frame
                 for i in range(0, newFrameDict[key]):
                     mqtt_msg = create_event_message(
-                        source, key, EDGEX_ENTER_EVENT, roi_name, frame_path)
+                        source, key, EDGEX_ENTER_EVENT, roi_name, frame_path,
+                        bestDetections.get(key))
                     client.publish(MQTT_OUTBOUND_TOPIC_NAME, mqtt_msg)
 
     # Lastly, in case of an object type is completely removed from frame,
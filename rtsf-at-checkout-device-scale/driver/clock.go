@@ -0,0 +1,24 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package driver
+
+import (
+	"time"
+)
+
+// Clock tells the time the scale readings are stamped and measured with
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// toEventTime converts the time to the event_time of a reading, in milliseconds since the epoch
+func toEventTime(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
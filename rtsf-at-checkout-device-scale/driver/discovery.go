@@ -0,0 +1,276 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package driver
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"device-scale/scale"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v3/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
+	"go.bug.st/serial.v1/enumerator"
+)
+
+const (
+	defaultKnownScales     = "0403:6001:cas-scale"
+	defaultHotPlugInterval = 5 * time.Second
+)
+
+// getPortsList enumerates the serial ports, replaced in tests as serial devices can not be mocked
+var getPortsList = enumerator.GetDetailedPortsList
+
+// parseKnownScales parses the KnownScales driver config, a comma separated list of VID:PID:Model entries,
+// into a map of scale models keyed by VID:PID
+func parseKnownScales(knownScales string) (map[string]string, error) {
+	if len(strings.TrimSpace(knownScales)) == 0 {
+		knownScales = defaultKnownScales
+	}
+
+	scales := make(map[string]string)
+	for _, entry := range strings.Split(knownScales, ",") {
+		fields := strings.Split(strings.TrimSpace(entry), ":")
+		if len(fields) != 3 || len(fields[0]) == 0 || len(fields[1]) == 0 || len(fields[2]) == 0 {
+			return nil, fmt.Errorf("known scale entry %q is not in VID:PID:Model format", entry)
+		}
+		if err := scale.CheckModel(fields[2]); err != nil {
+			return nil, fmt.Errorf("known scale entry %q: %v", entry, err)
+		}
+		scales[scaleKey(fields[0], fields[1])] = fields[2]
+	}
+	return scales, nil
+}
+
+func scaleKey(vid string, pid string) string {
+	return strings.ToUpper(vid) + ":" + strings.ToUpper(pid)
+}
+
+// provisionWatchers is the part of the SDK managing the provision watchers, replaced in tests
+type provisionWatchers interface {
+	ProvisionWatchers() []models.ProvisionWatcher
+	AddProvisionWatcher(watcher models.ProvisionWatcher) (string, error)
+}
+
+// scaleProvisionWatcher builds the provision watcher of a known scale, the scales discovered with its
+// VID:PID are added with the device profile named after their model
+func scaleProvisionWatcher(vid string, pid string, model string) models.ProvisionWatcher {
+	return models.ProvisionWatcher{
+		Name:   fmt.Sprintf("%s-%s-%s-provision-watcher", model, vid, pid),
+		Labels: []string{"scale", "serial", model},
+		// the case of the hexadecimal VID and PID reported for the serial ports depends on the platform
+		Identifiers: map[string]string{
+			"VID": "(?i)^" + vid + "$",
+			"PID": "(?i)^" + pid + "$",
+		},
+		AdminState: models.Unlocked,
+		DiscoveredDevice: models.DiscoveredDevice{
+			ProfileName: model,
+			AdminState:  models.Unlocked,
+			AutoEvents: []models.AutoEvent{
+				{Interval: "1s", OnChange: true, SourceName: "weight"},
+				{Interval: "30s", OnChange: false, SourceName: "scale-health"},
+			},
+		},
+	}
+}
+
+// addProvisionWatchers adds a provision watcher for every known scale that is not watched yet, so that
+// each scale listed in KnownScales is added once discovered
+func (drv *ScaleDriver) addProvisionWatchers() {
+	knownScales, err := parseKnownScales(drv.config["KnownScales"])
+	if err != nil {
+		drv.lc.Errorf("unable to add the scale provision watchers: %v", err)
+		return
+	}
+
+	keys := make([]string, 0, len(knownScales))
+	for key := range knownScales {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	existing := drv.watchers.ProvisionWatchers()
+	for _, key := range keys {
+		ids := strings.Split(key, ":")
+		watcher := scaleProvisionWatcher(ids[0], ids[1], knownScales[key])
+		if isWatchedScale(existing, watcher.Name, ids[0], ids[1]) {
+			continue
+		}
+		if _, err := drv.watchers.AddProvisionWatcher(watcher); err != nil {
+			drv.lc.Warnf("unable to add provision watcher %s: %v", watcher.Name, err)
+			continue
+		}
+		drv.lc.Infof("Added provision watcher %s for the %s scales %s", watcher.Name, knownScales[key], key)
+	}
+}
+
+// isWatchedScale checks if one of the provision watchers is the one of the scale or matches its VID:PID
+func isWatchedScale(watchers []models.ProvisionWatcher, name string, vid string, pid string) bool {
+	for _, watcher := range watchers {
+		if watcher.Name == name ||
+			(strings.EqualFold(watcher.Identifiers["VID"], vid) && strings.EqualFold(watcher.Identifiers["PID"], pid)) {
+			return true
+		}
+	}
+	return false
+}
+
+// discoveredScaleName builds a stable device name so the same scale is not proposed twice
+func discoveredScaleName(port *enumerator.PortDetails) string {
+	id := port.SerialNumber
+	if len(id) == 0 {
+		id = filepath.Base(port.Name)
+	}
+	return fmt.Sprintf("scale-%s-%s-%s", port.VID, port.PID, id)
+}
+
+// probeScale checks that a scale of the model is answering on the serial port using the protocol handshake
+func (drv *ScaleDriver) probeScale(serialPort string, model string) error {
+	device := newScaleDevice(serialPort, model, drv.lc, drv.config)
+	if device == nil {
+		return fmt.Errorf("unable to create scale device for %s", serialPort)
+	}
+	_, err := device.sendCommand(scale.CommandDiagnostics)
+	return err
+}
+
+// isRegisteredScale checks if the USB port belongs to a scale already managed by the driver
+func (drv *ScaleDriver) isRegisteredScale(port *enumerator.PortDetails) bool {
+	drv.mu.RLock()
+	defer drv.mu.RUnlock()
+
+	for _, device := range drv.scaleDevices {
+		if device.connected && device.serialPort == port.Name {
+			return true
+		}
+		if len(device.serialNumber) > 0 && device.serialNumber == port.SerialNumber &&
+			scaleKey(device.vid, device.pid) == scaleKey(port.VID, port.PID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Discover is a callback function that is invoked by the SDK when discovery is triggered.
+// USB serial ports matching a known scale VID:PID are probed and the scales that answer
+// are proposed to the SDK, which adds them according to the provision watchers
+func (drv *ScaleDriver) Discover() error {
+	knownScales, err := parseKnownScales(drv.config["KnownScales"])
+	if err != nil {
+		return err
+	}
+
+	ports, err := getPortsList()
+	if err != nil {
+		return fmt.Errorf("unable to list serial ports: %v", err)
+	}
+
+	discovered := []dsModels.DiscoveredDevice{}
+	for _, port := range ports {
+		if !port.IsUSB || drv.isRegisteredScale(port) {
+			continue
+		}
+
+		model, ok := knownScales[scaleKey(port.VID, port.PID)]
+		if !ok {
+			continue
+		}
+
+		if err := drv.probe(port.Name, model); err != nil {
+			drv.lc.Debugf("Serial port %s (%s:%s) did not answer the scale handshake: %v", port.Name, port.VID, port.PID, err)
+			continue
+		}
+
+		name := discoveredScaleName(port)
+		drv.lc.Infof("Discovered %s scale %s on %s", model, name, port.Name)
+		discovered = append(discovered, dsModels.DiscoveredDevice{
+			Name: name,
+			Protocols: map[string]models.ProtocolProperties{
+				"serial": {
+					"VID":          port.VID,
+					"PID":          port.PID,
+					"SerialNumber": port.SerialNumber,
+					"Model":        model,
+				},
+			},
+			Description: fmt.Sprintf("%s serial weight scale discovered on %s", model, port.Name),
+			Labels:      []string{"scale", "serial", model},
+		})
+	}
+
+	if drv.deviceCh != nil {
+		drv.deviceCh <- discovered
+	}
+	return nil
+}
+
+// monitorHotPlug periodically checks the serial ports so that unplugged scales are marked as
+// disconnected and reconnected scales are picked up again, even on a different port
+func (drv *ScaleDriver) monitorHotPlug(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			drv.checkHotPlug()
+		}
+	}
+}
+
+func (drv *ScaleDriver) checkHotPlug() {
+	ports, err := getPortsList()
+	if err != nil {
+		drv.lc.Warnf("unable to list serial ports: %v", err)
+		return
+	}
+
+	available := make(map[string]bool)
+	for _, port := range ports {
+		if port.IsUSB {
+			available[port.Name] = true
+		}
+	}
+
+	for _, device := range drv.listScaleDevices() {
+		if device.connected {
+			if !available[device.serialPort] {
+				drv.lc.Warnf("Scale %s was unplugged from %s", device.deviceName, device.serialPort)
+				disconnected := &scaleDevice{
+					deviceName:   device.deviceName,
+					vid:          device.vid,
+					pid:          device.pid,
+					model:        device.model,
+					serialNumber: device.serialNumber,
+					laneID:       device.laneID,
+					scaleID:      device.scaleID,
+					health:       device.health,
+				}
+				drv.replaceScaleDevice(device, disconnected)
+			}
+			continue
+		}
+
+		reconnected, err := drv.connectScaleDevice(device, ports)
+		if err != nil {
+			drv.lc.Debugf("Scale %s is still disconnected: %v", device.deviceName, err)
+			continue
+		}
+		drv.lc.Infof("Scale %s reconnected on %s", reconnected.deviceName, reconnected.serialPort)
+	}
+}
+
+func hotPlugInterval(config map[string]string) time.Duration {
+	interval, err := time.ParseDuration(config["HotPlugInterval"])
+	if err != nil || interval <= 0 {
+		return defaultHotPlugInterval
+	}
+	return interval
+}
@@ -0,0 +1,169 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package driver
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v3/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.bug.st/serial.v1/enumerator"
+)
+
+func setTestPortsList(t *testing.T, ports []*enumerator.PortDetails) {
+	original := getPortsList
+	getPortsList = func() ([]*enumerator.PortDetails, error) {
+		return ports, nil
+	}
+	t.Cleanup(func() {
+		getPortsList = original
+	})
+}
+
+func Test_parseKnownScales(t *testing.T) {
+	tests := []struct {
+		name        string
+		knownScales string
+		want        map[string]string
+		wantErr     bool
+	}{
+		{
+			name:        "default table",
+			knownScales: "",
+			want:        map[string]string{"0403:6001": "cas-scale"},
+		},
+		{
+			name:        "multiple scales",
+			knownScales: "0403:6001:cas-scale, 067b:2303:cas-scale",
+			want:        map[string]string{"0403:6001": "cas-scale", "067B:2303": "cas-scale"},
+		},
+		{
+			name:        "missing model",
+			knownScales: "0403:6001",
+			wantErr:     true,
+		},
+		{
+			name:        "unsupported model",
+			knownScales: "0403:6001:acme-scale",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKnownScales(tt.knownScales)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestScaleDriver_Discover(t *testing.T) {
+	setTestPortsList(t, []*enumerator.PortDetails{
+		{Name: "/dev/ttyUSB0", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A1"},
+		{Name: "/dev/ttyUSB1", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A2"},
+		{Name: "/dev/ttyUSB2", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A3"},
+		{Name: "/dev/ttyUSB3", IsUSB: true, VID: "1234", PID: "5678", SerialNumber: "B1"},
+		{Name: "/dev/ttyS0", IsUSB: false},
+	})
+
+	deviceCh := make(chan []dsModels.DiscoveredDevice, 1)
+	drv := getDefaultScaleDriver()
+	drv.deviceCh = deviceCh
+	drv.probe = func(serialPort string, model string) error {
+		if serialPort == "/dev/ttyUSB2" {
+			return errors.New("time out connecting to scale")
+		}
+		return nil
+	}
+	// scale on /dev/ttyUSB0 is already managed by the driver
+	registered := getTestScaleDevice("bagging-scale", nil)
+	registered.serialPort = "/dev/ttyUSB0"
+	drv.scaleDevices[registered.deviceName] = registered
+
+	require.NoError(t, drv.Discover())
+	require.Len(t, deviceCh, 1)
+	discovered := <-deviceCh
+	require.Len(t, discovered, 1)
+	assert.Equal(t, "scale-0403-6001-A2", discovered[0].Name)
+	assert.Equal(t, "A2", discovered[0].Protocols["serial"]["SerialNumber"])
+	assert.Equal(t, "cas-scale", discovered[0].Protocols["serial"]["Model"])
+}
+
+// fakeProvisionWatchers keeps the provision watchers added by the driver
+type fakeProvisionWatchers struct {
+	watchers []models.ProvisionWatcher
+}
+
+func (fake *fakeProvisionWatchers) ProvisionWatchers() []models.ProvisionWatcher {
+	return fake.watchers
+}
+
+func (fake *fakeProvisionWatchers) AddProvisionWatcher(watcher models.ProvisionWatcher) (string, error) {
+	fake.watchers = append(fake.watchers, watcher)
+	return watcher.Name, nil
+}
+
+func TestScaleDriver_addProvisionWatchers(t *testing.T) {
+	// the watcher of the default scale is loaded from the provision watchers directory
+	watchers := &fakeProvisionWatchers{watchers: []models.ProvisionWatcher{
+		{Name: "cas-scale-0403-6001-provision-watcher", Identifiers: map[string]string{"VID": "0403", "PID": "6001"}},
+	}}
+	drv := getDefaultScaleDriver()
+	drv.config["KnownScales"] = "0403:6001:cas-scale, 067b:2303:cas-scale"
+	drv.watchers = watchers
+
+	drv.addProvisionWatchers()
+	require.Len(t, watchers.watchers, 2)
+	added := watchers.watchers[1]
+	assert.Equal(t, "cas-scale-067B-2303-provision-watcher", added.Name)
+	assert.Equal(t, "cas-scale", added.DiscoveredDevice.ProfileName)
+	assert.Len(t, added.DiscoveredDevice.AutoEvents, 2)
+	for identifier, value := range map[string]string{"VID": "067b", "PID": "2303"} {
+		matched, err := regexp.MatchString(added.Identifiers[identifier], value)
+		require.NoError(t, err)
+		assert.True(t, matched, identifier)
+	}
+	matched, err := regexp.MatchString(added.Identifiers["VID"], "1067B")
+	require.NoError(t, err)
+	assert.False(t, matched)
+
+	// the watchers added on a previous start are kept
+	drv.addProvisionWatchers()
+	assert.Len(t, watchers.watchers, 2)
+}
+
+func TestScaleDriver_checkHotPlug(t *testing.T) {
+	drv := getDefaultScaleDriver()
+	device := getTestScaleDevice("bagging-scale", nil)
+	device.vid = "0403"
+	device.pid = "6001"
+	device.serialNumber = "A1"
+	device.serialPort = "/dev/ttyUSB0"
+	drv.scaleDevices[device.deviceName] = device
+
+	// scale unplugged
+	setTestPortsList(t, []*enumerator.PortDetails{})
+	drv.checkHotPlug()
+	_, ok := drv.getScaleDevice("bagging-scale")
+	assert.False(t, ok)
+
+	// scale plugged back in on a different port
+	setTestPortsList(t, []*enumerator.PortDetails{
+		{Name: "/dev/ttyUSB1", IsUSB: true, VID: "0403", PID: "6001", SerialNumber: "A1"},
+	})
+	drv.checkHotPlug()
+	reconnected, ok := drv.getScaleDevice("bagging-scale")
+	require.True(t, ok)
+	assert.Equal(t, "/dev/ttyUSB1", reconnected.serialPort)
+	assert.Equal(t, device.laneID, reconnected.laneID)
+	assert.Equal(t, device.scaleID, reconnected.scaleID)
+}
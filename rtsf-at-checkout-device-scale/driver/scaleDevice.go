@@ -5,6 +5,7 @@ package driver
 
 import (
 	"strconv"
+	"sync"
 
 	"device-scale/scale"
 
@@ -12,22 +13,44 @@ import (
 )
 
 type scaleDevice struct {
+	// mu serializes the requests to the scale, which share its serial port
+	mu           sync.Mutex
 	serialDevice scale.SerialDevice
+	deviceName   string
+	vid          string
+	pid          string
+	model        string
+	serialNumber string
+	serialPort   string
+	laneID       string
+	scaleID      string
+	connected    bool
+	health       *scaleHealth
 }
 
 // readWeight gets called by the auto event to read from the physical scale
-// the data read from the scale is wrapped and put on the bus
-func (device *scaleDevice) readWeight() (map[string]interface{}, error) {
+// the data read from the scale is wrapped and put on the bus, the clock times the read for the health metrics
+func (device *scaleDevice) readWeight(clock Clock) (map[string]interface{}, error) {
+	device.mu.Lock()
+	defer device.mu.Unlock()
 
 	scaleReading := make(chan scale.Reading)
 	readingErr := make(chan error)
 
+	start := clock.Now()
 	scale.GetScaleReading(device.serialDevice, scaleReading, readingErr)
 
 	select {
 	case err := <-readingErr:
+		if device.health != nil {
+			device.health.recordError(err, clock.Now().Sub(start))
+		}
 		return nil, err
 	case reading := <-scaleReading:
+		if device.health != nil {
+			now := clock.Now()
+			device.health.recordReading(reading, now.Sub(start), now)
+		}
 
 		if reading.Status != "OK" {
 			return nil, nil
@@ -46,7 +69,29 @@ func (device *scaleDevice) readWeight() (map[string]interface{}, error) {
 	}
 }
 
-func newScaleDevice(serialPort string, lc logger.LoggingClient, config map[string]string) *scaleDevice {
+// sendCommand sends a write command to the physical scale
+// the status the scale responds with is wrapped and put on the bus
+func (device *scaleDevice) sendCommand(command scale.Command) (map[string]interface{}, error) {
+	device.mu.Lock()
+	defer device.mu.Unlock()
+
+	commandReading := make(chan scale.Reading)
+	commandErr := make(chan error)
+
+	scale.SendCommand(device.serialDevice, command, commandReading, commandErr)
+
+	select {
+	case err := <-commandErr:
+		return nil, err
+	case reading := <-commandReading:
+		commandData := make(map[string]interface{})
+		commandData["command"] = string(command)
+		commandData["status"] = reading.Status
+		return commandData, nil
+	}
+}
+
+func newScaleDevice(serialPort string, model string, lc logger.LoggingClient, config map[string]string) *scaleDevice {
 
 	lc.Debug("Creating new scale device")
 	if config == nil {
@@ -69,7 +114,13 @@ func newScaleDevice(serialPort string, lc logger.LoggingClient, config map[strin
 		MinimumReadSize: 1,
 		ParityMode:      2,
 		TimeOutMilli:    timeout,
+		Model:           model,
 	}
 
-	return &scaleDevice{serialDevice: scale.NewScale(options)}
+	serialDevice, err := scale.NewScale(options)
+	if err != nil {
+		lc.Error(err.Error())
+		return nil
+	}
+	return &scaleDevice{serialDevice: serialDevice, model: model}
 }
@@ -56,7 +56,7 @@ func Test_scaleDevice_readWeight(t *testing.T) {
 			device := &scaleDevice{
 				serialDevice: testDevice,
 			}
-			got, err := device.readWeight()
+			got, err := device.readWeight(testClock{now: testNow})
 			if tt.wantErr {
 				require.NoError(t, err)
 				return
@@ -70,21 +70,31 @@ func Test_scaleDevice_readWeight(t *testing.T) {
 func Test_newScaleDevice(t *testing.T) {
 	tests := []struct {
 		name    string
+		model   string
 		config  map[string]string
 		isEmpty bool
 	}{
 		{
 			name:    "valid case",
+			model:   scale.DefaultModel,
 			config:  getDefaultDriverConfig(),
 			isEmpty: false,
 		},
 		{
 			name:    "nil config",
+			model:   scale.DefaultModel,
 			config:  nil,
 			isEmpty: true,
 		},
 		{
-			name: "missing TimeOutMilli from config",
+			name:    "unsupported model",
+			model:   "acme-scale",
+			config:  getDefaultDriverConfig(),
+			isEmpty: true,
+		},
+		{
+			name:  "missing TimeOutMilli from config",
+			model: scale.DefaultModel,
 			config: map[string]string{
 				"SimulatorPort": "8081",
 				"ScaleID":       "123",
@@ -95,7 +105,7 @@ func Test_newScaleDevice(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := newScaleDevice("testSerialPort", logger.NewMockClient(), tt.config)
+			got := newScaleDevice("testSerialPort", tt.model, logger.NewMockClient(), tt.config)
 
 			if tt.isEmpty {
 				require.Empty(t, got)
@@ -105,3 +115,31 @@ func Test_newScaleDevice(t *testing.T) {
 		})
 	}
 }
+
+func Test_scaleDevice_concurrentRequests(t *testing.T) {
+
+	config := scale.Config{
+		PortName:     "/dev/tty.usbserial-test",
+		TimeOutMilli: 500,
+	}
+
+	testDevice := scale.InitializeMockDevice(&config)
+	device := &scaleDevice{serialDevice: testDevice}
+
+	// the auto event reads the weight while a command is sent to the same scale
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_, err := device.sendCommand(scale.CommandTare)
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_, err := device.readWeight(testClock{now: testNow})
+		assert.NoError(t, err)
+	}
+	<-done
+
+	assert.Zero(t, testDevice.OverlappingOpens)
+}
@@ -9,7 +9,9 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
+	"sync"
+
+	"device-scale/scale"
 
 	"github.com/edgexfoundry/device-sdk-go/v3/pkg/interfaces"
 	dsModels "github.com/edgexfoundry/device-sdk-go/v3/pkg/models"
@@ -20,14 +22,31 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
 )
 
+const (
+	commandResultResource = "command-result"
+)
+
+// commandFailureStatus the statuses a scale responds with when it was not able to execute a command
+var commandFailureStatus = map[string]bool{
+	"Motion":         true,
+	"Under Capacity": true,
+	"Over Capacity":  true,
+	"N/A":            true,
+}
+
 // ScaleDriver the driver for a collection of scales
 type ScaleDriver struct {
-	lc             logger.LoggingClient
-	asyncCh        chan<- *dsModels.AsyncValues
-	scaleDevice    *scaleDevice
-	httpErrors     chan error
-	scaleConnected bool
-	config         map[string]string
+	lc           logger.LoggingClient
+	asyncCh      chan<- *dsModels.AsyncValues
+	deviceCh     chan<- []dsModels.DiscoveredDevice
+	scaleDevices map[string]*scaleDevice
+	mu           sync.RWMutex
+	httpErrors   chan error
+	config       map[string]string
+	probe        func(serialPort string, model string) error
+	stop         chan struct{}
+	clock        Clock
+	watchers     provisionWatchers
 }
 
 // NewScaleDeviceDriver instantiates a scale driver
@@ -45,22 +64,36 @@ func (drv *ScaleDriver) Initialize(sdk interfaces.DeviceServiceSDK) error {
 
 	drv.lc = sdk.LoggingClient()
 	drv.asyncCh = sdk.AsyncValuesChannel()
+	drv.deviceCh = sdk.DiscoveredDeviceChannel()
 	drv.httpErrors = make(chan error, 2)
 	drv.config = sdk.DriverConfigs()
+	drv.scaleDevices = make(map[string]*scaleDevice)
+	drv.probe = drv.probeScale
+	drv.stop = make(chan struct{})
+	drv.clock = systemClock{}
+	drv.watchers = sdk
+
+	if _, err := parseKnownScales(drv.config["KnownScales"]); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func (drv *ScaleDriver) processScaleData(scaleData map[string]interface{}, deviceResName string) (*dsModels.CommandValue, error) {
+func (drv *ScaleDriver) processScaleData(device *scaleDevice, scaleData map[string]interface{}, deviceResName string) (*dsModels.CommandValue, error) {
+	if device == nil {
+		return nil, errors.New("device can not be nil")
+	}
 	if len(scaleData) == 0 {
 		return nil, errors.New("scaleData can not be nil")
 	}
 	if len(deviceResName) == 0 {
 		return nil, errors.New("deviceResName can not be empty")
 	}
-	scaleData["lane_id"] = drv.config["LaneID"]
-	scaleData["scale_id"] = drv.config["ScaleID"]
-	scaleData["event_time"] = (time.Now().UnixNano() / 1000000)
+	scaleData["lane_id"] = device.laneID
+	scaleData["scale_id"] = device.scaleID
+	now := drv.clock.Now()
+	scaleData["event_time"] = toEventTime(now)
 
 	scaleBytes, err := json.Marshal(scaleData)
 	if err != nil {
@@ -71,7 +104,7 @@ func (drv *ScaleDriver) processScaleData(scaleData map[string]interface{}, devic
 		deviceResName,
 		edgexcommon.ValueTypeString,
 		string(scaleBytes),
-		time.Now().UnixNano()/int64(time.Millisecond),
+		toEventTime(now),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error on NewCommandValueWithOrigin for %v: %v", deviceResName, err)
@@ -85,15 +118,25 @@ func (drv *ScaleDriver) HandleReadCommands(deviceName string, protocols map[stri
 
 	res = make([]*dsModels.CommandValue, len(reqs))
 
-	if !drv.scaleConnected {
-		// we need to return nil when scale is not connected for simulator purpose
-		// if physical device is not connected, the error will trigger in AddDevice
-		drv.lc.Warnf("scale is not connected")
-		return nil, nil
-	}
-
 	for i, req := range reqs {
-		scaleData, err := drv.scaleDevice.readWeight()
+		if req.DeviceResourceName == healthResource {
+			result, err := drv.readScaleHealth(deviceName)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = result
+			continue
+		}
+
+		device, ok := drv.getScaleDevice(deviceName)
+		if !ok {
+			// we need to return nil when scale is not connected for simulator purpose
+			// if physical device is not connected, the error will trigger in AddDevice
+			drv.lc.Warnf("scale %s is not connected", deviceName)
+			return nil, nil
+		}
+
+		scaleData, err := device.readWeight(drv.clock)
 		if err != nil {
 			if strings.Contains(err.Error(), "no such file or directory") {
 				// scale is unplugged or unreachable
@@ -108,7 +151,7 @@ func (drv *ScaleDriver) HandleReadCommands(deviceName string, protocols map[stri
 			return nil, nil
 		}
 
-		result, err := drv.processScaleData(scaleData, req.DeviceResourceName)
+		result, err := drv.processScaleData(device, scaleData, req.DeviceResourceName)
 		if err != nil {
 			return nil, err
 		}
@@ -120,67 +163,264 @@ func (drv *ScaleDriver) HandleReadCommands(deviceName string, protocols map[stri
 	return res, nil
 }
 
-// HandleWriteCommands handle incoming write commands
+// readScaleHealth returns the health metrics of the scale, which are also reported while the scale is disconnected
+func (drv *ScaleDriver) readScaleHealth(deviceName string) (*dsModels.CommandValue, error) {
+	drv.mu.RLock()
+	device, ok := drv.scaleDevices[deviceName]
+	drv.mu.RUnlock()
+	if !ok || device.health == nil {
+		return nil, fmt.Errorf("scale %s is not managed by the device service", deviceName)
+	}
+
+	healthData := device.health.snapshot(drv.clock.Now())
+	healthData["connected"] = device.connected
+	if !device.connected {
+		healthData["healthy"] = false
+		healthData["degradation_reasons"] = append(healthData["degradation_reasons"].([]string), "disconnected")
+	}
+
+	return drv.processScaleData(device, healthData, healthResource)
+}
+
+// HandleWriteCommands handle incoming write commands such as zero, tare, clear-tare and diagnostics
+// the result of each command is put on the bus, failures are also returned as the command response
 func (drv *ScaleDriver) HandleWriteCommands(deviceName string, protocols map[string]models.ProtocolProperties, reqs []dsModels.CommandRequest, params []*dsModels.CommandValue) error {
+
+	device, ok := drv.getScaleDevice(deviceName)
+	if !ok {
+		return fmt.Errorf("scale %s is not connected", deviceName)
+	}
+
+	var failedCommands []string
+	for i, req := range reqs {
+		if i < len(params) && params[i] != nil {
+			// a command set to false is not sent to the scale
+			if enabled, err := params[i].BoolValue(); err == nil && !enabled {
+				continue
+			}
+		}
+
+		command := scale.Command(req.DeviceResourceName)
+		commandData, err := device.sendCommand(command)
+		if err == nil {
+			err = checkCommandStatus(command, commandData)
+		}
+
+		if err != nil {
+			drv.lc.Errorf("Scale command %s failed: %v", command, err)
+			if commandData == nil {
+				commandData = map[string]interface{}{"command": string(command)}
+			}
+			commandData["success"] = false
+			commandData["error"] = err.Error()
+			failedCommands = append(failedCommands, fmt.Sprintf("%s: %v", command, err))
+		} else {
+			drv.lc.Infof("Scale command %s completed with status %v", command, commandData["status"])
+			commandData["success"] = true
+		}
+
+		drv.publishCommandResult(device, commandData)
+	}
+
+	if len(failedCommands) > 0 {
+		return fmt.Errorf("scale command failed: %s", strings.Join(failedCommands, ", "))
+	}
+
 	return nil
 }
 
+// checkCommandStatus verifies the scale was able to execute the command, diagnostics always succeed as the status is the result
+func checkCommandStatus(command scale.Command, commandData map[string]interface{}) error {
+	if command == scale.CommandDiagnostics {
+		return nil
+	}
+
+	status, _ := commandData["status"].(string)
+	if commandFailureStatus[status] {
+		return fmt.Errorf("scale responded with status %s", status)
+	}
+	return nil
+}
+
+// publishCommandResult sends the result of a write command as an event
+func (drv *ScaleDriver) publishCommandResult(device *scaleDevice, commandData map[string]interface{}) {
+	result, err := drv.processScaleData(device, commandData, commandResultResource)
+	if err != nil {
+		drv.lc.Errorf("failed to create command result: %v", err)
+		return
+	}
+
+	drv.asyncCh <- &dsModels.AsyncValues{
+		DeviceName:    device.deviceName,
+		SourceName:    commandResultResource,
+		CommandValues: []*dsModels.CommandValue{result},
+	}
+}
+
 // Stop stop a device
 func (drv *ScaleDriver) Stop(force bool) error {
+	if drv.stop != nil {
+		close(drv.stop)
+		drv.stop = nil
+	}
 	return nil
 }
 
 // Start starts the driver logic
 func (drv *ScaleDriver) Start() error {
+	if drv.watchers != nil {
+		drv.addProvisionWatchers()
+	}
+	if drv.stop != nil {
+		go drv.monitorHotPlug(hotPlugInterval(drv.config), drv.stop)
+	}
 	return nil
 }
 
-func findSerialPort(ports []*enumerator.PortDetails, pid string, vid string) (string, error) {
+// getScaleDevice returns the connected scale for the EdgeX device name
+func (drv *ScaleDriver) getScaleDevice(deviceName string) (*scaleDevice, bool) {
+	drv.mu.RLock()
+	defer drv.mu.RUnlock()
+
+	device, ok := drv.scaleDevices[deviceName]
+	if !ok || !device.connected {
+		return nil, false
+	}
+	return device, true
+}
+
+// listScaleDevices returns all the scales managed by the driver, connected or not
+func (drv *ScaleDriver) listScaleDevices() []*scaleDevice {
+	drv.mu.RLock()
+	defer drv.mu.RUnlock()
+
+	devices := make([]*scaleDevice, 0, len(drv.scaleDevices))
+	for _, device := range drv.scaleDevices {
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// replaceScaleDevice swaps the scale with its new connection state, unless it was removed or updated in the meantime
+func (drv *ScaleDriver) replaceScaleDevice(previous *scaleDevice, device *scaleDevice) bool {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
+
+	if drv.scaleDevices[device.deviceName] != previous {
+		return false
+	}
+	drv.scaleDevices[device.deviceName] = device
+	return true
+}
+
+// claimedSerialPorts returns the serial ports in use by connected scales other than the given device
+func (drv *ScaleDriver) claimedSerialPorts(deviceName string) map[string]bool {
+	drv.mu.RLock()
+	defer drv.mu.RUnlock()
+
+	claimed := make(map[string]bool)
+	for name, device := range drv.scaleDevices {
+		if name != deviceName && device.connected {
+			claimed[device.serialPort] = true
+		}
+	}
+	return claimed
+}
+
+// connectScaleDevice finds the serial port of the scale and replaces it with a connected scale
+func (drv *ScaleDriver) connectScaleDevice(device *scaleDevice, ports []*enumerator.PortDetails) (*scaleDevice, error) {
+	serialPort, err := findSerialPort(ports, device.pid, device.vid, device.serialNumber, drv.claimedSerialPorts(device.deviceName))
+	if err != nil {
+		return nil, err
+	}
+
+	connected := newScaleDevice(serialPort, device.model, drv.lc, drv.config)
+	if connected == nil {
+		return nil, fmt.Errorf("unable to create scale device %s", device.deviceName)
+	}
+	connected.deviceName = device.deviceName
+	connected.vid = device.vid
+	connected.pid = device.pid
+	connected.serialNumber = device.serialNumber
+	connected.laneID = device.laneID
+	connected.scaleID = device.scaleID
+	connected.health = device.health
+	connected.serialPort = serialPort
+	connected.connected = true
+
+	if !drv.replaceScaleDevice(device, connected) {
+		return nil, fmt.Errorf("scale %s was changed while connecting", device.deviceName)
+	}
+	return connected, nil
+}
+
+// findSerialPort returns the first USB serial port matching the pid:vid, and the serial number when given, that is not already claimed
+func findSerialPort(ports []*enumerator.PortDetails, pid string, vid string, serialNumber string, claimed map[string]bool) (string, error) {
 
 	for _, port := range ports {
 
-		if port.IsUSB {
-			if port.PID == pid && port.VID == vid {
+		if port.IsUSB && !claimed[port.Name] {
+			if port.PID == pid && port.VID == vid && (len(serialNumber) == 0 || port.SerialNumber == serialNumber) {
 				return port.Name, nil
 			}
 		}
 	}
+	if len(serialNumber) > 0 {
+		return "", fmt.Errorf("serial device with pid:vid %s:%s and serial number %s not found", pid, vid, serialNumber)
+	}
 	return "", fmt.Errorf("serial device with pid:vid %s:%s not found", pid, vid)
 }
 
+// getProtocolProperty returns the optional string property from the serial protocol, or the fallback when it is not set
+func getProtocolProperty(serialProtocol models.ProtocolProperties, key string, fallback string) string {
+	value, ok := serialProtocol[key].(string)
+	if !ok || len(value) == 0 {
+		return fallback
+	}
+	return value
+}
+
 // AddDevice is a callback function that is invoked
 // when a new Device associated with this Device Service is added
 func (drv *ScaleDriver) AddDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
 	// Previously validated by ValidateDevice implicitly called by SDK
 
 	serialProtocol := protocols["serial"]
-	pid := serialProtocol["PID"].(string)
-	vid := serialProtocol["VID"].(string)
+	device := &scaleDevice{
+		deviceName:   deviceName,
+		vid:          serialProtocol["VID"].(string),
+		pid:          serialProtocol["PID"].(string),
+		model:        getProtocolProperty(serialProtocol, "Model", scale.DefaultModel),
+		serialNumber: getProtocolProperty(serialProtocol, "SerialNumber", ""),
+		laneID:       getProtocolProperty(serialProtocol, "LaneID", drv.config["LaneID"]),
+		scaleID:      getProtocolProperty(serialProtocol, "ScaleID", drv.config["ScaleID"]),
+		health:       newScaleHealth(drv.config),
+	}
+
+	// the scale is kept as disconnected until it is found, so it is connected once plugged in
+	drv.mu.Lock()
+	drv.scaleDevices[deviceName] = device
+	drv.mu.Unlock()
 
-	ports, err := enumerator.GetDetailedPortsList()
+	ports, err := getPortsList()
 	if err != nil {
 		return err
 	}
 
-	serialPort, err := findSerialPort(ports, pid, vid)
-
+	connected, err := drv.connectScaleDevice(device, ports)
 	if err != nil {
 		drv.lc.Error(err.Error())
-		drv.scaleConnected = false
 		return fmt.Errorf("unable to find weight scale serial port: %v", err)
-	} else {
-		drv.lc.Debugf("[serialPort]: %v", serialPort)
-		drv.scaleConnected = true
-		drv.scaleDevice = newScaleDevice(serialPort, drv.lc, drv.config)
-		drv.lc.Debugf("Connecting to scale: %v", serialPort)
+	}
 
-		scaleData, err := drv.scaleDevice.readWeight()
-		if err != nil {
-			return fmt.Errorf("readWeight failed: %v", err)
-		}
-		for _, v := range scaleData {
-			drv.lc.Debugf("[scaleData]: %v", v)
-		}
+	drv.lc.Debugf("Connecting to scale %s (lane %s, scale %s): %v", deviceName, connected.laneID, connected.scaleID, connected.serialPort)
+
+	scaleData, err := connected.readWeight(drv.clock)
+	if err != nil {
+		return fmt.Errorf("readWeight failed: %v", err)
+	}
+	for _, v := range scaleData {
+		drv.lc.Debugf("[scaleData]: %v", v)
 	}
 
 	return nil
@@ -189,24 +429,26 @@ func (drv *ScaleDriver) AddDevice(deviceName string, protocols map[string]models
 // UpdateDevice is a callback function that is invoked
 // when a Device associated with this Device Service is updated
 func (drv *ScaleDriver) UpdateDevice(deviceName string, protocols map[string]models.ProtocolProperties, adminState models.AdminState) error {
-	// Nothing required to do for UpdateDevice since device update will be available
-	// when data is posted to REST endpoint.
-	return nil
+	// the serial port, lane id or scale id may have changed, so the scale is reconnected
+	drv.removeScaleDevice(deviceName)
+	return drv.AddDevice(deviceName, protocols, adminState)
 }
 
 // RemoveDevice is a callback function that is invoked
 // when a Device associated with this Device Service is removed
 func (drv *ScaleDriver) RemoveDevice(deviceName string, protocols map[string]models.ProtocolProperties) error {
-	// Nothing required to do for RemoveDevice since removed device will no longer be available
-	// when data is posted to REST endpoint.
+	drv.removeScaleDevice(deviceName)
 	return nil
 }
 
-// Discover is a callback function that is invoked
-// by the SDK but should never be called
-func (drv *ScaleDriver) Discover() error {
+func (drv *ScaleDriver) removeScaleDevice(deviceName string) {
+	drv.mu.Lock()
+	defer drv.mu.Unlock()
 
-	return errors.New("discovery not implemented")
+	if _, ok := drv.scaleDevices[deviceName]; ok {
+		drv.lc.Debugf("Disconnecting scale %s", deviceName)
+		delete(drv.scaleDevices, deviceName)
+	}
 }
 
 // Validate is a callback function that is invoked by the SDK
@@ -241,5 +483,15 @@ func (drv *ScaleDriver) ValidateDevice(device models.Device) error {
 	if len(pid) == 0 {
 		return errors.New("PID is empty")
 	}
-	return nil
+
+	for _, key := range []string{"SerialNumber", "LaneID", "ScaleID", "Model"} {
+		if value, ok := serial[key]; ok {
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("%s value is not a string", key)
+			}
+		}
+	}
+
+	// the command table of the scale is selected from its model
+	return scale.CheckModel(getProtocolProperty(serial, "Model", scale.DefaultModel))
 }
@@ -6,10 +6,13 @@ package driver
 
 import (
 	"device-scale/scale"
+	"strconv"
 	"testing"
+	"time"
 
 	dsModels "github.com/edgexfoundry/device-sdk-go/v3/pkg/models"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	edgexcommon "github.com/edgexfoundry/go-mod-core-contracts/v3/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,14 +28,36 @@ func getDefaultDriverConfig() map[string]string {
 	return config
 }
 
-func getDefaultScaleDriver() ScaleDriver {
-	return ScaleDriver{
-		lc:             logger.NewMockClient(),
-		asyncCh:        make(chan<- *dsModels.AsyncValues, 16),
-		scaleDevice:    nil,
-		httpErrors:     nil,
-		scaleConnected: true,
-		config:         getDefaultDriverConfig(),
+// testClock stops the time at the same instant for every test
+type testClock struct {
+	now time.Time
+}
+
+func (clock testClock) Now() time.Time {
+	return clock.now
+}
+
+var testNow = time.Unix(1700000000, 0)
+
+func getDefaultScaleDriver() *ScaleDriver {
+	return &ScaleDriver{
+		lc:           logger.NewMockClient(),
+		asyncCh:      make(chan<- *dsModels.AsyncValues, 16),
+		scaleDevices: make(map[string]*scaleDevice),
+		httpErrors:   nil,
+		config:       getDefaultDriverConfig(),
+		clock:        testClock{now: testNow},
+	}
+}
+
+func getTestScaleDevice(deviceName string, serialDevice scale.SerialDevice) *scaleDevice {
+	return &scaleDevice{
+		serialDevice: serialDevice,
+		deviceName:   deviceName,
+		serialPort:   "/dev/tty.usbserial-test",
+		laneID:       "123",
+		scaleID:      "123",
+		connected:    true,
 	}
 }
 
@@ -74,14 +99,15 @@ func TestScaleDriver_processScaleData(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			drv := getDefaultScaleDriver()
-			got, err := drv.processScaleData(tt.args.scaleData, tt.args.deviceResName)
+			got, err := drv.processScaleData(getTestScaleDevice("testDeviceName", nil), tt.args.scaleData, tt.args.deviceResName)
 			if tt.wantErr {
 				require.Error(t, err)
 				return
 			}
 			require.NoError(t, err)
 			require.NotEmpty(t, got)
-
+			assert.Equal(t, testNow.UnixNano()/int64(time.Millisecond), got.Origin)
+			assert.Contains(t, got.ValueToString(), `"event_time":1700000000000`)
 		})
 	}
 }
@@ -119,9 +145,8 @@ func TestScaleDriver_HandleReadCommands(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			drv := getDefaultScaleDriver()
-			drv.scaleConnected = tt.scaleConnected
-			drv.scaleDevice = &scaleDevice{
-				serialDevice: testDevice,
+			if tt.scaleConnected {
+				drv.scaleDevices["testDeviceName"] = getTestScaleDevice("testDeviceName", testDevice)
 			}
 
 			gotRes, err := drv.HandleReadCommands("testDeviceName",
@@ -142,13 +167,132 @@ func TestScaleDriver_HandleReadCommands(t *testing.T) {
 	}
 }
 
+func TestScaleDriver_HandleWriteCommands(t *testing.T) {
+
+	config := scale.Config{
+		PortName:        "/dev/tty.usbserial-test",
+		BaudRate:        9600,
+		DataBits:        7,
+		StopBits:        1,
+		MinimumReadSize: 1,
+		ParityMode:      2,
+		TimeOutMilli:    500,
+	}
+
+	testDevice := scale.InitializeMockDevice(&config)
+
+	tests := []struct {
+		name           string
+		scaleConnected bool
+		testCaseIndex  int
+		resourceName   string
+		enabled        bool
+		wantResult     bool
+		expectedError  string
+	}{
+		{
+			name:           "zero succeeds",
+			scaleConnected: true,
+			testCaseIndex:  1,
+			resourceName:   "zero",
+			enabled:        true,
+			wantResult:     true,
+		},
+		{
+			name:           "tare fails on over capacity",
+			scaleConnected: true,
+			testCaseIndex:  3,
+			resourceName:   "tare",
+			enabled:        true,
+			wantResult:     true,
+			expectedError:  "scale command failed: tare: scale responded with status Over Capacity",
+		},
+		{
+			name:           "diagnostics reports over capacity",
+			scaleConnected: true,
+			testCaseIndex:  3,
+			resourceName:   "diagnostics",
+			enabled:        true,
+			wantResult:     true,
+		},
+		{
+			name:           "unsupported command",
+			scaleConnected: true,
+			testCaseIndex:  0,
+			resourceName:   "calibrate",
+			enabled:        true,
+			wantResult:     true,
+			expectedError:  "scale command failed: calibrate: command calibrate is not supported by the scale",
+		},
+		{
+			name:           "command disabled",
+			scaleConnected: true,
+			testCaseIndex:  0,
+			resourceName:   "clear-tare",
+			enabled:        false,
+			wantResult:     false,
+		},
+		{
+			name:           "scale not connected",
+			scaleConnected: false,
+			resourceName:   "zero",
+			enabled:        true,
+			wantResult:     false,
+			expectedError:  "scale testDeviceName is not connected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asyncCh := make(chan *dsModels.AsyncValues, 16)
+			drv := getDefaultScaleDriver()
+			drv.asyncCh = asyncCh
+			testDevice.TestCase = tt.testCaseIndex
+			if tt.scaleConnected {
+				drv.scaleDevices["testDeviceName"] = getTestScaleDevice("testDeviceName", testDevice)
+			}
+
+			param, err := dsModels.NewCommandValue(tt.resourceName, edgexcommon.ValueTypeBool, tt.enabled)
+			require.NoError(t, err)
+
+			err = drv.HandleWriteCommands("testDeviceName",
+				map[string]models.ProtocolProperties{},
+				[]dsModels.CommandRequest{
+					{
+						DeviceResourceName: tt.resourceName,
+					},
+				},
+				[]*dsModels.CommandValue{param},
+			)
+			if len(tt.expectedError) > 0 {
+				require.Error(t, err)
+				assert.Equal(t, tt.expectedError, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			if !tt.wantResult {
+				assert.Empty(t, asyncCh)
+				return
+			}
+			require.Len(t, asyncCh, 1)
+			result := <-asyncCh
+			assert.Equal(t, commandResultResource, result.SourceName)
+			require.Len(t, result.CommandValues, 1)
+			assert.Contains(t, result.CommandValues[0].ValueToString(), `"command":"`+tt.resourceName+`"`)
+			assert.Contains(t, result.CommandValues[0].ValueToString(), `"success":`+strconv.FormatBool(len(tt.expectedError) == 0))
+		})
+	}
+}
+
 func Test_findSerialPort(t *testing.T) {
 
 	tests := []struct {
-		name     string
-		portInfo enumerator.PortDetails
-		want     string
-		wantErr  bool
+		name         string
+		portInfo     enumerator.PortDetails
+		serialNumber string
+		claimed      map[string]bool
+		want         string
+		wantErr      bool
 	}{
 		{
 			name: "valid case",
@@ -174,12 +318,51 @@ func Test_findSerialPort(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name: "serial number matches",
+			portInfo: enumerator.PortDetails{
+				Name:         "testDevice",
+				IsUSB:        true,
+				PID:          "6001",
+				VID:          "0403",
+				SerialNumber: "0123456",
+			},
+			serialNumber: "0123456",
+			want:         "testDevice",
+			wantErr:      false,
+		},
+		{
+			name: "serial number not found",
+			portInfo: enumerator.PortDetails{
+				Name:         "testDevice",
+				IsUSB:        true,
+				PID:          "6001",
+				VID:          "0403",
+				SerialNumber: "0123456",
+			},
+			serialNumber: "6543210",
+			want:         "",
+			wantErr:      true,
+		},
+		{
+			name: "port claimed by another scale",
+			portInfo: enumerator.PortDetails{
+				Name:         "testDevice",
+				IsUSB:        true,
+				PID:          "6001",
+				VID:          "0403",
+				SerialNumber: "0123456",
+			},
+			claimed: map[string]bool{"testDevice": true},
+			want:    "",
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var ports []*enumerator.PortDetails
 			ports = append(ports, &tt.portInfo)
-			got, err := findSerialPort(ports, "6001", "0403")
+			got, err := findSerialPort(ports, "6001", "0403", tt.serialNumber, tt.claimed)
 			if tt.wantErr {
 				require.Error(t, err)
 				return
@@ -190,6 +373,54 @@ func Test_findSerialPort(t *testing.T) {
 	}
 }
 
+func TestScaleDriver_MultipleDevices(t *testing.T) {
+	config := scale.Config{
+		PortName:        "/dev/tty.usbserial-test",
+		BaudRate:        9600,
+		DataBits:        7,
+		StopBits:        1,
+		MinimumReadSize: 1,
+		ParityMode:      2,
+		TimeOutMilli:    500,
+	}
+
+	testDevice := scale.InitializeMockDevice(&config)
+
+	drv := getDefaultScaleDriver()
+	baggingScale := getTestScaleDevice("bagging-scale", testDevice)
+	baggingScale.laneID = "1"
+	baggingScale.scaleID = "bagging"
+	scannerScale := getTestScaleDevice("scanner-scale", testDevice)
+	scannerScale.serialPort = "/dev/tty.usbserial-test2"
+	scannerScale.laneID = "1"
+	scannerScale.scaleID = "scanner"
+	drv.scaleDevices[baggingScale.deviceName] = baggingScale
+	drv.scaleDevices[scannerScale.deviceName] = scannerScale
+
+	assert.Equal(t, map[string]bool{"/dev/tty.usbserial-test2": true}, drv.claimedSerialPorts("bagging-scale"))
+
+	for _, device := range []*scaleDevice{baggingScale, scannerScale} {
+		gotRes, err := drv.HandleReadCommands(device.deviceName,
+			map[string]models.ProtocolProperties{},
+			[]dsModels.CommandRequest{
+				{
+					DeviceResourceName: "weight",
+				},
+			},
+		)
+		require.NoError(t, err)
+		require.Len(t, gotRes, 1)
+		assert.Contains(t, gotRes[0].ValueToString(), `"lane_id":"1"`)
+		assert.Contains(t, gotRes[0].ValueToString(), `"scale_id":"`+device.scaleID+`"`)
+	}
+
+	require.NoError(t, drv.RemoveDevice("scanner-scale", map[string]models.ProtocolProperties{}))
+	_, ok := drv.getScaleDevice("scanner-scale")
+	assert.False(t, ok)
+	_, ok = drv.getScaleDevice("bagging-scale")
+	assert.True(t, ok)
+}
+
 func TestScaleDriver_AddDevice(t *testing.T) {
 	config := scale.Config{
 		PortName:        "/dev/tty.usbserial-test",
@@ -233,13 +464,16 @@ func TestScaleDriver_AddDevice(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			drv := getDefaultScaleDriver()
-			drv.scaleDevice = &scaleDevice{
-				serialDevice: testDevice,
-			}
+			drv.scaleDevices[tt.args.deviceName] = getTestScaleDevice(tt.args.deviceName, testDevice)
 
 			err := drv.AddDevice(tt.args.deviceName, tt.args.protocols, tt.args.adminState)
 			require.Error(t, err)
 			assert.Equal(t, tt.expectedError, err.Error())
+			// the scale is kept as disconnected so it is connected once plugged in
+			_, ok := drv.getScaleDevice(tt.args.deviceName)
+			assert.False(t, ok)
+			require.Len(t, drv.listScaleDevices(), 1)
+			assert.False(t, drv.listScaleDevices()[0].connected)
 		})
 	}
 }
@@ -337,13 +571,39 @@ func TestScaleDriver_ValidateDevice(t *testing.T) {
 			},
 			expectedError: "VID is empty",
 		},
+		{
+			name: "lane id not a string",
+			device: models.Device{
+				Name: "testDeviceName",
+				Protocols: map[string]models.ProtocolProperties{
+					"serial": {
+						"PID":    "6001",
+						"VID":    "0403",
+						"LaneID": 1,
+					},
+				},
+			},
+			expectedError: "LaneID value is not a string",
+		},
+		{
+			name: "unsupported model",
+			device: models.Device{
+				Name: "testDeviceName",
+				Protocols: map[string]models.ProtocolProperties{
+					"serial": {
+						"PID":   "6001",
+						"VID":   "0403",
+						"Model": "acme-scale",
+					},
+				},
+			},
+			expectedError: `scale model "acme-scale" is not supported`,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			drv := getDefaultScaleDriver()
-			drv.scaleDevice = &scaleDevice{
-				serialDevice: testDevice,
-			}
+			drv.scaleDevices[tt.device.Name] = getTestScaleDevice(tt.device.Name, testDevice)
 
 			err := drv.ValidateDevice(tt.device)
 			if len(tt.expectedError) > 0 {
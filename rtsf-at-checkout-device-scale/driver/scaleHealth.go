@@ -0,0 +1,190 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package driver
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"device-scale/scale"
+)
+
+const (
+	healthResource = "scale-health"
+
+	defaultEmptyThreshold   = 0.05
+	defaultMaxErrorRate     = 0.2
+	defaultMaxZeroDrift     = 0.02
+	defaultMaxTimeSinceOK   = 30 * time.Second
+	statusScaleAtZero       = "Scale at Zero"
+	timeOutErrorDescription = "time out"
+)
+
+// healthThresholds the limits used to decide if a scale is healthy, set via the driver config
+type healthThresholds struct {
+	emptyThreshold float64
+	maxErrorRate   float64
+	maxZeroDrift   float64
+	maxTimeSinceOK time.Duration
+}
+
+// scaleHealth tracks the health of a scale across reconnects
+type scaleHealth struct {
+	mu               sync.Mutex
+	thresholds       healthThresholds
+	readCount        int64
+	errorCount       int64
+	timeoutCount     int64
+	windowReads      int64
+	windowErrors     int64
+	totalLatency     time.Duration
+	lastLatency      time.Duration
+	lastOKTime       time.Time
+	emptySince       time.Time
+	zeroDrift        float64
+	platformIsEmpty  bool
+	lastErrorMessage string
+}
+
+func newScaleHealth(config map[string]string) *scaleHealth {
+	return &scaleHealth{thresholds: newHealthThresholds(config)}
+}
+
+func newHealthThresholds(config map[string]string) healthThresholds {
+	thresholds := healthThresholds{
+		emptyThreshold: parseFloatConfig(config, "HealthEmptyThreshold", defaultEmptyThreshold),
+		maxErrorRate:   parseFloatConfig(config, "HealthMaxErrorRate", defaultMaxErrorRate),
+		maxZeroDrift:   parseFloatConfig(config, "HealthMaxZeroDrift", defaultMaxZeroDrift),
+		maxTimeSinceOK: defaultMaxTimeSinceOK,
+	}
+	if maxTimeSinceOK, err := time.ParseDuration(config["HealthMaxTimeSinceOK"]); err == nil && maxTimeSinceOK > 0 {
+		thresholds.maxTimeSinceOK = maxTimeSinceOK
+	}
+	return thresholds
+}
+
+func parseFloatConfig(config map[string]string, key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(config[key], 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// recordReading updates the health with a reading received from the scale
+func (health *scaleHealth) recordReading(reading scale.Reading, latency time.Duration, now time.Time) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	health.readCount++
+	health.windowReads++
+	health.totalLatency += latency
+	health.lastLatency = latency
+
+	total, err := strconv.ParseFloat(strings.TrimSpace(reading.Value), 64)
+	if err != nil {
+		total = 0
+	}
+
+	isEmpty := false
+	switch reading.Status {
+	case statusScaleAtZero:
+		health.lastOKTime = now
+		isEmpty = true
+		total = 0
+	case "OK":
+		health.lastOKTime = now
+		isEmpty = math.Abs(total) <= health.thresholds.emptyThreshold
+	}
+
+	if !isEmpty {
+		health.platformIsEmpty = false
+		return
+	}
+
+	// the weight reported while the platform is empty is the drift from the zero point
+	if !health.platformIsEmpty {
+		health.platformIsEmpty = true
+		health.emptySince = now
+	}
+	health.zeroDrift = total
+}
+
+// recordError updates the health with a failed read from the scale
+func (health *scaleHealth) recordError(err error, latency time.Duration) {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	health.readCount++
+	health.errorCount++
+	health.windowReads++
+	health.windowErrors++
+	health.totalLatency += latency
+	health.lastLatency = latency
+	health.lastErrorMessage = err.Error()
+	if strings.Contains(err.Error(), timeOutErrorDescription) {
+		health.timeoutCount++
+	}
+}
+
+// snapshot returns the health metrics put on the bus as the scale-health reading,
+// the error rate covers the reads since the previous snapshot
+func (health *scaleHealth) snapshot(now time.Time) map[string]interface{} {
+	health.mu.Lock()
+	defer health.mu.Unlock()
+
+	var errorRate float64
+	if health.windowReads > 0 {
+		errorRate = float64(health.windowErrors) / float64(health.windowReads)
+	}
+	health.windowReads = 0
+	health.windowErrors = 0
+
+	var averageLatency time.Duration
+	if health.readCount > 0 {
+		averageLatency = health.totalLatency / time.Duration(health.readCount)
+	}
+
+	var timeAtZero time.Duration
+	if health.platformIsEmpty {
+		timeAtZero = now.Sub(health.emptySince)
+	}
+
+	var lastOKTime int64
+	var timeSinceOK time.Duration
+	if !health.lastOKTime.IsZero() {
+		lastOKTime = health.lastOKTime.UnixNano() / int64(time.Millisecond)
+		timeSinceOK = now.Sub(health.lastOKTime)
+	}
+
+	thresholds := health.thresholds
+	reasons := []string{}
+	if health.lastOKTime.IsZero() || timeSinceOK > thresholds.maxTimeSinceOK {
+		reasons = append(reasons, "no successful reading")
+	}
+	if errorRate > thresholds.maxErrorRate {
+		reasons = append(reasons, "error rate too high")
+	}
+	if health.platformIsEmpty && math.Abs(health.zeroDrift) > thresholds.maxZeroDrift {
+		reasons = append(reasons, "zero drift too high")
+	}
+
+	return map[string]interface{}{
+		"read_count":          health.readCount,
+		"error_count":         health.errorCount,
+		"timeout_count":       health.timeoutCount,
+		"error_rate":          errorRate,
+		"read_latency_ms":     health.lastLatency.Milliseconds(),
+		"avg_read_latency_ms": averageLatency.Milliseconds(),
+		"last_ok_time":        lastOKTime,
+		"time_at_zero_ms":     timeAtZero.Milliseconds(),
+		"zero_drift":          health.zeroDrift,
+		"last_error":          health.lastErrorMessage,
+		"healthy":             len(reasons) == 0,
+		"degradation_reasons": reasons,
+	}
+}
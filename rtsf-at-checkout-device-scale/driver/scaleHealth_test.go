@@ -0,0 +1,117 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package driver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"device-scale/scale"
+
+	dsModels "github.com/edgexfoundry/device-sdk-go/v3/pkg/models"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaleHealth_snapshot(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name            string
+		record          func(health *scaleHealth)
+		now             time.Time
+		expectedHealthy bool
+		expectedReasons []string
+	}{
+		{
+			name: "healthy scale at zero",
+			record: func(health *scaleHealth) {
+				health.recordReading(scale.Reading{Status: "Scale at Zero", Value: "000.00", Unit: "LB"}, 20*time.Millisecond, start)
+			},
+			now:             start.Add(10 * time.Second),
+			expectedHealthy: true,
+			expectedReasons: []string{},
+		},
+		{
+			name: "zero drift on empty platform",
+			record: func(health *scaleHealth) {
+				health.recordReading(scale.Reading{Status: "OK", Value: "0.040", Unit: "LB"}, 20*time.Millisecond, start)
+			},
+			now:             start.Add(time.Second),
+			expectedHealthy: false,
+			expectedReasons: []string{"zero drift too high"},
+		},
+		{
+			name: "timeouts",
+			record: func(health *scaleHealth) {
+				health.recordReading(scale.Reading{Status: "OK", Value: "2.494", Unit: "LB"}, 20*time.Millisecond, start)
+				health.recordError(errors.New("time out connecting to scale"), 500*time.Millisecond)
+			},
+			now:             start.Add(time.Minute),
+			expectedHealthy: false,
+			expectedReasons: []string{"no successful reading", "error rate too high"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := newScaleHealth(getDefaultDriverConfig())
+			tt.record(health)
+
+			got := health.snapshot(tt.now)
+			assert.Equal(t, tt.expectedHealthy, got["healthy"])
+			assert.Equal(t, tt.expectedReasons, got["degradation_reasons"])
+		})
+	}
+}
+
+func TestScaleHealth_timeAtZero(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	health := newScaleHealth(getDefaultDriverConfig())
+
+	health.recordReading(scale.Reading{Status: "Scale at Zero", Value: "000.00", Unit: "LB"}, 20*time.Millisecond, start)
+	health.recordReading(scale.Reading{Status: "OK", Value: "0.010", Unit: "LB"}, 30*time.Millisecond, start.Add(5*time.Second))
+	got := health.snapshot(start.Add(10 * time.Second))
+	assert.Equal(t, int64(10000), got["time_at_zero_ms"])
+	assert.Equal(t, 0.01, got["zero_drift"])
+	assert.Equal(t, int64(25), got["avg_read_latency_ms"])
+	assert.Equal(t, 0.0, got["error_rate"])
+
+	health.recordReading(scale.Reading{Status: "OK", Value: "2.494", Unit: "LB"}, 20*time.Millisecond, start.Add(11*time.Second))
+	got = health.snapshot(start.Add(12 * time.Second))
+	assert.Equal(t, int64(0), got["time_at_zero_ms"])
+}
+
+func TestScaleDriver_HandleReadCommandsHealth(t *testing.T) {
+	drv := getDefaultScaleDriver()
+	device := getTestScaleDevice("testDeviceName", nil)
+	device.connected = false
+	device.health = newScaleHealth(drv.config)
+	drv.scaleDevices[device.deviceName] = device
+
+	gotRes, err := drv.HandleReadCommands("testDeviceName",
+		map[string]models.ProtocolProperties{},
+		[]dsModels.CommandRequest{
+			{
+				DeviceResourceName: healthResource,
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, gotRes, 1)
+	assert.Equal(t, healthResource, gotRes[0].DeviceResourceName)
+	assert.Contains(t, gotRes[0].ValueToString(), `"healthy":false`)
+	assert.Contains(t, gotRes[0].ValueToString(), `"disconnected"`)
+
+	_, err = drv.HandleReadCommands("unknownDevice",
+		map[string]models.ProtocolProperties{},
+		[]dsModels.CommandRequest{
+			{
+				DeviceResourceName: healthResource,
+			},
+		},
+	)
+	require.Error(t, err)
+}
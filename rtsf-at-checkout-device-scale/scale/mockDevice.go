@@ -5,7 +5,7 @@ package scale
 
 import (
 	"io"
-	"os"
+	"sync/atomic"
 
 	"github.com/jacobsa/go-serial/serial"
 )
@@ -23,6 +23,27 @@ type MockDevice struct {
 	SerialPort io.ReadWriteCloser
 	Config     *Config
 	TestCase   int
+	// OverlappingOpens counts the times the port was opened while it was already open
+	OverlappingOpens int32
+	openPorts        int32
+}
+
+// mockPort is the serial port of the mock device, it keeps track of the open ports of the device
+type mockPort struct {
+	device *MockDevice
+}
+
+func (port *mockPort) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (port *mockPort) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (port *mockPort) Close() error {
+	atomic.AddInt32(&port.device.openPorts, -1)
+	return nil
 }
 
 func InitializeMockDevice(config *Config) *MockDevice {
@@ -38,12 +59,18 @@ func InitializeMockDevice(config *Config) *MockDevice {
 	if config.TimeOutMilli <= 0 {
 		config.TimeOutMilli = 500
 	}
+	if len(config.Model) == 0 {
+		config.Model = DefaultModel
+	}
 
 	return &MockDevice{Config: config}
 }
 
 func (device *MockDevice) openSerialPort() error {
-	device.SerialPort = &os.File{}
+	if atomic.AddInt32(&device.openPorts, 1) > 1 {
+		atomic.AddInt32(&device.OverlappingOpens, 1)
+	}
+	device.SerialPort = &mockPort{device: device}
 	return nil
 }
 
@@ -62,6 +89,17 @@ func (device *MockDevice) getReading(scaleReading chan Reading, readingErr chan
 	scaleReading <- reading
 }
 
+func (device *MockDevice) getStatus(scaleReading chan Reading, readingErr chan error) {
+
+	reading := ReadingTbl[device.TestCase]
+
+	scaleReading <- Reading{Status: reading.Status}
+}
+
+func (device *MockDevice) commandBytes(command Command) ([]byte, error) {
+	return commandRequest(device.Config.Model, command)
+}
+
 func (device *MockDevice) sendBytes(bytes []byte) (int, error) {
 	// no op
 	return 0, nil
@@ -30,10 +30,26 @@ type Config struct {
 	MinimumReadSize uint
 	// The time out in milliseconds for reading from the scale
 	TimeOutMilli int64
+	// The model of the scale, which selects the protocol requests of the write commands
+	Model string
 
 	Options *serial.OpenOptions
 }
 
+// Command a write command supported by the scale
+type Command string
+
+const (
+	// CommandZero sets the current weight on the platform as the zero point
+	CommandZero Command = "zero"
+	// CommandTare stores the current weight on the platform as tare weight
+	CommandTare Command = "tare"
+	// CommandClearTare clears the stored tare weight
+	CommandClearTare Command = "clear-tare"
+	// CommandDiagnostics requests the status of the scale
+	CommandDiagnostics Command = "diagnostics"
+)
+
 // Reading the weight and status from the scale
 type Reading struct {
 	Status string
@@ -45,6 +61,8 @@ type Reading struct {
 type SerialDevice interface {
 	openSerialPort() error
 	getReading(scaleReading chan Reading, readingErr chan error)
+	getStatus(scaleReading chan Reading, readingErr chan error)
+	commandBytes(command Command) ([]byte, error)
 	sendBytes(bytes []byte) (int, error)
 	readBytes() ([]byte, error)
 	getSerialPort() io.ReadWriteCloser
@@ -57,8 +75,44 @@ type CasPD2 struct {
 	config     *Config
 }
 
-// NewScale creates a new instance of the SerialDevice
-func NewScale(config Config) SerialDevice {
+// DefaultModel is the model of the scales that do not set one
+const DefaultModel = "cas-scale"
+
+// modelCommands maps the scale models to the protocol requests of their write commands
+var modelCommands = map[string]map[Command][]byte{
+	// CAS PD-II (NCI/ECR) protocol
+	DefaultModel: {
+		CommandZero:        {0x5A, 0x0D}, // Z<CR>
+		CommandTare:        {0x54, 0x0D}, // T<CR>
+		CommandClearTare:   {0x43, 0x0D}, // C<CR>
+		CommandDiagnostics: {0x53, 0x0D}, // S<CR>
+	},
+}
+
+var statusTbl = map[string]string{
+	"00": "OK",
+	"10": "Motion",
+	"20": "Scale at Zero",
+	"01": "Under Capacity",
+	"02": "Over Capacity",
+}
+
+// CheckModel returns an error when the scale model is not supported
+func CheckModel(model string) error {
+	if _, ok := modelCommands[model]; !ok {
+		return fmt.Errorf("scale model %q is not supported", model)
+	}
+	return nil
+}
+
+// NewScale creates a new instance of the SerialDevice, the DefaultModel is used when the config does not set one
+func NewScale(config Config) (SerialDevice, error) {
+	if len(config.Model) == 0 {
+		config.Model = DefaultModel
+	}
+	if err := CheckModel(config.Model); err != nil {
+		return nil, err
+	}
 
 	options := &serial.OpenOptions{BaudRate: config.BaudRate,
 		DataBits:        config.DataBits,
@@ -72,45 +126,67 @@ func NewScale(config Config) SerialDevice {
 		config.TimeOutMilli = 500
 	}
 
-	return newCasPD2(&config)
+	return newCasPD2(&config), nil
 }
 
 // GetScaleReading gets the current reading and status from the scale (async call), config timeOutMilli specifies the maximum time before the request times out
 func GetScaleReading(device SerialDevice, scaleReading chan Reading, readingErr chan error) {
+	weightRequest := []byte{0x57, 0x0D}
+	sendRequest(device, weightRequest, device.getReading, scaleReading, readingErr)
+}
 
-	broadcastReading := make(chan Reading)
-	broadcastErr := make(chan error)
-	go func() {
+// SendCommand sends a write command to the scale and gets the resulting status (async call), config timeOutMilli specifies the maximum time before the request times out
+func SendCommand(device SerialDevice, command Command, commandReading chan Reading, commandErr chan error) {
+	request, err := device.commandBytes(command)
+	if err != nil {
+		go func() {
+			commandErr <- err
+		}()
+		return
+	}
+
+	sendRequest(device, request, device.getStatus, commandReading, commandErr)
+}
 
-		if err := device.openSerialPort(); err != nil {
+func sendRequest(device SerialDevice, request []byte, getResponse func(chan Reading, chan error), scaleReading chan Reading, readingErr chan error) {
+	go func() {
+		reading, err := exchange(device, request, getResponse)
+		if err != nil {
 			readingErr <- err
 			return
 		}
+		scaleReading <- reading
+	}()
+}
 
-		defer device.getSerialPort().Close()
+// exchange opens the serial port, sends the request and waits for the response. The port is closed
+// before the response is returned, so that the next request on the device can open it again.
+func exchange(device SerialDevice, request []byte, getResponse func(chan Reading, chan error)) (Reading, error) {
+	if err := device.openSerialPort(); err != nil {
+		return Reading{}, err
+	}
+	defer device.getSerialPort().Close()
 
-		weightRequest := []byte{0x57, 0x0D}
-		timeOut := time.NewTimer(time.Duration(device.getConfig().TimeOutMilli) * time.Millisecond)
+	// buffered so that a response arriving after the time out does not block its reader
+	broadcastReading := make(chan Reading, 1)
+	broadcastErr := make(chan error, 1)
+	timeOut := time.NewTimer(time.Duration(device.getConfig().TimeOutMilli) * time.Millisecond)
+	defer timeOut.Stop()
 
-		go device.getReading(broadcastReading, readingErr)
+	go getResponse(broadcastReading, broadcastErr)
 
-		_, err := device.sendBytes(weightRequest)
-		if err != nil {
-			readingErr <- err
-		}
+	if _, err := device.sendBytes(request); err != nil {
+		return Reading{}, err
+	}
 
-		select {
-		case err = <-broadcastErr:
-			readingErr <- err
-			return
-		case reading := <-broadcastReading:
-			scaleReading <- reading
-			return
-		case <-timeOut.C:
-			timeOutMsg := "time out connecting to scale"
-			readingErr <- errors.New(timeOutMsg)
-		}
-	}()
+	select {
+	case err := <-broadcastErr:
+		return Reading{}, err
+	case reading := <-broadcastReading:
+		return reading, nil
+	case <-timeOut.C:
+		return Reading{}, errors.New("time out connecting to scale")
+	}
 }
 
 func newCasPD2(config *Config) *CasPD2 {
@@ -144,14 +220,6 @@ func (device *CasPD2) getReading(scaleReading chan Reading, readingErr chan erro
 	periodByte := "2E"
 	expectedPeriodIndex := 6
 
-	statusTbl := map[string]string{
-		"00": "OK",
-		"10": "Motion",
-		"20": "Scale at Zero",
-		"01": "Under Capacity",
-		"02": "Over Capacity",
-	}
-
 	for {
 		weightBytes, err = device.readBytes()
 		if err != nil {
@@ -181,14 +249,7 @@ func (device *CasPD2) getReading(scaleReading chan Reading, readingErr chan erro
 			periodIndex := strings.Index(bufferStr, periodByte)
 
 			if statusIndex >= 0 && len(bufferStr) > statusIndex {
-				status := bufferStr[statusIndex-statusLen : statusIndex]
-				s, _ := hex.DecodeString(status)
-				statusDef, ok := statusTbl[fmt.Sprintf("%s", s)]
-				if !ok {
-					statusDef = "N/A"
-				}
-
-				reading.Status = statusDef
+				reading.Status = decodeStatus(bufferStr[statusIndex-statusLen : statusIndex])
 			}
 
 			if weightIndex >= 0 && len(bufferStr) > weightIndex && periodIndex == expectedPeriodIndex {
@@ -207,6 +268,57 @@ func (device *CasPD2) getReading(scaleReading chan Reading, readingErr chan erro
 	}
 }
 
+// getStatus reads the status only response the scale sends back to a write command
+func (device *CasPD2) getStatus(scaleReading chan Reading, readingErr chan error) {
+	bufferBytes := []byte{}
+	statusEnding := "0D03"
+	statusStart := "0A53"
+	statusLen := 4
+
+	for {
+		statusBytes, err := device.readBytes()
+		if err != nil {
+			readingErr <- err
+			return
+		}
+
+		bufferBytes = append(bufferBytes, statusBytes...)
+		bufferStr := strings.ToUpper(hex.EncodeToString(bufferBytes))
+
+		if strings.Index(bufferStr, statusStart) != 0 || len(bufferStr) > 12 {
+			return
+		}
+
+		if statusIndex := strings.Index(bufferStr, statusEnding); statusIndex >= statusLen {
+			scaleReading <- Reading{Status: decodeStatus(bufferStr[statusIndex-statusLen : statusIndex])}
+			return
+		}
+	}
+}
+
+func (device *CasPD2) commandBytes(command Command) ([]byte, error) {
+	return commandRequest(device.config.Model, command)
+}
+
+// commandRequest returns the protocol request of the write command for the scale model
+func commandRequest(model string, command Command) ([]byte, error) {
+	request, ok := modelCommands[model][command]
+	if !ok {
+		return nil, fmt.Errorf("command %s is not supported by the scale", command)
+	}
+	return request, nil
+}
+
+// decodeStatus converts the hex encoded status bytes to the status definition
+func decodeStatus(status string) string {
+	s, _ := hex.DecodeString(status)
+	statusDef, ok := statusTbl[fmt.Sprintf("%s", s)]
+	if !ok {
+		statusDef = "N/A"
+	}
+	return statusDef
+}
+
 func (device *CasPD2) sendBytes(bytes []byte) (int, error) {
 
 	n, err := device.serialPort.Write(bytes)
@@ -42,3 +42,65 @@ func TestGetScaleReading(t *testing.T) {
 		}
 	}
 }
+
+func TestSendCommand(t *testing.T) {
+
+	options := Config{
+		PortName:        "/dev/tty.usbserial-test",
+		BaudRate:        9600,
+		DataBits:        7,
+		StopBits:        1,
+		MinimumReadSize: 1,
+		ParityMode:      2,
+		TimeOutMilli:    500,
+	}
+
+	commandReading := make(chan Reading)
+	commandErr := make(chan error)
+
+	testDevice := InitializeMockDevice(&options)
+
+	for _, command := range []Command{CommandZero, CommandTare, CommandClearTare, CommandDiagnostics} {
+
+		testDevice.TestCase = 1
+
+		SendCommand(testDevice, command, commandReading, commandErr)
+		select {
+		case err := <-commandErr:
+			t.Fatalf("Error sending %s to test device %v", command, err)
+
+		case reading := <-commandReading:
+			if reading.Status != ReadingTbl[1].Status {
+				t.Fatalf("Expected status %s does not match real status %s", ReadingTbl[1].Status, reading.Status)
+			}
+		}
+	}
+
+	SendCommand(testDevice, Command("calibrate"), commandReading, commandErr)
+	select {
+	case <-commandErr:
+	case reading := <-commandReading:
+		t.Fatalf("Expected unsupported command to fail, got reading %v", reading)
+	}
+}
+
+func TestNewScaleModel(t *testing.T) {
+
+	options := Config{
+		PortName:     "/dev/tty.usbserial-test",
+		TimeOutMilli: 500,
+	}
+
+	device, err := NewScale(options)
+	if err != nil {
+		t.Fatalf("Expected the default model to be supported, got %v", err)
+	}
+	if model := device.getConfig().Model; model != DefaultModel {
+		t.Fatalf("Expected model %s, got %s", DefaultModel, model)
+	}
+
+	options.Model = "acme-scale"
+	if _, err := NewScale(options); err == nil {
+		t.Fatalf("Expected unsupported model %s to fail", options.Model)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+)
+
+// Entry is a line of the NDJSON capture file
+type Entry struct {
+	Received time.Time  `json:"received"`
+	Event    dtos.Event `json:"event"`
+}
+
+// Recorder writes the checkout events to a capture file which is rotated once it reaches
+// the maximum size, keeping at most maxFiles rotated files named <file>.1, <file>.2, ...
+type Recorder struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+func NewRecorder(path string, maxSize int64, maxFiles int) (*Recorder, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("capture file maximum size must be positive")
+	}
+	if maxFiles < 0 {
+		return nil, fmt.Errorf("number of rotated capture files can not be negative")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	recorder := &Recorder{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := recorder.open(); err != nil {
+		return nil, err
+	}
+	return recorder, nil
+}
+
+func (recorder *Recorder) open() error {
+	file, err := os.OpenFile(recorder.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	recorder.file = file
+	recorder.size = info.Size()
+	return nil
+}
+
+// Record appends the event to the capture file
+func (recorder *Recorder) Record(event dtos.Event, received time.Time) error {
+	line, err := json.Marshal(Entry{Received: received, Event: event})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.file == nil {
+		return fmt.Errorf("capture file %s is closed", recorder.path)
+	}
+
+	if recorder.size > 0 && recorder.size+int64(len(line)) > recorder.maxSize {
+		if err := recorder.rotate(); err != nil {
+			return err
+		}
+	}
+
+	written, err := recorder.file.Write(line)
+	recorder.size += int64(written)
+	return err
+}
+
+// rotate shifts the rotated files, dropping the oldest one, and starts a new capture file
+func (recorder *Recorder) rotate() error {
+	if err := recorder.file.Close(); err != nil {
+		return err
+	}
+	recorder.file = nil
+
+	if recorder.maxFiles == 0 {
+		if err := os.Remove(recorder.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return recorder.open()
+	}
+
+	oldest := fmt.Sprintf("%s.%d", recorder.path, recorder.maxFiles)
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for index := recorder.maxFiles - 1; index >= 1; index-- {
+		rotated := fmt.Sprintf("%s.%d", recorder.path, index)
+		if err := os.Rename(rotated, fmt.Sprintf("%s.%d", recorder.path, index+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(recorder.path, recorder.path+".1"); err != nil {
+		return err
+	}
+	return recorder.open()
+}
+
+func (recorder *Recorder) Close() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	if recorder.file == nil {
+		return nil
+	}
+	err := recorder.file.Close()
+	recorder.file = nil
+	return err
+}
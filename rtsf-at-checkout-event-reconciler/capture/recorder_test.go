@@ -0,0 +1,86 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getTestEvent(resourceName string) dtos.Event {
+	event := dtos.NewEvent("pos", "pos-rest", resourceName)
+	event.AddObjectReading(resourceName, map[string]interface{}{"lane_id": "1", "basket_id": "abc-012345-def"})
+	return event
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := Entry{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRecorder_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture", "events.ndjson")
+	recorder, err := NewRecorder(path, 1024*1024, 2)
+	require.NoError(t, err)
+
+	received := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, recorder.Record(getTestEvent("basket-open"), received))
+	require.NoError(t, recorder.Record(getTestEvent("payment-start"), received.Add(time.Second)))
+	require.NoError(t, recorder.Close())
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, received, entries[0].Received)
+	assert.Equal(t, "pos-rest", entries[0].Event.DeviceName)
+	assert.Equal(t, "payment-start", entries[1].Event.Readings[0].ResourceName)
+	assert.Equal(t, "1", entries[1].Event.Readings[0].ObjectValue.(map[string]interface{})["lane_id"])
+
+	assert.Error(t, recorder.Record(getTestEvent("basket-close"), received))
+}
+
+func TestRecorder_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	line, err := json.Marshal(Entry{Received: time.Now(), Event: getTestEvent("basket-open")})
+	require.NoError(t, err)
+
+	// room for two events per file
+	recorder, err := NewRecorder(path, int64(2*(len(line)+1)+10), 2)
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, recorder.Record(getTestEvent("basket-open"), time.Now()))
+	}
+
+	assert.Len(t, readEntries(t, path), 1)
+	assert.Len(t, readEntries(t, path+".1"), 2)
+	assert.Len(t, readEntries(t, path+".2"), 2)
+	assert.NoFileExists(t, path+".3")
+}
+
+func TestNewRecorder_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	_, err := NewRecorder(path, 0, 1)
+	assert.Error(t, err)
+	_, err = NewRecorder(path, 1024, -1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,110 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package clock provides the time source of the reconciler, so that checkout events can be
+// reconciled against the system time, the time of the events being replayed or a test clock
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// SourceSystem reconciles against the system time
+	SourceSystem = "system"
+	// SourceEvent reconciles against the time of the latest checkout event
+	SourceEvent = "event"
+)
+
+// Clock tells the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Observer is implemented by the clocks that follow the time of the checkout events
+type Observer interface {
+	Observe(eventTime time.Time)
+}
+
+// New returns the clock of the source
+func New(source string) Clock {
+	if source == SourceEvent {
+		return NewEventClock()
+	}
+	return NewSystemClock()
+}
+
+type systemClock struct{}
+
+// NewSystemClock returns the clock of the system time
+func NewSystemClock() Clock {
+	return systemClock{}
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// EventClock tells the time of the latest checkout event observed, it never goes back in time
+// so that events replayed out of order do not rewind it
+type EventClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewEventClock() *EventClock {
+	return &EventClock{}
+}
+
+func (eventClock *EventClock) Now() time.Time {
+	eventClock.mu.Lock()
+	defer eventClock.mu.Unlock()
+	return eventClock.now
+}
+
+func (eventClock *EventClock) Observe(eventTime time.Time) {
+	eventClock.mu.Lock()
+	defer eventClock.mu.Unlock()
+	if eventTime.After(eventClock.now) {
+		eventClock.now = eventTime
+	}
+}
+
+// ManualClock only moves when it is set or advanced, for tests
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+func (manualClock *ManualClock) Now() time.Time {
+	manualClock.mu.Lock()
+	defer manualClock.mu.Unlock()
+	return manualClock.now
+}
+
+func (manualClock *ManualClock) Set(now time.Time) {
+	manualClock.mu.Lock()
+	defer manualClock.mu.Unlock()
+	manualClock.now = now
+}
+
+func (manualClock *ManualClock) Advance(duration time.Duration) {
+	manualClock.mu.Lock()
+	defer manualClock.mu.Unlock()
+	manualClock.now = manualClock.now.Add(duration)
+}
+
+// FromEventTime converts the event_time of a checkout event, in milliseconds since the epoch
+func FromEventTime(eventTime int64) time.Time {
+	return time.Unix(0, eventTime*int64(time.Millisecond))
+}
+
+// ToEventTime converts the time to the event_time of a checkout event, in milliseconds since the epoch
+func ToEventTime(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventClock(t *testing.T) {
+	eventClock := NewEventClock()
+	assert.True(t, eventClock.Now().IsZero())
+
+	eventClock.Observe(FromEventTime(1559679684000))
+	assert.Equal(t, int64(1559679684000), ToEventTime(eventClock.Now()))
+
+	// a late event does not rewind the clock
+	eventClock.Observe(FromEventTime(1559679683000))
+	assert.Equal(t, int64(1559679684000), ToEventTime(eventClock.Now()))
+
+	eventClock.Observe(FromEventTime(1559679685500))
+	assert.Equal(t, int64(1559679685500), ToEventTime(eventClock.Now()))
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2023, 6, 1, 10, 0, 0, 0, time.UTC)
+	manualClock := NewManualClock(start)
+	assert.Equal(t, start, manualClock.Now())
+
+	manualClock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, start.Add(1500*time.Millisecond), manualClock.Now())
+
+	manualClock.Set(start)
+	assert.Equal(t, start, manualClock.Now())
+}
+
+func TestNew(t *testing.T) {
+	_, ok := New(SourceEvent).(Observer)
+	assert.True(t, ok)
+
+	_, ok = New(SourceSystem).(Observer)
+	assert.False(t, ok)
+}
@@ -7,6 +7,16 @@ import (
 	"fmt"
 	"reflect"
 	"time"
+
+	"event-reconciler/clock"
+)
+
+const (
+	// ScaleDegradedSuppress drops the scale suspects while a scale of the lane is degraded
+	ScaleDegradedSuppress = "suppress"
+	// ScaleDegradedDownWeight keeps the scale suspects while a scale of the lane is degraded,
+	// but they are only reported along with CV or RFID suspects
+	ScaleDegradedDownWeight = "downweight"
 )
 
 type ServiceConfig struct {
@@ -14,15 +24,42 @@ type ServiceConfig struct {
 }
 
 type ReconcilerConfig struct {
-	DeviceNames           string
-	DevicePos             string
-	DeviceScale           string
-	DeviceCV              string
-	DeviceRFID            string
-	ProductLookupEndpoint string
-	WebSocketPort         string
-	ScaleToScaleTolerance float64
-	CvTimeAlignment       string
+	DeviceNames               string
+	DevicePos                 string
+	DeviceScale               string
+	DeviceCV                  string
+	DeviceRFID                string
+	ProductLookupEndpoint     string
+	WebSocketPort             string
+	ScaleToScaleTolerance     float64
+	CvTimeAlignment           string
+	ScaleDegradedSuspects     string
+	CaptureEnabled            bool
+	CaptureFile               string
+	CaptureMaxSizeMB          int
+	CaptureMaxFiles           int
+	ClockSource               string
+	ReorderLateness           string
+	StateMachineFile          string
+	PriceOverrideLimit        float64
+	SeverityMediumValue       float64
+	SeverityHighValue         float64
+	FindingsTopic             string
+	BlockPaymentOnFindings    bool
+	JournalEnabled            bool
+	JournalFile               string
+	FusionPrior               float64
+	FusionScaleWeight         float64
+	FusionCVWeight            float64
+	FusionRFIDWeight          float64
+	FusionTimingWeight        float64
+	FusionTimeWindow          string
+	FusionConfidenceThreshold float64
+	CVLabelMappingFile        string
+	CVConfidenceThreshold     float64
+	CVTrackMergeWindow        string
+	SoldEPCTTL                string
+	SuspendedTransactionTTL   string
 }
 
 // UpdateFromRaw updates the service's full configuration from raw data received from
@@ -53,6 +90,62 @@ func (bs *ReconcilerConfig) Validate() (time.Duration, error) {
 		}
 	}
 
+	if bs.ScaleDegradedSuspects != ScaleDegradedSuppress && bs.ScaleDegradedSuspects != ScaleDegradedDownWeight {
+		return defaultRtnVal, fmt.Errorf("ScaleDegradedSuspects must be %s or %s", ScaleDegradedSuppress, ScaleDegradedDownWeight)
+	}
+
+	if bs.ClockSource != clock.SourceSystem && bs.ClockSource != clock.SourceEvent {
+		return defaultRtnVal, fmt.Errorf("ClockSource must be %s or %s", clock.SourceSystem, clock.SourceEvent)
+	}
+
+	if bs.CaptureEnabled && (bs.CaptureMaxSizeMB <= 0 || bs.CaptureMaxFiles < 0) {
+		return defaultRtnVal, fmt.Errorf("CaptureMaxSizeMB must be positive and CaptureMaxFiles can not be negative")
+	}
+
+	if bs.PriceOverrideLimit < 0 {
+		return defaultRtnVal, fmt.Errorf("PriceOverrideLimit can not be negative")
+	}
+
+	if bs.SeverityMediumValue < 0 || bs.SeverityHighValue < bs.SeverityMediumValue {
+		return defaultRtnVal, fmt.Errorf("SeverityMediumValue can not be negative nor above SeverityHighValue")
+	}
+
+	if _, err := bs.GetReorderLateness(); err != nil {
+		return defaultRtnVal, err
+	}
+
+	if bs.FusionPrior <= 0 || bs.FusionPrior >= 1 {
+		return defaultRtnVal, fmt.Errorf("FusionPrior must be between 0 and 1")
+	}
+
+	if bs.FusionScaleWeight < 0 || bs.FusionCVWeight < 0 || bs.FusionRFIDWeight < 0 || bs.FusionTimingWeight < 0 {
+		return defaultRtnVal, fmt.Errorf("FusionScaleWeight, FusionCVWeight, FusionRFIDWeight and FusionTimingWeight can not be negative")
+	}
+
+	if bs.FusionConfidenceThreshold < 0 || bs.FusionConfidenceThreshold >= 1 {
+		return defaultRtnVal, fmt.Errorf("FusionConfidenceThreshold must be at least 0 and below 1")
+	}
+
+	if bs.CVConfidenceThreshold < 0 || bs.CVConfidenceThreshold > 1 {
+		return defaultRtnVal, fmt.Errorf("CVConfidenceThreshold must be between 0 and 1")
+	}
+
+	if _, err := bs.GetFusionTimeWindow(); err != nil {
+		return defaultRtnVal, err
+	}
+
+	if _, err := bs.GetCVTrackMergeWindow(); err != nil {
+		return defaultRtnVal, err
+	}
+
+	if _, err := bs.GetSoldEPCTTL(); err != nil {
+		return defaultRtnVal, err
+	}
+
+	if _, err := bs.GetSuspendedTransactionTTL(); err != nil {
+		return defaultRtnVal, err
+	}
+
 	tempDuration, err := time.ParseDuration(bs.CvTimeAlignment)
 	if err != nil {
 		return defaultRtnVal, fmt.Errorf("failed to parse cvTimeAlignment duration: %v", err)
@@ -60,3 +153,67 @@ func (bs *ReconcilerConfig) Validate() (time.Duration, error) {
 
 	return tempDuration, nil
 }
+
+// GetReorderLateness returns how long the readings are held to be processed in event_time order,
+// zero processes the readings as they arrive
+func (bs *ReconcilerConfig) GetReorderLateness() (time.Duration, error) {
+	lateness, err := time.ParseDuration(bs.ReorderLateness)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ReorderLateness duration: %v", err)
+	}
+	if lateness < 0 {
+		return 0, fmt.Errorf("ReorderLateness can not be negative")
+	}
+	return lateness, nil
+}
+
+// GetFusionTimeWindow returns how far from the scan of a line its sensor items can be before their
+// timing stops adding to the confidence of the line
+func (bs *ReconcilerConfig) GetFusionTimeWindow() (time.Duration, error) {
+	window, err := time.ParseDuration(bs.FusionTimeWindow)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse FusionTimeWindow duration: %v", err)
+	}
+	if window < 0 {
+		return 0, fmt.Errorf("FusionTimeWindow can not be negative")
+	}
+	return window, nil
+}
+
+// GetCVTrackMergeWindow returns how long after a CV track left every ROI a new track of the same
+// object is taken as the same object reacquired by the tracker, zero never merges tracks
+func (bs *ReconcilerConfig) GetCVTrackMergeWindow() (time.Duration, error) {
+	window, err := time.ParseDuration(bs.CVTrackMergeWindow)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse CVTrackMergeWindow duration: %v", err)
+	}
+	if window < 0 {
+		return 0, fmt.Errorf("CVTrackMergeWindow can not be negative")
+	}
+	return window, nil
+}
+
+// GetSoldEPCTTL returns how long the RFID tags sold at payment-success are taken as paid when read
+// at the Departure ROI
+func (bs *ReconcilerConfig) GetSoldEPCTTL() (time.Duration, error) {
+	ttl, err := time.ParseDuration(bs.SoldEPCTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse SoldEPCTTL duration: %v", err)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("SoldEPCTTL must be positive")
+	}
+	return ttl, nil
+}
+
+// GetSuspendedTransactionTTL returns how long a suspended transaction can be resumed before it is dropped
+func (bs *ReconcilerConfig) GetSuspendedTransactionTTL() (time.Duration, error) {
+	ttl, err := time.ParseDuration(bs.SuspendedTransactionTTL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse SuspendedTransactionTTL duration: %v", err)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("SuspendedTransactionTTL must be positive")
+	}
+	return ttl, nil
+}
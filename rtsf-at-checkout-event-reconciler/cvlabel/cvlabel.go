@@ -0,0 +1,84 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package cvlabel maps the class labels of the CV product detection model to the GTINs of the
+// products they may be
+package cvlabel
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gtinLength is the length GTINs are padded to, the length of the product ids of the POS
+const gtinLength = 14
+
+// Mapping holds the candidate GTINs of each class label, labels are matched regardless of case
+type Mapping map[string][]string
+
+// LoadMapping reads the mapping of the class labels from a YAML file
+func LoadMapping(path string) (Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CV label mapping %s: %v", path, err)
+	}
+	mapping, err := ParseMapping(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CV label mapping %s: %v", path, err)
+	}
+	return mapping, nil
+}
+
+// ParseMapping reads the mapping of the class labels from YAML, the GTINs are padded to 14 digits
+func ParseMapping(data []byte) (Mapping, error) {
+	definition := struct {
+		Labels map[string][]string `yaml:"labels"`
+	}{}
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return nil, err
+	}
+
+	mapping := make(Mapping)
+	for label, gtins := range definition.Labels {
+		for _, gtin := range gtins {
+			normalized, err := NormalizeGTIN(gtin)
+			if err != nil {
+				return nil, fmt.Errorf("label %q: %v", label, err)
+			}
+			mapping.add(label, normalized)
+		}
+	}
+	return mapping, nil
+}
+
+// NormalizeGTIN pads the GTIN with zeros to 14 digits
+func NormalizeGTIN(gtin string) (string, error) {
+	gtin = strings.TrimSpace(gtin)
+	if len(gtin) == 0 || len(gtin) > gtinLength {
+		return "", fmt.Errorf("GTIN %q must have 1 to %d digits", gtin, gtinLength)
+	}
+	for _, digit := range gtin {
+		if digit < '0' || digit > '9' {
+			return "", fmt.Errorf("GTIN %q must only have digits", gtin)
+		}
+	}
+	return strings.Repeat("0", gtinLength-len(gtin)) + gtin, nil
+}
+
+func (mapping Mapping) add(label string, gtin string) {
+	key := strings.ToLower(strings.TrimSpace(label))
+	for _, existing := range mapping[key] {
+		if existing == gtin {
+			return
+		}
+	}
+	mapping[key] = append(mapping[key], gtin)
+}
+
+// GTINs returns the candidate GTINs of the class label, none for an unknown label
+func (mapping Mapping) GTINs(label string) []string {
+	return mapping[strings.ToLower(strings.TrimSpace(label))]
+}
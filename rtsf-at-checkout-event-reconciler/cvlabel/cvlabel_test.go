@@ -0,0 +1,63 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package cvlabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfiguredMapping(t *testing.T) {
+	mapping, err := LoadMapping("../res/cv-label-mapping.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"00028400159609", "00038000183713"}, mapping.GTINs("Chips"))
+	assert.Empty(t, mapping.GTINs("unknown"))
+}
+
+func TestParseMapping(t *testing.T) {
+	tables := []struct {
+		name     string
+		mapping  string
+		label    string
+		expected []string
+		err      string
+	}{
+		{
+			name:     "padded GTIN",
+			mapping:  "labels:\n  soda: [\"735797\"]",
+			label:    "SODA",
+			expected: []string{"00000000735797"},
+		},
+		{
+			name:     "duplicate GTIN",
+			mapping:  "labels:\n  soda: [\"735797\", \"00000000735797\"]",
+			label:    "soda",
+			expected: []string{"00000000735797"},
+		},
+		{
+			name:    "GTIN with letters",
+			mapping: "labels:\n  soda: [\"73579a\"]",
+			err:     `label "soda": GTIN "73579a" must only have digits`,
+		},
+		{
+			name:    "GTIN too long",
+			mapping: "labels:\n  soda: [\"000000000735797\"]",
+			err:     `label "soda": GTIN "000000000735797" must have 1 to 14 digits`,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			mapping, err := ParseMapping([]byte(table.mapping))
+			if len(table.err) > 0 {
+				assert.EqualError(t, err, table.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, table.expected, mapping.GTINs(table.label))
+		})
+	}
+}
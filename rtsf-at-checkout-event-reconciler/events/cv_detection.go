@@ -0,0 +1,105 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"event-reconciler/cvlabel"
+)
+
+// SetCVLabelMapping sets the candidate GTINs of the class labels the CV items are matched with
+func (eventsProcessing *EventsProcessor) SetCVLabelMapping(mapping cvlabel.Mapping) {
+	eventsProcessing.cvLabelMapping = mapping
+}
+
+func (eventsProcessing *EventsProcessor) cvConfidenceThreshold() float64 {
+	if eventsProcessing.processConfig == nil {
+		return 0
+	}
+	return eventsProcessing.processConfig.CVConfidenceThreshold
+}
+
+func addCandidateGTIN(candidates []string, gtin string) []string {
+	normalized, err := cvlabel.NormalizeGTIN(gtin)
+	if err != nil {
+		return candidates
+	}
+	for _, candidate := range candidates {
+		if candidate == normalized {
+			return candidates
+		}
+	}
+	return append(candidates, normalized)
+}
+
+// resolveCVDetection names the detection after its class label when it has no product name, adds the
+// candidate GTINs of its labels and flags it when its confidence is below CVConfidenceThreshold. A
+// detection without confidence is taken as confident.
+func (eventsProcessing *EventsProcessor) resolveCVDetection(cvReading *CVEventEntry) {
+	if len(cvReading.ObjectName) == 0 {
+		cvReading.ObjectName = cvReading.ClassLabel
+	}
+
+	candidates := []string{}
+	for _, gtin := range cvReading.CandidateGTINs {
+		candidates = addCandidateGTIN(candidates, gtin)
+	}
+	for _, label := range []string{cvReading.ClassLabel, cvReading.ObjectName} {
+		for _, gtin := range eventsProcessing.cvLabelMapping.GTINs(label) {
+			candidates = addCandidateGTIN(candidates, gtin)
+		}
+	}
+	cvReading.CandidateGTINs = candidates
+	cvReading.LowConfidence = cvReading.Confidence > 0 && cvReading.Confidence < eventsProcessing.cvConfidenceThreshold()
+}
+
+// updateCVDetection adds the candidate GTINs of the detection to the CV item, and keeps the detection
+// metadata of the detection when it is confident while the item was not, or when it is more confident
+func updateCVDetection(cvReading CVEventEntry, cvItem *CVEventEntry) {
+	for _, gtin := range cvReading.CandidateGTINs {
+		cvItem.CandidateGTINs = addCandidateGTIN(cvItem.CandidateGTINs, gtin)
+	}
+
+	if (cvItem.LowConfidence && !cvReading.LowConfidence) || (cvItem.LowConfidence == cvReading.LowConfidence && cvReading.Confidence > cvItem.Confidence) {
+		cvItem.ClassLabel = cvReading.ClassLabel
+		cvItem.Confidence = cvReading.Confidence
+		cvItem.BoundingBox = cvReading.BoundingBox
+		cvItem.FrameId = cvReading.FrameId
+		cvItem.LowConfidence = cvReading.LowConfidence
+	}
+}
+
+// cvItemMatchesLine tells whether the CV item may be the product of the line, by name or by GTIN
+func (eventsProcessing *EventsProcessor) cvItemMatchesLine(cvItem CVEventEntry, line RTTLogEventEntry) bool {
+	if line.ProductName == cvItem.ObjectName {
+		return true
+	}
+	productID := eventsProcessing.convertProductIDTo14Char(line.ProductId)
+	for _, gtin := range cvItem.CandidateGTINs {
+		if gtin == productID {
+			return true
+		}
+	}
+	return false
+}
+
+// cvItemGTIN returns the GTIN the CV item is taken for, its first candidate when it was not priced
+func cvItemGTIN(cvItem CVEventEntry) string {
+	if len(cvItem.GTIN) > 0 || len(cvItem.CandidateGTINs) == 0 {
+		return cvItem.GTIN
+	}
+	return cvItem.CandidateGTINs[0]
+}
+
+// getLowConfidenceCVItems returns the low confidence CV items not associated with a line, they are
+// not suspects but are reported apart for review
+func (eventsProcessing *EventsProcessor) getLowConfidenceCVItems() []CVEventEntry {
+	lowConfidenceItems := []CVEventEntry{}
+	for _, cvItem := range eventsProcessing.currentCVData {
+		if cvItem.LowConfidence && cvItem.AssociatedRTTLEntry == nil &&
+			!eventsProcessing.atROILocation(GoBackROI, cvItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, cvItem.ROIs) {
+			lowConfidenceItems = append(lowConfidenceItems, cvItem)
+		}
+	}
+	return lowConfidenceItems
+}
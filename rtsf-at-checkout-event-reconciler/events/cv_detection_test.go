@@ -0,0 +1,121 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"event-reconciler/config"
+	"event-reconciler/cvlabel"
+)
+
+func newCVDetectionTestProcessor(t *testing.T) *EventsProcessor {
+	eventsProcessor := NewEventsProcessor(5*time.Second, &config.ReconcilerConfig{CVConfidenceThreshold: 0.5})
+	mapping, err := cvlabel.ParseMapping([]byte("labels:\n  soda: [\"49000050158\"]\n  chips: [\"00028400159609\", \"00038000183713\"]"))
+	require.NoError(t, err)
+	eventsProcessor.SetCVLabelMapping(mapping)
+	return eventsProcessor
+}
+
+func TestResolveCVDetection(t *testing.T) {
+	tables := []struct {
+		name                  string
+		reading               CVEventEntry
+		expectedName          string
+		expectedCandidates    []string
+		expectedLowConfidence bool
+	}{
+		{
+			name:               "class label mapped",
+			reading:            CVEventEntry{ClassLabel: "Soda", Confidence: 0.9},
+			expectedName:       "Soda",
+			expectedCandidates: []string{"00049000050158"},
+		},
+		{
+			name:               "candidates of the event first",
+			reading:            CVEventEntry{ObjectName: "Pringles", ClassLabel: "chips", CandidateGTINs: []string{"38000183713"}, Confidence: 0.7},
+			expectedName:       "Pringles",
+			expectedCandidates: []string{"00038000183713", "00028400159609"},
+		},
+		{
+			name:                  "low confidence",
+			reading:               CVEventEntry{ObjectName: "Red Wine", Confidence: 0.3},
+			expectedName:          "Red Wine",
+			expectedCandidates:    []string{},
+			expectedLowConfidence: true,
+		},
+		{
+			name:               "confidence not reported",
+			reading:            CVEventEntry{ObjectName: "Red Wine"},
+			expectedName:       "Red Wine",
+			expectedCandidates: []string{},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newCVDetectionTestProcessor(t)
+			reading := table.reading
+			eventsProcessor.resolveCVDetection(&reading)
+			assert.Equal(t, table.expectedName, reading.ObjectName)
+			assert.Equal(t, table.expectedCandidates, reading.CandidateGTINs)
+			assert.Equal(t, table.expectedLowConfidence, reading.LowConfidence)
+		})
+	}
+}
+
+func TestUpdateCVDetection(t *testing.T) {
+	cvItem := CVEventEntry{ObjectName: "soda", Confidence: 0.3, LowConfidence: true, FrameId: 1}
+
+	updateCVDetection(CVEventEntry{Confidence: 0.8, FrameId: 2, BoundingBox: BoundingBox{XMax: 10, YMax: 20},
+		CandidateGTINs: []string{"00049000050158"}}, &cvItem)
+	assert.False(t, cvItem.LowConfidence)
+	assert.Equal(t, 0.8, cvItem.Confidence)
+	assert.Equal(t, int64(2), cvItem.FrameId)
+	assert.Equal(t, BoundingBox{XMax: 10, YMax: 20}, cvItem.BoundingBox)
+	assert.Equal(t, []string{"00049000050158"}, cvItem.CandidateGTINs)
+
+	// a low confidence detection does not replace a confident one
+	updateCVDetection(CVEventEntry{Confidence: 0.4, LowConfidence: true, FrameId: 3}, &cvItem)
+	assert.False(t, cvItem.LowConfidence)
+	assert.Equal(t, int64(2), cvItem.FrameId)
+}
+
+func TestCVBasketReconciliationByGTIN(t *testing.T) {
+	eventsProcessor := newCVDetectionTestProcessor(t)
+	BasketOpen(eventsProcessor)
+	for _, reading := range []CVEventEntry{
+		{ClassLabel: "soda", Confidence: 0.9, EventTime: 1000},
+		{ClassLabel: "chips", Confidence: 0.2, EventTime: 1100},
+	} {
+		eventsProcessor.resolveCVDetection(&reading)
+		reading.ROIs = map[string]ROILocation{ScannerROI: {AtLocation: true, LastAtLocation: reading.EventTime}}
+		eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, reading)
+	}
+
+	soda := RTTLogEventEntry{ProductId: "00049000050158", ProductName: "Sprite", Quantity: 1, EventTime: 1500}
+	eventsProcessor.cvBasketReconciliation(&soda)
+	assert.True(t, soda.CVConfirmed)
+	require.Len(t, soda.AssociatedCVItems, 1)
+	assert.Equal(t, "soda", soda.AssociatedCVItems[0].ObjectName)
+
+	chips := RTTLogEventEntry{ProductId: "00028400159609", ProductName: "Ruffles", Quantity: 1, EventTime: 1500}
+	eventsProcessor.cvBasketReconciliation(&chips)
+	assert.False(t, chips.CVConfirmed)
+	assert.Empty(t, chips.AssociatedCVItems)
+	require.Len(t, chips.Decisions, 1)
+	assert.Equal(t, DecisionCVLowConfidence, chips.Decisions[0].Reason)
+	assert.Equal(t, 0.2, chips.Decisions[0].Measured)
+	assert.Equal(t, 0.5, chips.Decisions[0].Tolerance)
+
+	// the low confidence detection is reported apart from the suspects
+	assert.Empty(t, eventsProcessor.getSuspectCVItems())
+	lowConfidenceItems := eventsProcessor.getLowConfidenceCVItems()
+	require.Len(t, lowConfidenceItems, 1)
+	assert.Equal(t, "chips", lowConfidenceItems[0].ObjectName)
+}
@@ -0,0 +1,118 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"time"
+
+	"event-reconciler/clock"
+)
+
+func (eventsProcessing *EventsProcessor) cvTrackMergeWindow() time.Duration {
+	if eventsProcessing.processConfig == nil {
+		return 0
+	}
+	window, err := eventsProcessing.processConfig.GetCVTrackMergeWindow()
+	if err != nil {
+		return 0
+	}
+	return window
+}
+
+// atAnyROILocation checks if the item is at one of the ROIs it was seen in
+func (eventsProcessing *EventsProcessor) atAnyROILocation(ROIs map[string]ROILocation) bool {
+	for _, location := range ROIs {
+		if location.AtLocation {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTrack(cvItem CVEventEntry, trackID string) bool {
+	for _, itemTrackID := range cvItem.TrackIds {
+		if itemTrackID == trackID {
+			return true
+		}
+	}
+	return false
+}
+
+func removeTrack(cvItem *CVEventEntry, trackID string) {
+	trackIDs := []string{}
+	for _, itemTrackID := range cvItem.TrackIds {
+		if itemTrackID != trackID {
+			trackIDs = append(trackIDs, itemTrackID)
+		}
+	}
+	cvItem.TrackIds = trackIDs
+}
+
+// getExistingCVItem returns the CV item the reading is a detection of, nil for a new item. Items are
+// keyed by the tracks of the CV tracker, readings without track are collapsed by object name.
+//
+// A track lost by the tracker is reacquired with a new id, so a new track entering an ROI is merged
+// into the item of the same name that left every ROI within CVTrackMergeWindow. When the merged track
+// turns out to be alive while the item is seen by another of its tracks, they were two objects after
+// all and the track is split off as a new item.
+func (eventsProcessing *EventsProcessor) getExistingCVItem(cvReading CVEventEntry) *CVEventEntry {
+	if len(cvReading.TrackId) == 0 {
+		return eventsProcessing.getExistingCVDataByObjectName(cvReading)
+	}
+
+	for cvIndex := range eventsProcessing.currentCVData {
+		cvItem := &eventsProcessing.currentCVData[cvIndex]
+		if !hasTrack(*cvItem, cvReading.TrackId) {
+			continue
+		}
+		if cvItem.TrackId != cvReading.TrackId && eventsProcessing.atAnyROILocation(cvItem.ROIs) {
+			removeTrack(cvItem, cvReading.TrackId)
+			return nil
+		}
+		cvItem.TrackId = cvReading.TrackId
+		return cvItem
+	}
+
+	if cvReading.ROIAction != ROIActionEnter {
+		return nil
+	}
+	var lostItem *CVEventEntry
+	mergeWindow := eventsProcessing.cvTrackMergeWindow()
+	for cvIndex := range eventsProcessing.currentCVData {
+		cvItem := &eventsProcessing.currentCVData[cvIndex]
+		if len(cvItem.TrackIds) == 0 || cvItem.ObjectName != cvReading.ObjectName || eventsProcessing.atAnyROILocation(cvItem.ROIs) {
+			continue
+		}
+		gap := clock.FromEventTime(cvReading.EventTime).Sub(clock.FromEventTime(cvItem.EventTime))
+		if gap < 0 || gap > mergeWindow {
+			continue
+		}
+		if lostItem == nil || cvItem.EventTime > lostItem.EventTime {
+			lostItem = cvItem
+		}
+	}
+	if lostItem != nil {
+		lostItem.TrackId = cvReading.TrackId
+		lostItem.TrackIds = append(lostItem.TrackIds, cvReading.TrackId)
+	}
+	return lostItem
+}
+
+// cvItemOnLine checks if the CV item is associated with the line, the entry the item references
+// may be a copy of the line or of one of the scans collected in it
+func cvItemOnLine(cvItem CVEventEntry, line RTTLogEventEntry) bool {
+	entry := cvItem.AssociatedRTTLEntry
+	if entry == nil || entry.ProductId != line.ProductId {
+		return false
+	}
+	if entry.EventTime == line.EventTime {
+		return true
+	}
+	for _, scan := range line.Collection {
+		if scan.EventTime == entry.EventTime {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"event-reconciler/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCVTrackingTestProcessor(t *testing.T) *EventsProcessor {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ProductDetails{Name: "Soda", ExpectedMinWeight: 0.7, ExpectedMaxWeight: 0.8})
+	}))
+	t.Cleanup(server.Close)
+
+	eventsProcessor := NewEventsProcessor(5*time.Second, &config.ReconcilerConfig{
+		DevicePos:             "pos",
+		DeviceScale:           "scale",
+		DeviceCV:              "cv-roi",
+		DeviceRFID:            "rfid-roi",
+		ProductLookupEndpoint: strings.TrimPrefix(server.URL, "http://"),
+		CVTrackMergeWindow:    "500ms",
+	})
+	eventsProcessor.ResetCheckoutState()
+	return eventsProcessor
+}
+
+func processCVTrackEvent(t *testing.T, eventsProcessor *EventsProcessor, trackID string, roiName string, roiAction string, eventTime int64) {
+	_, err := eventsProcessor.ProcessCheckoutEvents(context, testCheckoutEvent("cv-roi-rest", cvRoiEvent, map[string]interface{}{
+		"product_name": "Soda",
+		"track_id":     trackID,
+		"roi_name":     roiName,
+		"roi_action":   roiAction,
+		"event_time":   eventTime,
+	}))
+	assert.Nil(t, err)
+}
+
+func scannedSoda(quantity float64, eventTime int64) map[string]interface{} {
+	return map[string]interface{}{
+		"lane_id":       "1",
+		"basket_id":     "abc",
+		"product_id":    "00049000050158",
+		"product_name":  "Soda",
+		"quantity":      quantity,
+		"quantity_unit": quantityUnitEA,
+		"event_time":    eventTime,
+	}
+}
+
+func TestCVTracksCountDistinctItems(t *testing.T) {
+	tables := []struct {
+		name              string
+		trackIDs          []string
+		scans             []float64
+		expectedItems     int
+		expectedConfirmed bool
+	}{
+		{
+			name:              "three cans scanned as a quantity of 3",
+			trackIDs:          []string{"1", "2", "3"},
+			scans:             []float64{3},
+			expectedItems:     3,
+			expectedConfirmed: true,
+		},
+		{
+			name:              "three cans scanned one by one",
+			trackIDs:          []string{"1", "2", "3"},
+			scans:             []float64{1, 1, 1},
+			expectedItems:     3,
+			expectedConfirmed: true,
+		},
+		{
+			name:              "one can shown three times",
+			trackIDs:          []string{"1", "1", "1"},
+			scans:             []float64{1, 1, 1},
+			expectedItems:     1,
+			expectedConfirmed: false,
+		},
+		{
+			name:              "one can scanned as a quantity of 3",
+			trackIDs:          []string{"1"},
+			scans:             []float64{3},
+			expectedItems:     1,
+			expectedConfirmed: false,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newCVTrackingTestProcessor(t)
+			processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+
+			eventTime := int64(2000)
+			for _, trackID := range table.trackIDs {
+				processCVTrackEvent(t, eventsProcessor, trackID, ScannerROI, ROIActionEnter, eventTime)
+				processCVTrackEvent(t, eventsProcessor, trackID, ScannerROI, ROIActionExit, eventTime+100)
+				eventTime += 1000
+			}
+			for _, quantity := range table.scans {
+				processPosEvent(t, eventsProcessor, posItemEvent, scannedSoda(quantity, eventTime))
+				eventTime += 100
+			}
+
+			line := eventsProcessor.rttlogData[len(eventsProcessor.rttlogData)-1]
+			assert.Equal(t, 3.0, line.Quantity)
+			assert.Len(t, line.AssociatedCVItems, table.expectedItems)
+			assert.Equal(t, table.expectedConfirmed, line.CVConfirmed)
+			assert.Len(t, eventsProcessor.currentCVData, table.expectedItems)
+		})
+	}
+}
+
+func TestCVTrackMerge(t *testing.T) {
+	tables := []struct {
+		name             string
+		reacquiredAt     int64
+		expectedItems    int
+		expectedTrackIds []string
+	}{
+		{
+			name:             "reacquired within the merge window",
+			reacquiredAt:     3000,
+			expectedItems:    1,
+			expectedTrackIds: []string{"1", "2"},
+		},
+		{
+			name:             "reacquired after the merge window",
+			reacquiredAt:     5000,
+			expectedItems:    2,
+			expectedTrackIds: []string{"1"},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newCVTrackingTestProcessor(t)
+			processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+
+			processCVTrackEvent(t, eventsProcessor, "1", ScannerROI, ROIActionEnter, 2000)
+			processCVTrackEvent(t, eventsProcessor, "1", ScannerROI, ROIActionExit, 2500)
+			processCVTrackEvent(t, eventsProcessor, "2", BaggingROI, ROIActionEnter, table.reacquiredAt)
+
+			require.Len(t, eventsProcessor.currentCVData, table.expectedItems)
+			assert.Equal(t, table.expectedTrackIds, eventsProcessor.currentCVData[0].TrackIds)
+		})
+	}
+}
+
+func TestCVTrackSplit(t *testing.T) {
+	eventsProcessor := newCVTrackingTestProcessor(t)
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+
+	processCVTrackEvent(t, eventsProcessor, "1", ScannerROI, ROIActionEnter, 2000)
+	processCVTrackEvent(t, eventsProcessor, "1", ScannerROI, ROIActionExit, 2500)
+	// a second can enters the bagging area right after the first one left the scanner
+	processCVTrackEvent(t, eventsProcessor, "2", BaggingROI, ROIActionEnter, 3000)
+	require.Len(t, eventsProcessor.currentCVData, 1)
+
+	// the first track is still alive, the cans are two objects
+	processCVTrackEvent(t, eventsProcessor, "1", ScannerROI, ROIActionEnter, 3500)
+	require.Len(t, eventsProcessor.currentCVData, 2)
+	assert.Equal(t, []string{"2"}, eventsProcessor.currentCVData[0].TrackIds)
+	assert.Equal(t, "2", eventsProcessor.currentCVData[0].TrackId)
+	assert.Equal(t, []string{"1"}, eventsProcessor.currentCVData[1].TrackIds)
+
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedSoda(2, 4000))
+	line := eventsProcessor.rttlogData[len(eventsProcessor.rttlogData)-1]
+	assert.Len(t, line.AssociatedCVItems, 2)
+	assert.True(t, line.CVConfirmed)
+}
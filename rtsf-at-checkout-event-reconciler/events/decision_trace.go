@@ -0,0 +1,150 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"event-reconciler/clock"
+)
+
+const (
+	DecisionWeightInRange         = "weight-within-range"
+	DecisionWeightBelowMinimum    = "weight-below-item-minimum"
+	DecisionWeightAboveMaximum    = "weight-above-remaining-maximum"
+	DecisionWeightOverQuantity    = "weight-over-line-quantity"
+	DecisionWeightReassigned      = "weight-matched-with-other-line"
+	DecisionLineConfirmed         = "line-already-confirmed"
+	DecisionScaleConfirmed        = "scale-confirmed"
+	DecisionScaleNotConfirmed     = "scale-not-confirmed"
+	DecisionCVTimeAligned         = "cv-within-time-alignment"
+	DecisionCVTimeMisaligned      = "cv-outside-time-alignment"
+	DecisionCVLowConfidence       = "cv-below-confidence-threshold"
+	DecisionCVAlreadyAssociated   = "cv-already-associated"
+	DecisionCVQuantityReached     = "cv-line-quantity-reached"
+	DecisionRFIDMatched           = "rfid-upc-matched"
+	DecisionRFIDAlreadyAssociated = "rfid-already-associated"
+	DecisionRFIDQuantityReached   = "rfid-line-quantity-reached"
+	DecisionQuantityDecreased     = "released-on-quantity-decrease"
+
+	// maxLineDecisions is the number of decisions kept per RTT log line, the oldest ones are dropped first
+	maxLineDecisions = 100
+)
+
+// Decision explains why a sensor item was, or was not, associated with an RTT log line. For scale
+// decisions the measured and expected values are weights and the tolerance is the scale-to-scale
+// tolerance, for CV decisions they are the gap to the scan and CvTimeAlignment in milliseconds, or the
+// confidence of the detection and CVConfidenceThreshold.
+type Decision struct {
+	Detector    string  `json:"detector"`
+	Reference   string  `json:"reference"`
+	EventTime   int64   `json:"event_time"`
+	Accepted    bool    `json:"accepted"`
+	Reason      string  `json:"reason"`
+	Measured    float64 `json:"measured"`
+	ExpectedMin float64 `json:"expected_min"`
+	ExpectedMax float64 `json:"expected_max"`
+	Tolerance   float64 `json:"tolerance"`
+	DecidedTime int64   `json:"decided_time"`
+}
+
+// LineTrace is the decision trace of an RTT log line of the current basket
+type LineTrace struct {
+	LineIndex      int        `json:"line_index"`
+	ProductId      string     `json:"product_id"`
+	ProductName    string     `json:"product_name"`
+	Quantity       float64    `json:"quantity"`
+	EventTime      int64      `json:"event_time"`
+	ScaleConfirmed bool       `json:"scale_confirmed"`
+	CVConfirmed    bool       `json:"cv_confirmed"`
+	RFIDConfirmed  bool       `json:"rfid_confirmed"`
+	Decisions      []Decision `json:"decisions"`
+}
+
+// sameDecision tells whether the decisions only differ by the time they were made
+func sameDecision(a Decision, b Decision) bool {
+	a.DecidedTime = b.DecidedTime
+	return a == b
+}
+
+// traceDecision adds the decision to the trace of the line. The sensor items are evaluated again on
+// every event, so a decision repeating the latest one made about the same item is not added again.
+func (eventsProcessing *EventsProcessor) traceDecision(line *RTTLogEventEntry, decision Decision) {
+	for index := len(line.Decisions) - 1; index >= 0; index-- {
+		previous := line.Decisions[index]
+		if previous.Detector != decision.Detector || previous.Reference != decision.Reference || previous.EventTime != decision.EventTime {
+			continue
+		}
+		if sameDecision(previous, decision) {
+			return
+		}
+		break
+	}
+
+	if eventsProcessing.clock != nil {
+		decision.DecidedTime = clock.ToEventTime(eventsProcessing.clock.Now())
+	}
+	line.Decisions = append(line.Decisions, decision)
+	if len(line.Decisions) > maxLineDecisions {
+		line.Decisions = line.Decisions[len(line.Decisions)-maxLineDecisions:]
+	}
+}
+
+// scaleDecision is the decision about a scale reading, against the weight window of the line
+func scaleDecision(scaleItem *ScaleEventEntry, expectedMin float64, expectedMax float64, accepted bool, reason string) Decision {
+	return Decision{
+		Detector:    DetectorScale,
+		Reference:   scaleItem.ScaleId,
+		EventTime:   scaleItem.EventTime,
+		Accepted:    accepted,
+		Reason:      reason,
+		Measured:    scaleItem.Delta,
+		ExpectedMin: expectedMin,
+		ExpectedMax: expectedMax,
+	}
+}
+
+// traceScaleOutcome adds whether the weight of the scale readings associated with the line confirms it
+func (eventsProcessing *EventsProcessor) traceScaleOutcome(line *RTTLogEventEntry) {
+	decision := Decision{Detector: DetectorScale, Accepted: line.ScaleConfirmed, Reason: DecisionScaleNotConfirmed}
+	if line.ScaleConfirmed {
+		decision.Reason = DecisionScaleConfirmed
+	}
+	for _, scaleItem := range line.AssociatedScaleItems {
+		decision.Measured = decision.Measured + scaleItem.Delta
+	}
+
+	if eventsProcessing.rttlQuantityIsEach(*line) {
+		decision.ExpectedMin = line.ProductDetails.ExpectedMinWeight * line.Quantity
+		decision.ExpectedMax = line.ProductDetails.ExpectedMaxWeight * line.Quantity
+	} else {
+		decision.ExpectedMin = line.Quantity
+		decision.ExpectedMax = line.Quantity
+		decision.Tolerance = eventsProcessing.GetScaleToScaleTolerance()
+	}
+	eventsProcessing.traceDecision(line, decision)
+}
+
+// GetDecisionTrace returns the decision trace of the POS item lines of the current basket
+func (eventsProcessing *EventsProcessor) GetDecisionTrace() []LineTrace {
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+
+	traces := []LineTrace{}
+	for index, line := range eventsProcessing.rttlogData {
+		if line.EventType != posItemEvent {
+			continue
+		}
+		traces = append(traces, LineTrace{
+			LineIndex:      index,
+			ProductId:      line.ProductId,
+			ProductName:    line.ProductName,
+			Quantity:       line.Quantity,
+			EventTime:      line.EventTime,
+			ScaleConfirmed: line.ScaleConfirmed,
+			CVConfirmed:    line.CVConfirmed,
+			RFIDConfirmed:  line.RFIDConfirmed,
+			Decisions:      append([]Decision{}, line.Decisions...),
+		})
+	}
+	return traces
+}
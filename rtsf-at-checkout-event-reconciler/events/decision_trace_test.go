@@ -0,0 +1,153 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decisionReasons(decisions []Decision) []string {
+	reasons := []string{}
+	for _, decision := range decisions {
+		reasons = append(reasons, decision.Reason)
+	}
+	return reasons
+}
+
+func TestScaleDecisionTrace(t *testing.T) {
+	tables := []struct {
+		name            string
+		drops           []float64
+		expectedReasons []string
+		expectedAccept  []bool
+	}{
+		{
+			name:            "item dropped",
+			drops:           []float64{10.5},
+			expectedReasons: []string{DecisionWeightInRange, DecisionScaleConfirmed},
+			expectedAccept:  []bool{true, true},
+		},
+		{
+			name:            "light item dropped",
+			drops:           []float64{5},
+			expectedReasons: []string{DecisionWeightBelowMinimum, DecisionScaleNotConfirmed},
+			expectedAccept:  []bool{false, false},
+		},
+		{
+			name:            "heavy item dropped",
+			drops:           []float64{20},
+			expectedReasons: []string{DecisionWeightAboveMaximum, DecisionScaleNotConfirmed},
+			expectedAccept:  []bool{false, false},
+		},
+		{
+			name:            "second item dropped",
+			drops:           []float64{10.5, 10.5},
+			expectedReasons: []string{DecisionWeightInRange, DecisionScaleConfirmed, DecisionLineConfirmed},
+			expectedAccept:  []bool{true, true, false},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessing := EventsProcessor{}
+			BasketOpen(&eventsProcessing)
+			RTTLScanItemA(1, &eventsProcessing) // item weighs 10lbs
+
+			for index, drop := range table.drops {
+				scaleEvent := ScaleDrop(drop, &eventsProcessing)
+				scaleEvent.EventTime = int64(1000 + index)
+				eventsProcessing.scaleBasketReconciliation(scaleEvent)
+			}
+
+			decisions := eventsProcessing.rttlogData[len(eventsProcessing.rttlogData)-1].Decisions
+			assert.Equal(t, table.expectedReasons, decisionReasons(decisions))
+			for index, decision := range decisions {
+				assert.Equal(t, DetectorScale, decision.Detector)
+				assert.Equal(t, table.expectedAccept[index], decision.Accepted)
+			}
+		})
+	}
+}
+
+func TestCVDecisionTrace(t *testing.T) {
+	eventsProcessing := EventsProcessor{cvTimeAlignment: 5 * time.Second}
+	BasketOpen(&eventsProcessing)
+	eventsProcessing.currentCVData = []CVEventEntry{{
+		ObjectName: "123",
+		EventTime:  1559679660000,
+		ROIs:       map[string]ROILocation{ScannerROI: {LastAtLocation: 1559679660000}},
+	}, {
+		ObjectName: "456",
+		ROIs:       map[string]ROILocation{ScannerROI: {LastAtLocation: 1559679673000}},
+	}}
+
+	posEntry := RTTLogEventEntry{ProductName: "123", EventTime: 1559679673000, Quantity: 1}
+	eventsProcessing.cvBasketReconciliation(&posEntry)
+	// the reading is evaluated again on the next CV event, the same decision is not traced twice
+	eventsProcessing.cvBasketReconciliation(&posEntry)
+
+	require.Len(t, posEntry.Decisions, 1)
+	decision := posEntry.Decisions[0]
+	assert.Equal(t, DetectorCV, decision.Detector)
+	assert.Equal(t, "123", decision.Reference)
+	assert.False(t, decision.Accepted)
+	assert.Equal(t, DecisionCVTimeMisaligned, decision.Reason)
+	assert.Equal(t, 13000.0, decision.Measured)
+	assert.Equal(t, 5000.0, decision.Tolerance)
+
+	eventsProcessing.currentCVData[0].ROIs[ScannerROI] = ROILocation{LastAtLocation: 1559679672000}
+	eventsProcessing.cvBasketReconciliation(&posEntry)
+	assert.Equal(t, []string{DecisionCVTimeMisaligned, DecisionCVTimeAligned}, decisionReasons(posEntry.Decisions))
+	assert.True(t, posEntry.Decisions[1].Accepted)
+}
+
+func TestRFIDDecisionTrace(t *testing.T) {
+	eventsProcessing := EventsProcessor{}
+	BasketOpen(&eventsProcessing)
+	otherLine := RTTLogEventEntry{ProductId: "00000000735797"}
+	eventsProcessing.currentRFIDData = []RFIDEventEntry{
+		{EPC: "301400000047DAC000003039", UPC: "00000000735797", AssociatedRTTLEntry: &otherLine},
+		{EPC: "301400000047DAC000003040", UPC: "00000000735797"},
+		{EPC: "301400000047DAC000003041", UPC: "00000000735797"},
+		{EPC: "3014000000565D8000003039", UPC: "00000000884389"},
+	}
+
+	rttlEntry := RTTLogEventEntry{ProductId: "00000000735797", Quantity: 1}
+	require.NoError(t, eventsProcessing.rfidBasketReconciliation(&rttlEntry))
+
+	assert.Equal(t, []string{DecisionRFIDAlreadyAssociated, DecisionRFIDMatched, DecisionRFIDQuantityReached}, decisionReasons(rttlEntry.Decisions))
+	assert.Equal(t, "301400000047DAC000003040", rttlEntry.Decisions[1].Reference)
+	assert.True(t, rttlEntry.Decisions[1].Accepted)
+}
+
+func TestGetDecisionTrace(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, CVEventEntry{LaneId: "1", ObjectName: "Bananas", EventTime: 1900,
+		ROIs: map[string]ROILocation{ScannerROI: {AtLocation: true, LastAtLocation: 1900}}})
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+
+	trace := eventsProcessor.GetDecisionTrace()
+	require.Len(t, trace, 1)
+	assert.Equal(t, 1, trace[0].LineIndex)
+	assert.Equal(t, "00000000004011", trace[0].ProductId)
+	require.Len(t, trace[0].Decisions, 1)
+	assert.Equal(t, DecisionCVTimeAligned, trace[0].Decisions[0].Reason)
+	assert.NotZero(t, trace[0].Decisions[0].DecidedTime)
+}
+
+func TestDecisionTraceCapped(t *testing.T) {
+	eventsProcessing := EventsProcessor{}
+	line := RTTLogEventEntry{}
+	for index := 0; index < maxLineDecisions+10; index++ {
+		eventsProcessing.traceDecision(&line, Decision{Detector: DetectorCV, Reference: "123", EventTime: int64(index)})
+	}
+	require.Len(t, line.Decisions, maxLineDecisions)
+	assert.Equal(t, int64(10), line.Decisions[0].EventTime)
+}
@@ -4,16 +4,47 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
 	"github.com/gorilla/websocket"
 )
 
-func (eventsProcessing *EventsProcessor) formatWebsocketMessage(eventName string) []byte {
+// lastEventJSONString identifies the event that produced the websocket message,
+// so that clients can measure how long the event took to go through
+func (eventsProcessing *EventsProcessor) lastEventJSONString(reading dtos.BaseReading) string {
+	eventDetails := struct {
+		LaneId    string `json:"lane_id"`
+		EventTime int64  `json:"event_time"`
+	}{}
+	if reading.ObjectValue != nil {
+		_ = eventsProcessing.unmarshalObjValue(reading.ObjectValue, &eventDetails)
+	}
+
+	lastEvent, err := json.Marshal(struct {
+		Device    string `json:"device"`
+		Resource  string `json:"resource"`
+		LaneId    string `json:"lane_id"`
+		EventTime int64  `json:"event_time"`
+	}{
+		Device:    reading.DeviceName,
+		Resource:  reading.ResourceName,
+		LaneId:    eventDetails.LaneId,
+		EventTime: eventDetails.EventTime,
+	})
+	if err != nil {
+		return "{}"
+	}
+	return string(lastEvent)
+}
+
+func (eventsProcessing *EventsProcessor) formatWebsocketMessage(reading dtos.BaseReading) []byte {
 	var sb strings.Builder
 	sb.WriteString(`{
 		"positems": [`)
@@ -41,7 +72,7 @@ func (eventsProcessing *EventsProcessor) formatWebsocketMessage(eventName string
 	sb.WriteString(`,
 		"scalesuspectitems": [`)
 	idx := 0
-	for _, suspectItem := range eventsProcessing.suspectScaleItems {
+	for _, suspectItem := range eventsProcessing.getReportedSuspectScaleItems() {
 		if suspectItem.Delta > 0 {
 			if idx > 0 {
 				sb.WriteString(",")
@@ -86,10 +117,23 @@ func (eventsProcessing *EventsProcessor) formatWebsocketMessage(eventName string
 	rfidCount := len(eventsProcessing.currentRFIDData) + len(eventsProcessing.nextRFIDData)
 	scaleCount := len(eventsProcessing.scaleData)
 
+	sb.WriteString(`,
+		"scale_degraded": ` + strconv.FormatBool(eventsProcessing.isScaleDegraded()))
+
+	sb.WriteString(`,
+		"findings": ` + eventsProcessing.findingsJSONString())
+
+	sb.WriteString(`,
+		"last_event": ` + eventsProcessing.lastEventJSONString(reading))
+
+	reorderMetrics := eventsProcessing.GetReorderMetrics()
+
 	sb.WriteString(`,"stats": {
 		"cv_count": "` + fmt.Sprintf("%v", cvCount) + `",
 		"rfid_count": "` + fmt.Sprintf("%v", rfidCount) + `",
-		"scale_count": "` + fmt.Sprintf("%v", scaleCount) +
+		"scale_count": "` + fmt.Sprintf("%v", scaleCount) + `",
+		"late_count": "` + fmt.Sprintf("%v", reorderMetrics.Late) + `",
+		"dropped_count": "` + fmt.Sprintf("%v", reorderMetrics.Dropped) +
 		`"}`)
 
 	sb.WriteString("\n}")
@@ -103,14 +147,17 @@ func (eventsProcessing *EventsProcessor) formatWebsocketMessage(eventName string
 func (eventsProcessing *EventsProcessor) InitWebSocketConnection(service interfaces.ApplicationService, lc logger.LoggingClient) {
 	wsAddr := eventsProcessing.processConfig.WebSocketPort
 
+	// every client receives the messages so the UI and the event simulator can listen at the same time
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		var err error
 		eventsProcessing.upgrader.CheckOrigin = func(r *http.Request) bool { return true }
-		eventsProcessing.conn, err = eventsProcessing.upgrader.Upgrade(w, r, nil)
+		conn, err := eventsProcessing.upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			lc.Errorf("upgrade: %s", err)
 			return
 		}
+		eventsProcessing.mu.Lock()
+		eventsProcessing.conns[conn] = true
+		eventsProcessing.mu.Unlock()
 	})
 
 	go func() {
@@ -121,19 +168,21 @@ func (eventsProcessing *EventsProcessor) InitWebSocketConnection(service interfa
 	}()
 }
 
-func (eventsProcessing *EventsProcessor) sendWebsocketMessage(message []byte, edgexcontext interfaces.AppFunctionContext) {
-	lc := edgexcontext.LoggingClient()
-	if eventsProcessing.conn == nil {
+func (eventsProcessing *EventsProcessor) sendWebsocketMessage(message []byte, lc logger.LoggingClient) {
+	eventsProcessing.mu.Lock()
+	defer eventsProcessing.mu.Unlock()
+	if len(eventsProcessing.conns) == 0 {
 		lc.Trace("websocket not connected")
 		return
 	}
 
-	eventsProcessing.mu.Lock()
-	defer eventsProcessing.mu.Unlock()
 	lc.Tracef("websocket message: %v", string(message))
-	err := eventsProcessing.conn.WriteMessage(websocket.TextMessage, message)
-	if err != nil {
-		lc.Infof("write: %s", err)
-		return
+	for conn := range eventsProcessing.conns {
+		err := conn.WriteMessage(websocket.TextMessage, message)
+		if err != nil {
+			lc.Infof("write: %s", err)
+			conn.Close()
+			delete(eventsProcessing.conns, conn)
+		}
 	}
 }
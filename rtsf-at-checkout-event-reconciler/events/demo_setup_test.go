@@ -0,0 +1,61 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"event-reconciler/config"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatWebsocketMessage(t *testing.T) {
+	tables := []struct {
+		name   string
+		laneID string
+	}{
+		{name: "lane", laneID: "2"},
+		{name: "lane with quotes", laneID: `2", "injected": "\`},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{})
+			reading := dtos.NewObjectReading(
+				"pos-rest",
+				"pos-rest",
+				basketOpenEvent,
+				map[string]interface{}{
+					"lane_id":    table.laneID,
+					"basket_id":  "abc-012345-def",
+					"event_time": 1559679684,
+				},
+			)
+			simulateJson, _ := json.Marshal(reading)
+			simulateStruct := dtos.BaseReading{}
+			_ = json.Unmarshal(simulateJson, &simulateStruct)
+
+			message := eventsProcessor.formatWebsocketMessage(simulateStruct)
+
+			state := struct {
+				LastEvent struct {
+					Device    string `json:"device"`
+					Resource  string `json:"resource"`
+					LaneId    string `json:"lane_id"`
+					EventTime int64  `json:"event_time"`
+				} `json:"last_event"`
+			}{}
+			require.NoError(t, json.Unmarshal(message, &state))
+			assert.Equal(t, "pos-rest", state.LastEvent.Device)
+			assert.Equal(t, basketOpenEvent, state.LastEvent.Resource)
+			assert.Equal(t, table.laneID, state.LastEvent.LaneId)
+			assert.Equal(t, int64(1559679684), state.LastEvent.EventTime)
+			assert.Equal(t, message, eventsProcessor.GetCurrentStateMessage())
+		})
+	}
+}
@@ -3,89 +3,100 @@
 
 package events
 
-import "github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+import (
+	"event-reconciler/statemachine"
+)
 
 const (
-	posItemEvent        = "scanned-item"
-	basketOpenEvent     = "basket-open"
-	basketCloseEvent    = "basket-close"
-	paymentStartEvent   = "payment-start"
-	paymentSuccessEvent = "payment-success"
-	removeItemEvent     = "remove-item"
-	scaleItemEvent      = "weight"
-	cvRoiEvent          = "cv-roi-event"
-	rfidRoiEvent        = "rfid-roi-event"
+	posItemEvent            = "scanned-item"
+	basketOpenEvent         = "basket-open"
+	basketCloseEvent        = "basket-close"
+	paymentStartEvent       = "payment-start"
+	paymentSuccessEvent     = "payment-success"
+	paymentFailureEvent     = "payment-failure"
+	removeItemEvent         = "remove-item"
+	attendantOverrideEvent  = "attendant-override"
+	suspendTransactionEvent = "suspend-transaction"
+	resumeTransactionEvent  = "resume-transaction"
+	voidTransactionEvent    = "void-transaction"
+	quantityChangeEvent     = "quantity-change"
+	priceOverrideEvent      = "price-override"
+	discountAppliedEvent    = "discount-applied"
+	scaleItemEvent          = "weight"
+	scaleHealthEvent        = "scale-health"
+	cvRoiEvent              = "cv-roi-event"
+	rfidRoiEvent            = "rfid-roi-event"
+
+	// maxRejectedTransitions is the number of rejected transitions kept for the checkout state endpoint
+	maxRejectedTransitions = 50
 )
 
-func (eventsProcessing *EventsProcessor) ResetEventsOccurrence() {
-	events := []string{posItemEvent, basketOpenEvent, basketCloseEvent, paymentStartEvent, paymentSuccessEvent, removeItemEvent}
-	eventsProcessing.eventOccurred = make(map[string]bool)
-	for _, evt := range events {
-		eventsProcessing.eventOccurred[evt] = false
+// CheckoutState is the current state of the checkout along with the latest rejected transitions
+type CheckoutState struct {
+	State               string                       `json:"state"`
+	RejectedTransitions []statemachine.RejectedError `json:"rejected_transitions"`
+	// RejectedCount is the number of transitions rejected since the reconciler started
+	RejectedCount         int64    `json:"rejected_count"`
+	SuspendedTransactions []string `json:"suspended_transactions"`
+}
+
+// SetStateMachineDefinition replaces the lifecycle of the checkout, the checkout is back in its initial state
+func (eventsProcessing *EventsProcessor) SetStateMachineDefinition(definition statemachine.Definition) error {
+	guards := map[string]statemachine.Guard{
+		"has_items":           func() bool { return eventsProcessing.hasPOSItems },
+		findingsResolvedGuard: eventsProcessing.findingsResolved,
+	}
+	actions := map[string]statemachine.Action{
+		"scan_item":   func() { eventsProcessing.hasPOSItems = true },
+		"clear_items": func() { eventsProcessing.hasPOSItems = false },
+	}
+
+	checkoutState, err := statemachine.New(definition, guards, actions)
+	if err != nil {
+		return err
 	}
+	eventsProcessing.checkoutState = checkoutState
+	eventsProcessing.hasPOSItems = false
+	eventsProcessing.pendingPaymentSuccess = nil
+	return nil
 }
 
-func (eventsProcessing *EventsProcessor) checkEventOrderValid(event string, edgexcontext interfaces.AppFunctionContext) bool {
-	eventValid := true
-	switch event {
-	case basketOpenEvent:
-		if eventsProcessing.eventOccurred[basketOpenEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.eventOccurred[basketOpenEvent] = true
-			eventsProcessing.eventOccurred[basketCloseEvent] = false
+// ResetCheckoutState moves the checkout back to the initial state of its lifecycle
+func (eventsProcessing *EventsProcessor) ResetCheckoutState() {
+	eventsProcessing.checkoutState.Reset()
+	eventsProcessing.hasPOSItems = false
+	eventsProcessing.pendingPaymentSuccess = nil
+}
 
-			// add to clear the UI for the demo
-			// sendWebsocketMessage([]byte("{\"positems\": [], \"cvsuspectitems\": [], \"rfidsuspectitems\": [], \"scalesuspectitems\": [] }"), edgexcontext)
-		}
-	case scaleItemEvent:
-		if !eventsProcessing.eventOccurred[basketOpenEvent] || eventsProcessing.eventOccurred[basketCloseEvent] || eventsProcessing.eventOccurred[paymentStartEvent] || eventsProcessing.eventOccurred[paymentSuccessEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.eventOccurred[scaleItemEvent] = true
-		}
-	case posItemEvent:
-		if !eventsProcessing.eventOccurred[basketOpenEvent] || eventsProcessing.eventOccurred[basketCloseEvent] || eventsProcessing.eventOccurred[paymentStartEvent] || eventsProcessing.eventOccurred[paymentSuccessEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.eventOccurred[posItemEvent] = true
-		}
-	case removeItemEvent:
-		if !eventsProcessing.eventOccurred[basketOpenEvent] || eventsProcessing.eventOccurred[basketCloseEvent] || !eventsProcessing.eventOccurred[posItemEvent] || eventsProcessing.eventOccurred[paymentStartEvent] || eventsProcessing.eventOccurred[paymentSuccessEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.eventOccurred[removeItemEvent] = true
-		}
-	case paymentStartEvent:
-		if !eventsProcessing.eventOccurred[basketOpenEvent] || !eventsProcessing.eventOccurred[posItemEvent] || eventsProcessing.eventOccurred[paymentStartEvent] || eventsProcessing.eventOccurred[paymentSuccessEvent] || eventsProcessing.eventOccurred[basketCloseEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.eventOccurred[paymentStartEvent] = true
-		}
-	case paymentSuccessEvent:
-		if !eventsProcessing.eventOccurred[basketOpenEvent] || !eventsProcessing.eventOccurred[posItemEvent] || !eventsProcessing.eventOccurred[paymentStartEvent] || eventsProcessing.eventOccurred[paymentSuccessEvent] || eventsProcessing.eventOccurred[basketCloseEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.eventOccurred[paymentSuccessEvent] = true
-			eventsProcessing.eventOccurred[paymentStartEvent] = false
-		}
-	case basketCloseEvent:
-		if !eventsProcessing.eventOccurred[basketOpenEvent] || eventsProcessing.eventOccurred[paymentStartEvent] {
-			eventValid = false
-		} else {
-			eventsProcessing.ResetEventsOccurrence()
-			eventsProcessing.eventOccurred[basketCloseEvent] = true
+// GetCheckoutState returns the state of the checkout, the latest rejected transitions and the suspended transactions
+func (eventsProcessing *EventsProcessor) GetCheckoutState() CheckoutState {
+	eventsProcessing.processMu.Lock()
+	suspendedTransactions := eventsProcessing.getSuspendedTransactionIDs()
+	eventsProcessing.processMu.Unlock()
 
-			// add to clear the UI for the demo
-			// sendWebsocketMessage([]byte("{\"positems\": [], \"cvsuspectitems\": [], \"rfidsuspectitems\": [], \"scalesuspectitems\": [] }"), edgexcontext)
-		}
-	case cvRoiEvent:
-		break
-	case rfidRoiEvent:
-		break
-	default:
-		eventValid = false
+	eventsProcessing.rejectedMu.Lock()
+	defer eventsProcessing.rejectedMu.Unlock()
+
+	return CheckoutState{
+		State:                 eventsProcessing.checkoutState.State(),
+		RejectedTransitions:   append([]statemachine.RejectedError{}, eventsProcessing.rejectedTransitions...),
+		RejectedCount:         eventsProcessing.rejectedCount,
+		SuspendedTransactions: suspendedTransactions,
 	}
+}
 
-	return eventValid
+// checkEventTransition moves the checkout to its next state, the event is not processed when the
+// transition is rejected
+func (eventsProcessing *EventsProcessor) checkEventTransition(event string) error {
+	err := eventsProcessing.checkoutState.Fire(event)
+	if rejected, ok := err.(*statemachine.RejectedError); ok {
+		eventsProcessing.rejectedMu.Lock()
+		eventsProcessing.rejectedCount++
+		eventsProcessing.rejectedTransitions = append(eventsProcessing.rejectedTransitions, *rejected)
+		if len(eventsProcessing.rejectedTransitions) > maxRejectedTransitions {
+			eventsProcessing.rejectedTransitions = eventsProcessing.rejectedTransitions[1:]
+		}
+		eventsProcessing.rejectedMu.Unlock()
+	}
+	return err
 }
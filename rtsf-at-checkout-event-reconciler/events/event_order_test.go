@@ -5,8 +5,12 @@ package events
 
 import (
 	"event-reconciler/config"
+	"event-reconciler/statemachine"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckEventOrderValid(t *testing.T) {
@@ -65,15 +69,65 @@ func TestCheckEventOrderValid(t *testing.T) {
 			eventsList:     []string{basketOpenEvent, posItemEvent, paymentStartEvent, removeItemEvent},
 			expectedResult: false,
 		},
+		{
+			name:           "payment failure and retry",
+			eventsList:     []string{basketOpenEvent, posItemEvent, paymentStartEvent, paymentFailureEvent, posItemEvent, paymentStartEvent, paymentSuccessEvent, basketCloseEvent},
+			expectedResult: true,
+		},
+		{
+			name:           "attendant override during payment",
+			eventsList:     []string{basketOpenEvent, posItemEvent, paymentStartEvent, attendantOverrideEvent, removeItemEvent, basketCloseEvent},
+			expectedResult: true,
+		},
+		{
+			name:           "suspend and resume",
+			eventsList:     []string{basketOpenEvent, posItemEvent, suspendTransactionEvent, resumeTransactionEvent, posItemEvent, paymentStartEvent, paymentSuccessEvent, basketCloseEvent},
+			expectedResult: true,
+		},
+		{
+			name:           "new basket while a transaction is suspended",
+			eventsList:     []string{basketOpenEvent, posItemEvent, suspendTransactionEvent, basketOpenEvent, posItemEvent},
+			expectedResult: true,
+		},
+		{
+			name:           "resume during a basket",
+			eventsList:     []string{basketOpenEvent, posItemEvent, resumeTransactionEvent},
+			expectedResult: false,
+		},
+		{
+			name:           "scanned item while suspended",
+			eventsList:     []string{basketOpenEvent, posItemEvent, suspendTransactionEvent, posItemEvent},
+			expectedResult: false,
+		},
+		{
+			name:           "price events during a basket",
+			eventsList:     []string{basketOpenEvent, posItemEvent, quantityChangeEvent, priceOverrideEvent, discountAppliedEvent, paymentStartEvent},
+			expectedResult: true,
+		},
+		{
+			name:           "price override during payment",
+			eventsList:     []string{basketOpenEvent, posItemEvent, paymentStartEvent, priceOverrideEvent},
+			expectedResult: false,
+		},
+		{
+			name:           "void then new basket",
+			eventsList:     []string{basketOpenEvent, posItemEvent, paymentStartEvent, voidTransactionEvent, basketOpenEvent, posItemEvent},
+			expectedResult: true,
+		},
+		{
+			name:           "sensor events before basket-open",
+			eventsList:     []string{cvRoiEvent, rfidRoiEvent, scaleHealthEvent},
+			expectedResult: true,
+		},
 	}
 
 	for _, table := range tables {
 		config := config.ReconcilerConfig{}
 		processor := NewEventsProcessor(time.Second, &config)
-		processor.ResetEventsOccurrence()
+		processor.ResetCheckoutState()
 		var eventValid bool
 		for _, event := range table.eventsList {
-			eventValid = processor.checkEventOrderValid(event, nil)
+			eventValid = processor.checkEventTransition(event) == nil
 			if !eventValid {
 				break
 			}
@@ -84,3 +138,21 @@ func TestCheckEventOrderValid(t *testing.T) {
 	}
 
 }
+
+func TestCheckEventTransitionRejectedReason(t *testing.T) {
+	processor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{})
+
+	assert.NoError(t, processor.checkEventTransition(basketOpenEvent))
+	assert.EqualError(t, processor.checkEventTransition(paymentStartEvent), "payment-start rejected in state open: guard has_items failed")
+	assert.EqualError(t, processor.checkEventTransition(paymentSuccessEvent), "payment-success rejected in state open: no transition from this state")
+	assert.EqualError(t, processor.checkEventTransition("hello"), "hello rejected in state open: unknown event")
+
+	checkoutState := processor.GetCheckoutState()
+	assert.Equal(t, "open", checkoutState.State)
+	require.Len(t, checkoutState.RejectedTransitions, 3)
+	assert.Equal(t, int64(3), checkoutState.RejectedCount)
+	assert.Equal(t, statemachine.RejectedError{Event: paymentStartEvent, State: "open", Reason: "guard has_items failed", Guard: "has_items"}, checkoutState.RejectedTransitions[0])
+
+	processor.ResetCheckoutState()
+	assert.Equal(t, "idle", processor.GetCheckoutState().State)
+}
@@ -0,0 +1,192 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/google/uuid"
+
+	"event-reconciler/clock"
+)
+
+const (
+	DetectorScale = "scale"
+	DetectorCV    = "cv"
+	DetectorRFID  = "rfid"
+	DetectorRule  = "rule"
+	// DetectorFusion reports the lines the sensors together do not confirm with enough confidence
+	DetectorFusion = "fusion"
+
+	ReasonUnscannedCVItem    = "unscanned-cv-item"
+	ReasonUnscannedRFIDItem  = "unscanned-rfid-item"
+	ReasonUnexpectedWeight   = "unexpected-scale-weight"
+	ReasonHighValueOverride  = "high-value-price-override"
+	ReasonLowConfidenceLine  = "low-confidence-line"
+	ReasonSerialSoldTwice    = "serial-sold-twice"
+	ReasonSoldTagReentered   = "sold-tag-reentered"
+	ReasonUnpaidItemLeaving  = "unpaid-item-leaving"
+	findingProfileName       = "Finding"
+	findingDeviceName        = "event-reconciler"
+	findingResourceName      = "suspect-finding"
+	findingEvidenceSeparator = "|"
+)
+
+// findingNamespace scopes the name based UUIDs of the findings
+var findingNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("urn:rtsf-at-checkout:finding"))
+
+// Finding is a suspect item or rule violation of a transaction, its id only depends on the transaction
+// and the evidence so that a finding reported again, i.e. on a payment retry, keeps its id
+type Finding struct {
+	Id               string            `json:"id"`
+	LaneId           string            `json:"lane_id"`
+	TransactionId    string            `json:"transaction_id"`
+	Detector         string            `json:"detector"`
+	ReasonCode       string            `json:"reason_code"`
+	Severity         string            `json:"severity"`
+	ProductName      string            `json:"product_name"`
+	GTIN             string            `json:"gtin"`
+	Serial           string            `json:"serial"`
+	EstimatedPrice   float64           `json:"estimated_price"`
+	Evidence         []FindingEvidence `json:"evidence"`
+	EventTime        int64             `json:"event_time"`
+	DetectedTime     int64             `json:"detected_time"`
+	Status           string            `json:"status"`
+	AcknowledgedBy   string            `json:"acknowledged_by"`
+	AcknowledgedTime int64             `json:"acknowledged_time"`
+	Resolution       string            `json:"resolution"`
+	ResolvedBy       string            `json:"resolved_by"`
+	ResolvedTime     int64             `json:"resolved_time"`
+	Note             string            `json:"note"`
+}
+
+// FindingEvidence references the checkout event a finding is based on
+type FindingEvidence struct {
+	Event     string `json:"event"`
+	Reference string `json:"reference"`
+	EventTime int64  `json:"event_time"`
+}
+
+// newFinding builds the finding, its id is based on the events and references of the evidence along with
+// the identity telling apart the evidence of the same references. The event times of the evidence are left
+// out as they follow the items moving between the regions of interest.
+func newFinding(laneID string, transactionID string, detector string, reasonCode string, identity string, evidence ...FindingEvidence) Finding {
+	references := []string{laneID, transactionID, detector, reasonCode, identity}
+	var eventTime int64
+	for _, item := range evidence {
+		references = append(references, item.Event, item.Reference)
+		if eventTime == 0 || item.EventTime < eventTime {
+			eventTime = item.EventTime
+		}
+	}
+
+	return Finding{
+		Id:            uuid.NewSHA1(findingNamespace, []byte(strings.Join(references, findingEvidenceSeparator))).String(),
+		LaneId:        laneID,
+		TransactionId: transactionID,
+		Detector:      detector,
+		ReasonCode:    reasonCode,
+		Evidence:      evidence,
+		EventTime:     eventTime,
+	}
+}
+
+// buildFindings turns the suspect lists of the transaction into findings
+func (eventsProcessing *EventsProcessor) buildFindings(suspectList SuspectLists, paymentStart RTTLogEventEntry) []Finding {
+	findings := []Finding{}
+	transactionID := transactionID(paymentStart)
+	laneID := func(suspectLaneID string) string {
+		if len(suspectLaneID) > 0 {
+			return suspectLaneID
+		}
+		return paymentStart.LaneId
+	}
+
+	for _, cvItem := range suspectList.CVSuspect {
+		finding := newFinding(laneID(cvItem.LaneId), transactionID, DetectorCV, ReasonUnscannedCVItem, fmt.Sprintf("%d", cvItem.FirstSeenTime),
+			FindingEvidence{Event: cvRoiEvent, Reference: cvItem.ObjectName, EventTime: cvItem.EventTime})
+		finding.ProductName, finding.GTIN, finding.EstimatedPrice, finding.Severity = cvItem.ObjectName, cvItem.GTIN, cvItem.EstimatedPrice, cvItem.Severity
+		findings = append(findings, finding)
+	}
+
+	for _, rfidItem := range suspectList.RFIDSuspect {
+		finding := newFinding(laneID(rfidItem.LaneId), transactionID, DetectorRFID, ReasonUnscannedRFIDItem, "",
+			FindingEvidence{Event: rfidRoiEvent, Reference: rfidItem.EPC, EventTime: rfidItem.EventTime})
+		finding.ProductName, finding.GTIN, finding.EstimatedPrice, finding.Severity = rfidItem.ProductName, rfidItem.GTIN, rfidItem.EstimatedPrice, rfidItem.Severity
+		finding.Serial = rfidItem.Serial
+		findings = append(findings, finding)
+	}
+
+	// the earlier sale of the serial is part of the evidence
+	soldFindings := []struct {
+		reasonCode string
+		items      []RFIDEventEntry
+	}{
+		{reasonCode: ReasonSerialSoldTwice, items: suspectList.RFIDSoldTwice},
+		{reasonCode: ReasonSoldTagReentered, items: suspectList.RFIDPreviouslySold},
+	}
+	for _, soldFinding := range soldFindings {
+		for _, rfidItem := range soldFinding.items {
+			finding := newFinding(laneID(rfidItem.LaneId), transactionID, DetectorRFID, soldFinding.reasonCode, "",
+				FindingEvidence{Event: rfidRoiEvent, Reference: rfidItem.EPC, EventTime: rfidItem.EventTime},
+				FindingEvidence{Event: basketCloseEvent, Reference: rfidItem.SoldTransactionId, EventTime: rfidItem.SoldTime})
+			finding.ProductName, finding.GTIN, finding.EstimatedPrice, finding.Severity = rfidItem.ProductName, rfidItem.GTIN, rfidItem.EstimatedPrice, rfidItem.Severity
+			finding.Serial = rfidItem.Serial
+			findings = append(findings, finding)
+		}
+	}
+
+	scaleTimes := make([]int64, 0, len(suspectList.ScaleSuspect))
+	for eventTime := range suspectList.ScaleSuspect {
+		scaleTimes = append(scaleTimes, eventTime)
+	}
+	sort.Slice(scaleTimes, func(i, j int) bool { return scaleTimes[i] < scaleTimes[j] })
+	for _, eventTime := range scaleTimes {
+		scaleItem := suspectList.ScaleSuspect[eventTime]
+		finding := newFinding(laneID(scaleItem.LaneId), transactionID, DetectorScale, ReasonUnexpectedWeight, fmt.Sprintf("%d", scaleItem.EventTime),
+			FindingEvidence{Event: scaleItemEvent, Reference: scaleItem.ScaleId, EventTime: scaleItem.EventTime})
+		finding.ProductName, finding.GTIN, finding.EstimatedPrice, finding.Severity = scaleItem.ProductName, scaleItem.GTIN, scaleItem.EstimatedPrice, scaleItem.Severity
+		findings = append(findings, finding)
+	}
+
+	for _, override := range suspectList.PriceOverride {
+		finding := newFinding(paymentStart.LaneId, transactionID, DetectorRule, ReasonHighValueOverride, fmt.Sprintf("%d", override.EventTime),
+			FindingEvidence{Event: posItemEvent, Reference: override.ProductId, EventTime: override.EventTime})
+		finding.ProductName, finding.GTIN, finding.EstimatedPrice, finding.Severity = override.ProductName, override.ProductId, override.ValueAtRisk, override.Severity
+		findings = append(findings, finding)
+	}
+
+	for _, line := range suspectList.LowConfidence {
+		finding := newFinding(paymentStart.LaneId, transactionID, DetectorFusion, ReasonLowConfidenceLine, fmt.Sprintf("%d", line.EventTime),
+			FindingEvidence{Event: posItemEvent, Reference: line.ProductId, EventTime: line.EventTime})
+		estimatedPrice := line.PaidPrice * line.Quantity
+		finding.ProductName, finding.GTIN, finding.EstimatedPrice, finding.Severity = line.ProductName, line.ProductId, estimatedPrice, eventsProcessing.severity(estimatedPrice)
+		findings = append(findings, finding)
+	}
+
+	detectedTime := clock.ToEventTime(eventsProcessing.clock.Now())
+	for index := range findings {
+		findings[index].DetectedTime = detectedTime
+	}
+	return findings
+}
+
+// publishFindings publishes every finding as its own EdgeX event on FindingsTopic, the id of the
+// event is the id of the finding
+func (eventsProcessing *EventsProcessor) publishFindings(findings []Finding, edgexcontext interfaces.AppFunctionContext) error {
+	for _, finding := range findings {
+		event := dtos.NewEvent(findingProfileName, findingDeviceName, findingResourceName)
+		event.Id = finding.Id
+		event.AddObjectReading(findingResourceName, finding)
+		if err := edgexcontext.PublishWithTopic(eventsProcessing.processConfig.FindingsTopic, event, common.ContentTypeJSON); err != nil {
+			return fmt.Errorf("failed to publish finding %s: %v", finding.Id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"event-reconciler/clock"
+	"event-reconciler/config"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFindingTestProcessor() *EventsProcessor {
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{PriceOverrideLimit: 5, SeverityMediumValue: 10, SeverityHighValue: 50})
+	eventsProcessor.SetClock(clock.NewManualClock(time.UnixMilli(1700000000000)))
+	eventsProcessor.catalog = testCatalog
+	eventsProcessor.currentCVData = []CVEventEntry{{LaneId: "1", ObjectName: "Red Wine", EventTime: 1000, FirstSeenTime: 1000, ROIs: map[string]ROILocation{}}}
+	eventsProcessor.currentRFIDData = []RFIDEventEntry{{EPC: "3014000000565D8000003039", UPC: "00000000884389", ProductName: "Red Wine", EventTime: 1100, ROIs: map[string]ROILocation{}}}
+	eventsProcessor.suspectScaleItems[1200] = &ScaleEventEntry{LaneId: "1", ScaleId: "bagging", Delta: 3.05, EventTime: 1200}
+	eventsProcessor.rttlogData = []RTTLogEventEntry{
+		{ProductId: "00000000735797", ProductName: "Steak", Quantity: 1, ListPrice: 20, PaidPrice: 10, PriceOverridden: true, HighValueOverride: true, EventTime: 900},
+	}
+	return eventsProcessor
+}
+
+func TestBuildFindings(t *testing.T) {
+	eventsProcessor := newFindingTestProcessor()
+	paymentStart := RTTLogEventEntry{LaneId: "1", BasketId: "abc", TransactionId: "t-1", EventTime: 2000}
+
+	findings := eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), paymentStart)
+	require.Len(t, findings, 4)
+
+	expected := []struct {
+		detector   string
+		reasonCode string
+		reference  string
+		eventTime  int64
+	}{
+		{detector: DetectorCV, reasonCode: ReasonUnscannedCVItem, reference: "Red Wine", eventTime: 1000},
+		{detector: DetectorRFID, reasonCode: ReasonUnscannedRFIDItem, reference: "3014000000565D8000003039", eventTime: 1100},
+		{detector: DetectorScale, reasonCode: ReasonUnexpectedWeight, reference: "bagging", eventTime: 1200},
+		{detector: DetectorRule, reasonCode: ReasonHighValueOverride, reference: "00000000735797", eventTime: 900},
+	}
+	for index, finding := range findings {
+		_, err := uuid.Parse(finding.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "1", finding.LaneId)
+		assert.Equal(t, "t-1", finding.TransactionId)
+		assert.Equal(t, expected[index].detector, finding.Detector)
+		assert.Equal(t, expected[index].reasonCode, finding.ReasonCode)
+		require.Len(t, finding.Evidence, 1)
+		assert.Equal(t, expected[index].reference, finding.Evidence[0].Reference)
+		assert.Equal(t, expected[index].eventTime, finding.EventTime)
+		assert.Equal(t, int64(1700000000000), finding.DetectedTime)
+	}
+	assert.Equal(t, "00000000884389", findings[0].GTIN)
+	assert.Equal(t, SeverityMedium, findings[0].Severity)
+	assert.Equal(t, "Red Wine", findings[2].ProductName)
+}
+
+func TestFindingIDs(t *testing.T) {
+	eventsProcessor := newFindingTestProcessor()
+	paymentStart := RTTLogEventEntry{LaneId: "1", BasketId: "abc", TransactionId: "t-1", EventTime: 2000}
+	findings := eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), paymentStart)
+
+	// a payment retry reports the same findings later on, after the unscanned items moved
+	eventsProcessor.SetClock(clock.NewManualClock(time.UnixMilli(1700000060000)))
+	updateCVObjectLocation(CVEventEntry{ObjectName: "Red Wine", ROIName: BaggingROI, ROIAction: ROIActionEnter, EventTime: 2500},
+		&eventsProcessor.currentCVData[0], context.LoggingClient())
+	updateRFIDObjectLocation(RFIDEventEntry{EPC: "3014000000565D8000003039", ROIName: BaggingROI, ROIAction: ROIActionEnter, EventTime: 2600},
+		&eventsProcessor.currentRFIDData[0], context.LoggingClient())
+	paymentStart.EventTime = 3000
+	retried := eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), paymentStart)
+	require.Len(t, retried, len(findings))
+	ids := make(map[string]bool)
+	for index := range findings {
+		assert.Equal(t, findings[index].Id, retried[index].Id)
+		ids[findings[index].Id] = true
+	}
+	assert.Equal(t, int64(2500), retried[0].EventTime)
+	assert.Equal(t, int64(2600), retried[1].EventTime)
+	assert.Len(t, ids, len(findings))
+
+	// the same suspects in another transaction are other findings
+	paymentStart.TransactionId = "t-2"
+	other := eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), paymentStart)
+	for index := range findings {
+		assert.NotEqual(t, findings[index].Id, other[index].Id)
+	}
+}
+
+func TestPublishFindings(t *testing.T) {
+	eventsProcessor := newFindingTestProcessor()
+	eventsProcessor.processConfig.FindingsTopic = "findings"
+
+	assert.NoError(t, eventsProcessor.publishFindings([]Finding{}, context))
+
+	// the test context has no message bus to publish to
+	findings := eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), RTTLogEventEntry{LaneId: "1", BasketId: "abc"})
+	assert.Error(t, eventsProcessor.publishFindings(findings, context))
+}
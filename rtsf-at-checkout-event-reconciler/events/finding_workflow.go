@@ -0,0 +1,299 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+
+	"event-reconciler/clock"
+	"event-reconciler/journal"
+	"event-reconciler/statemachine"
+)
+
+const (
+	FindingOpen         = "open"
+	FindingAcknowledged = "acknowledged"
+	FindingResolved     = "resolved"
+
+	ResolutionItemScanned      = "item-scanned"
+	ResolutionCustomerDeclined = "customer-declined"
+	ResolutionFalsePositive    = "false-positive"
+	ResolutionTheft            = "theft"
+
+	// maxStoredFindings is the number of findings kept, the oldest ones are dropped first
+	maxStoredFindings = 1000
+	// findingUpdateResource is the resource of the websocket message sent when a finding is updated
+	findingUpdateResource = "finding-update"
+	// findingsResolvedGuard is the state machine guard of payment-success on the findings of the transaction
+	findingsResolvedGuard = "findings_resolved"
+	// findingRecordType is the record type of the journal lines of the findings
+	findingRecordType = "finding"
+)
+
+// ErrFindingNotFound is returned when the finding to update is not stored
+var ErrFindingNotFound = errors.New("finding not found")
+
+// FindingResolution is the struct for the resolution of a finding by an attendant
+type FindingResolution struct {
+	Id         string `json:"id"`
+	Resolution string `json:"resolution"`
+	EmployeeId string `json:"employee_id"`
+	Note       string `json:"note"`
+}
+
+// FindingRecord is the journal line of a finding, appended when the finding is reported, acknowledged
+// and resolved, the latest line of a finding holding its status
+type FindingRecord struct {
+	journal.Entry
+	Finding Finding `json:"finding"`
+}
+
+func isFindingResolution(resolution string) bool {
+	switch resolution {
+	case ResolutionItemScanned, ResolutionCustomerDeclined, ResolutionFalsePositive, ResolutionTheft:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordFindings stores and journals the findings reported at payment-start, a finding reported again
+// keeps its acknowledgement and resolution. The findings are returned with their status.
+func (eventsProcessing *EventsProcessor) recordFindings(findings []Finding, lc logger.LoggingClient) []Finding {
+	for index, finding := range findings {
+		if stored, ok := eventsProcessing.findings[finding.Id]; ok {
+			findings[index] = *stored
+			continue
+		}
+
+		finding.Status = FindingOpen
+		eventsProcessing.storeFinding(finding)
+		eventsProcessing.journalFinding(finding, lc)
+		findings[index] = finding
+	}
+	return findings
+}
+
+// storeFinding keeps the finding along with the latest ones, the oldest findings being dropped first
+func (eventsProcessing *EventsProcessor) storeFinding(finding Finding) {
+	if eventsProcessing.findings == nil {
+		eventsProcessing.findings = make(map[string]*Finding)
+	}
+
+	eventsProcessing.findings[finding.Id] = &finding
+	eventsProcessing.findingIDs = append(eventsProcessing.findingIDs, finding.Id)
+	for len(eventsProcessing.findingIDs) > maxStoredFindings {
+		delete(eventsProcessing.findings, eventsProcessing.findingIDs[0])
+		eventsProcessing.findingIDs = eventsProcessing.findingIDs[1:]
+	}
+}
+
+// journalFinding appends the finding with its current status to the journal, if any
+func (eventsProcessing *EventsProcessor) journalFinding(finding Finding, lc logger.LoggingClient) {
+	if eventsProcessing.journal == nil {
+		return
+	}
+
+	now := clock.ToEventTime(eventsProcessing.clock.Now())
+	record := FindingRecord{
+		Entry: journal.Entry{
+			RecordType:    findingRecordType,
+			LaneId:        finding.LaneId,
+			TransactionId: finding.TransactionId,
+			StartTime:     now,
+			EndTime:       now,
+			ProductIds:    []string{},
+			SoldSerials:   []string{},
+		},
+		Finding: finding,
+	}
+	if len(finding.GTIN) > 0 {
+		record.ProductIds = append(record.ProductIds, finding.GTIN)
+	}
+	if err := eventsProcessing.journal.Append(record); err != nil {
+		lc.Errorf("Failed to journal finding %s: %v", finding.Id, err)
+	}
+}
+
+// loadFindings stores again the journaled findings which are not resolved, in the order they were reported
+func (eventsProcessing *EventsProcessor) loadFindings() error {
+	lines, err := eventsProcessing.journal.Find(journal.Query{RecordType: findingRecordType})
+	if err != nil {
+		return err
+	}
+
+	findings := make(map[string]Finding)
+	findingIDs := []string{}
+	for _, line := range lines {
+		record := FindingRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+		if _, ok := findings[record.Finding.Id]; !ok {
+			findingIDs = append(findingIDs, record.Finding.Id)
+		}
+		findings[record.Finding.Id] = record.Finding
+	}
+
+	for _, id := range findingIDs {
+		if findings[id].Status != FindingResolved {
+			eventsProcessing.storeFinding(findings[id])
+		}
+	}
+	return nil
+}
+
+// GetFindings returns the findings of the lane, or of every lane when laneID is empty, in the order they were
+// reported. Resolved findings are only returned along with the unresolved ones when includeResolved is set.
+func (eventsProcessing *EventsProcessor) GetFindings(laneID string, includeResolved bool) []Finding {
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+
+	findings := []Finding{}
+	for _, id := range eventsProcessing.findingIDs {
+		finding := eventsProcessing.findings[id]
+		if len(laneID) > 0 && finding.LaneId != laneID {
+			continue
+		}
+		if finding.Status == FindingResolved && !includeResolved {
+			continue
+		}
+		findings = append(findings, *finding)
+	}
+	return findings
+}
+
+// AcknowledgeFinding records that an attendant is taking care of the finding
+func (eventsProcessing *EventsProcessor) AcknowledgeFinding(id string, employeeID string, lc logger.LoggingClient) (Finding, error) {
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+
+	finding, ok := eventsProcessing.findings[id]
+	if !ok {
+		return Finding{}, ErrFindingNotFound
+	}
+	if finding.Status == FindingResolved {
+		return *finding, fmt.Errorf("finding %s is already resolved", id)
+	}
+
+	finding.Status = FindingAcknowledged
+	finding.AcknowledgedBy = employeeID
+	finding.AcknowledgedTime = clock.ToEventTime(eventsProcessing.clock.Now())
+	lc.Infof("Finding %s acknowledged by %s", id, employeeID)
+	eventsProcessing.journalFinding(*finding, lc)
+
+	eventsProcessing.sendFindingUpdate(lc)
+	return *finding, nil
+}
+
+// ResolveFinding records how an attendant resolved the finding, the payment-success held while the
+// finding blocked the payment is processed once the last blocking finding is resolved
+func (eventsProcessing *EventsProcessor) ResolveFinding(resolution FindingResolution, edgexcontext interfaces.AppFunctionContext) (Finding, error) {
+	lc := edgexcontext.LoggingClient()
+	if !isFindingResolution(resolution.Resolution) {
+		return Finding{}, fmt.Errorf("resolution must be %s, %s, %s or %s", ResolutionItemScanned,
+			ResolutionCustomerDeclined, ResolutionFalsePositive, ResolutionTheft)
+	}
+
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+
+	finding, ok := eventsProcessing.findings[resolution.Id]
+	if !ok {
+		return Finding{}, ErrFindingNotFound
+	}
+	if finding.Status == FindingResolved {
+		return *finding, fmt.Errorf("finding %s is already resolved", resolution.Id)
+	}
+
+	now := clock.ToEventTime(eventsProcessing.clock.Now())
+	if finding.Status == FindingOpen {
+		finding.AcknowledgedBy = resolution.EmployeeId
+		finding.AcknowledgedTime = now
+	}
+	finding.Status = FindingResolved
+	finding.Resolution = resolution.Resolution
+	finding.ResolvedBy = resolution.EmployeeId
+	finding.ResolvedTime = now
+	finding.Note = resolution.Note
+	lc.Infof("Finding %s resolved as %s by %s", resolution.Id, resolution.Resolution, resolution.EmployeeId)
+	eventsProcessing.journalFinding(*finding, lc)
+
+	eventsProcessing.sendFindingUpdate(lc)
+	resolved := *finding
+	eventsProcessing.applyPendingPaymentSuccess(edgexcontext)
+	return resolved, nil
+}
+
+// holdBlockedPaymentSuccess keeps the payment-success rejected by the findings_resolved guard, so that
+// it is processed when the findings are resolved rather than dropped
+func (eventsProcessing *EventsProcessor) holdBlockedPaymentSuccess(reading dtos.BaseReading, err error) bool {
+	rejected, ok := err.(*statemachine.RejectedError)
+	if !ok || rejected.Event != paymentSuccessEvent || rejected.Guard != findingsResolvedGuard {
+		return false
+	}
+	eventsProcessing.pendingPaymentSuccess = &reading
+	return true
+}
+
+// applyPendingPaymentSuccess processes the held payment-success once no high severity finding of the
+// transaction is unresolved
+func (eventsProcessing *EventsProcessor) applyPendingPaymentSuccess(edgexcontext interfaces.AppFunctionContext) {
+	if eventsProcessing.pendingPaymentSuccess == nil || !eventsProcessing.findingsResolved() {
+		return
+	}
+	reading := *eventsProcessing.pendingPaymentSuccess
+	eventsProcessing.pendingPaymentSuccess = nil
+	edgexcontext.LoggingClient().Infof("Findings of transaction %s resolved, processing the held payment success", eventsProcessing.paymentTransactionID)
+	eventsProcessing.processReadings([]dtos.BaseReading{reading}, edgexcontext)
+}
+
+// findingsResolved is the guard of payment-success, it fails while high severity findings of the
+// transaction are unresolved and BlockPaymentOnFindings is set
+func (eventsProcessing *EventsProcessor) findingsResolved() bool {
+	if eventsProcessing.processConfig == nil || !eventsProcessing.processConfig.BlockPaymentOnFindings {
+		return true
+	}
+	for _, finding := range eventsProcessing.getTransactionFindings() {
+		if finding.Severity == SeverityHigh && finding.Status != FindingResolved {
+			return false
+		}
+	}
+	return true
+}
+
+// getTransactionFindings returns the findings of the transaction that went through payment-start last
+func (eventsProcessing *EventsProcessor) getTransactionFindings() []Finding {
+	findings := []Finding{}
+	if len(eventsProcessing.paymentTransactionID) == 0 {
+		return findings
+	}
+	for _, id := range eventsProcessing.findingIDs {
+		if finding := eventsProcessing.findings[id]; finding.TransactionId == eventsProcessing.paymentTransactionID {
+			findings = append(findings, *finding)
+		}
+	}
+	return findings
+}
+
+// findingsJSONString lists the findings of the transaction for the websocket message
+func (eventsProcessing *EventsProcessor) findingsJSONString() string {
+	findings, err := json.Marshal(eventsProcessing.getTransactionFindings())
+	if err != nil {
+		return "[]"
+	}
+	return string(findings)
+}
+
+// sendFindingUpdate pushes the state, with the updated findings, to the websocket clients
+func (eventsProcessing *EventsProcessor) sendFindingUpdate(lc logger.LoggingClient) {
+	reading := dtos.BaseReading{DeviceName: findingDeviceName, ResourceName: findingUpdateResource}
+	eventsProcessing.sendWebsocketMessage(eventsProcessing.formatWebsocketMessage(reading), lc)
+}
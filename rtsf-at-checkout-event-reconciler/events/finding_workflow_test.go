@@ -0,0 +1,238 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"event-reconciler/journal"
+)
+
+func TestRecordFindings(t *testing.T) {
+	eventsProcessor := newFindingTestProcessor()
+	paymentStart := RTTLogEventEntry{LaneId: "1", BasketId: "abc", TransactionId: "t-1"}
+
+	findings := eventsProcessor.recordFindings(eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), paymentStart), logger.NewMockClient())
+	require.Len(t, findings, 4)
+	for _, finding := range findings {
+		assert.Equal(t, FindingOpen, finding.Status)
+	}
+
+	_, err := eventsProcessor.AcknowledgeFinding(findings[0].Id, "mary1", logger.NewMockClient())
+	require.NoError(t, err)
+
+	// the findings reported again on a payment retry keep their status
+	retried := eventsProcessor.recordFindings(eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(), paymentStart), logger.NewMockClient())
+	require.Len(t, retried, 4)
+	assert.Equal(t, FindingAcknowledged, retried[0].Status)
+	assert.Equal(t, "mary1", retried[0].AcknowledgedBy)
+	assert.Equal(t, FindingOpen, retried[1].Status)
+	assert.Len(t, eventsProcessor.GetFindings("", true), 4)
+}
+
+func TestFindingWorkflow(t *testing.T) {
+	tables := []struct {
+		name             string
+		acknowledge      bool
+		resolution       FindingResolution
+		resolveTwice     bool
+		expectedError    bool
+		expectedStatus   string
+		expectedOpenLeft int
+	}{
+		{
+			name:             "acknowledge only",
+			acknowledge:      true,
+			expectedStatus:   FindingAcknowledged,
+			expectedOpenLeft: 4,
+		},
+		{
+			name:             "acknowledge and resolve",
+			acknowledge:      true,
+			resolution:       FindingResolution{Resolution: ResolutionItemScanned, EmployeeId: "mary1"},
+			expectedStatus:   FindingResolved,
+			expectedOpenLeft: 3,
+		},
+		{
+			name:             "resolve without acknowledgement",
+			resolution:       FindingResolution{Resolution: ResolutionTheft, EmployeeId: "mary1", Note: "left the store"},
+			expectedStatus:   FindingResolved,
+			expectedOpenLeft: 3,
+		},
+		{
+			name:             "unknown resolution",
+			resolution:       FindingResolution{Resolution: "lost", EmployeeId: "mary1"},
+			expectedError:    true,
+			expectedStatus:   FindingOpen,
+			expectedOpenLeft: 4,
+		},
+		{
+			name:             "resolve twice",
+			resolution:       FindingResolution{Resolution: ResolutionFalsePositive, EmployeeId: "mary1"},
+			resolveTwice:     true,
+			expectedError:    true,
+			expectedStatus:   FindingResolved,
+			expectedOpenLeft: 3,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			lc := logger.NewMockClient()
+			eventsProcessor := newFindingTestProcessor()
+			findings := eventsProcessor.recordFindings(eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(),
+				RTTLogEventEntry{LaneId: "1", TransactionId: "t-1"}), lc)
+			id := findings[0].Id
+
+			if table.acknowledge {
+				finding, err := eventsProcessor.AcknowledgeFinding(id, "mary1", lc)
+				require.NoError(t, err)
+				assert.Equal(t, FindingAcknowledged, finding.Status)
+			}
+
+			var err error
+			if len(table.resolution.Resolution) > 0 {
+				table.resolution.Id = id
+				_, err = eventsProcessor.ResolveFinding(table.resolution, context)
+				if table.resolveTwice {
+					require.NoError(t, err)
+					_, err = eventsProcessor.ResolveFinding(table.resolution, context)
+				}
+			}
+			assert.Equal(t, table.expectedError, err != nil)
+
+			stored := eventsProcessor.GetFindings("1", true)
+			require.Len(t, stored, 4)
+			assert.Equal(t, table.expectedStatus, stored[0].Status)
+			if table.expectedStatus == FindingResolved {
+				assert.Equal(t, "mary1", stored[0].ResolvedBy)
+				assert.Equal(t, "mary1", stored[0].AcknowledgedBy)
+			}
+			assert.Len(t, eventsProcessor.GetFindings("1", false), table.expectedOpenLeft)
+			assert.Empty(t, eventsProcessor.GetFindings("2", true))
+		})
+	}
+}
+
+func TestFindingsReloadedFromJournal(t *testing.T) {
+	lc := logger.NewMockClient()
+	path := filepath.Join(t.TempDir(), "transactions.ndjson")
+	transactionJournal, err := journal.NewJournal(path)
+	require.NoError(t, err)
+	eventsProcessor := newFindingTestProcessor()
+	require.NoError(t, eventsProcessor.SetJournal(transactionJournal))
+
+	findings := eventsProcessor.recordFindings(eventsProcessor.buildFindings(eventsProcessor.getSuspectLists(),
+		RTTLogEventEntry{LaneId: "1", TransactionId: "t-1"}), lc)
+	require.Len(t, findings, 4)
+	_, err = eventsProcessor.AcknowledgeFinding(findings[0].Id, "mary1", lc)
+	require.NoError(t, err)
+	_, err = eventsProcessor.ResolveFinding(FindingResolution{Id: findings[1].Id, Resolution: ResolutionFalsePositive, EmployeeId: "mary1"}, context)
+	require.NoError(t, err)
+	require.NoError(t, transactionJournal.Close())
+
+	// the findings not resolved yet are back after a restart, with their acknowledgement
+	transactionJournal, err = journal.NewJournal(path)
+	require.NoError(t, err)
+	defer transactionJournal.Close()
+	restarted := newFindingTestProcessor()
+	require.NoError(t, restarted.SetJournal(transactionJournal))
+
+	reloaded := restarted.GetFindings("", true)
+	require.Len(t, reloaded, 3)
+	assert.Equal(t, findings[0].Id, reloaded[0].Id)
+	assert.Equal(t, FindingAcknowledged, reloaded[0].Status)
+	assert.Equal(t, "mary1", reloaded[0].AcknowledgedBy)
+	assert.Equal(t, findings[2].Id, reloaded[1].Id)
+	assert.Equal(t, FindingOpen, reloaded[1].Status)
+	assert.Equal(t, findings[3].Id, reloaded[2].Id)
+
+	// the finding records are not transactions
+	transactions, err := restarted.GetTransactions(journal.Query{})
+	require.NoError(t, err)
+	assert.Empty(t, transactions)
+}
+
+func TestFindingNotFound(t *testing.T) {
+	lc := logger.NewMockClient()
+	eventsProcessor := newFindingTestProcessor()
+
+	_, err := eventsProcessor.AcknowledgeFinding("unknown", "mary1", lc)
+	assert.ErrorIs(t, err, ErrFindingNotFound)
+	_, err = eventsProcessor.ResolveFinding(FindingResolution{Id: "unknown", Resolution: ResolutionTheft}, context)
+	assert.ErrorIs(t, err, ErrFindingNotFound)
+}
+
+func TestBlockPaymentOnFindings(t *testing.T) {
+	tables := []struct {
+		name          string
+		block         bool
+		price         float64
+		expectedState string
+	}{
+		{name: "not blocking", block: false, price: 799, expectedState: "paid"},
+		{name: "high severity finding", block: true, price: 799, expectedState: "payment"},
+		{name: "medium severity finding", block: true, price: 10.99, expectedState: "paid"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newTransactionTestProcessor()
+			eventsProcessor.processConfig.BlockPaymentOnFindings = table.block
+			eventsProcessor.processConfig.SeverityMediumValue = 10
+			eventsProcessor.processConfig.SeverityHighValue = 50
+			eventsProcessor.catalog = []CatalogProduct{{Barcode: "00000000019293", Name: "HP MP9", Price: table.price}}
+
+			processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+			processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+			eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, CVEventEntry{LaneId: "1", ObjectName: "HP MP9", ROIs: map[string]ROILocation{}})
+			processPosEvent(t, eventsProcessor, paymentStartEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 3000})
+			processPosEvent(t, eventsProcessor, paymentSuccessEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 4000})
+			assert.Equal(t, table.expectedState, eventsProcessor.GetCheckoutState().State)
+
+			if table.expectedState == "payment" {
+				findings := eventsProcessor.GetFindings("1", false)
+				require.Len(t, findings, 1)
+				_, err := eventsProcessor.ResolveFinding(FindingResolution{Id: findings[0].Id, Resolution: ResolutionCustomerDeclined, EmployeeId: "mary1"}, context)
+				require.NoError(t, err)
+
+				// the held payment-success is processed once the finding is resolved
+				assert.Equal(t, "paid", eventsProcessor.GetCheckoutState().State)
+				assert.Nil(t, eventsProcessor.pendingPaymentSuccess)
+				processPosEvent(t, eventsProcessor, basketCloseEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 5000})
+				assert.Equal(t, "idle", eventsProcessor.GetCheckoutState().State)
+			}
+		})
+	}
+}
+
+func TestBlockedPaymentSuccessDroppedOnVoid(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+	eventsProcessor.processConfig.BlockPaymentOnFindings = true
+	eventsProcessor.processConfig.SeverityMediumValue = 10
+	eventsProcessor.processConfig.SeverityHighValue = 50
+	eventsProcessor.catalog = []CatalogProduct{{Barcode: "00000000019293", Name: "HP MP9", Price: 799}}
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+	eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, CVEventEntry{LaneId: "1", ObjectName: "HP MP9", ROIs: map[string]ROILocation{}})
+	processPosEvent(t, eventsProcessor, paymentStartEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 3000})
+	processPosEvent(t, eventsProcessor, paymentSuccessEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 4000})
+	require.NotNil(t, eventsProcessor.pendingPaymentSuccess)
+
+	// the transaction is voided before the finding is resolved, the payment is not applied
+	processPosEvent(t, eventsProcessor, voidTransactionEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 5000})
+	assert.Nil(t, eventsProcessor.pendingPaymentSuccess)
+
+	findings := eventsProcessor.GetFindings("1", false)
+	require.Len(t, findings, 1)
+	_, err := eventsProcessor.ResolveFinding(FindingResolution{Id: findings[0].Id, Resolution: ResolutionTheft, EmployeeId: "mary1"}, context)
+	require.NoError(t, err)
+	assert.Equal(t, "idle", eventsProcessor.GetCheckoutState().State)
+}
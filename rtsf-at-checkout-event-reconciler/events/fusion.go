@@ -0,0 +1,240 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"math"
+)
+
+const (
+	// SensorTiming is the evidence of the sensor items being close in time to the scan of their line
+	SensorTiming = "timing"
+
+	// fusionDefaultPrior is the confidence of a line before any evidence when FusionPrior is not set
+	fusionDefaultPrior = 0.5
+	// fusionScoreBound keeps the sensor scores away from 0 and 1, a single sensor can then not
+	// rule on its own whatever the other sensors report
+	fusionScoreBound = 0.01
+)
+
+// SensorEvidence is the score, from 0 to 1, given by a sensor to the presence of the item of a line,
+// and the weight of the sensor in the confidence of the line
+type SensorEvidence struct {
+	Sensor string  `json:"sensor"`
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+}
+
+// LineConfidence is the confidence that the item of a POS line is the item that was scanned
+type LineConfidence struct {
+	LineIndex   int              `json:"line_index"`
+	ProductId   string           `json:"product_id"`
+	ProductName string           `json:"product_name"`
+	Quantity    float64          `json:"quantity"`
+	PaidPrice   float64          `json:"paid_price"`
+	EventTime   int64            `json:"event_time"`
+	Confidence  float64          `json:"confidence"`
+	Evidence    []SensorEvidence `json:"evidence"`
+}
+
+// BasketConfidence is the confidence of the basket, the confidence of its least confirmed line
+type BasketConfidence struct {
+	Confidence         float64          `json:"confidence"`
+	Threshold          float64          `json:"threshold"`
+	LowConfidenceLines int              `json:"low_confidence_lines"`
+	Lines              []LineConfidence `json:"lines"`
+}
+
+// fusionSettings returns the sensor weights and the prior the lines are scored with
+func (eventsProcessing *EventsProcessor) fusionSettings() (prior float64, weights map[string]float64) {
+	prior = fusionDefaultPrior
+	if eventsProcessing.processConfig == nil {
+		return prior, map[string]float64{DetectorScale: 1, DetectorCV: 1, DetectorRFID: 1}
+	}
+
+	fusionConfig := eventsProcessing.processConfig
+	if fusionConfig.FusionPrior > 0 && fusionConfig.FusionPrior < 1 {
+		prior = fusionConfig.FusionPrior
+	}
+	return prior, map[string]float64{
+		DetectorScale: fusionConfig.FusionScaleWeight,
+		DetectorCV:    fusionConfig.FusionCVWeight,
+		DetectorRFID:  fusionConfig.FusionRFIDWeight,
+		SensorTiming:  fusionConfig.FusionTimingWeight,
+	}
+}
+
+func (eventsProcessing *EventsProcessor) fusionThreshold() float64 {
+	if eventsProcessing.processConfig == nil {
+		return 0
+	}
+	return eventsProcessing.processConfig.FusionConfidenceThreshold
+}
+
+// quantityShare is the share of the quantity of the line accounted for by the sensor items
+func (eventsProcessing *EventsProcessor) quantityShare(line RTTLogEventEntry, count int) float64 {
+	if !eventsProcessing.rttlQuantityIsEach(line) || line.Quantity <= floatingPointTolerance {
+		// a weighed product is seen as a single item whatever its weight
+		if count > 0 {
+			return 1
+		}
+		return 0
+	}
+	return math.Min(1, float64(count)/line.Quantity)
+}
+
+// cvScore is the share of the quantity of the line seen by CV, weighted by the confidence of the
+// detections, a detection without confidence being taken as certain
+func (eventsProcessing *EventsProcessor) cvScore(line RTTLogEventEntry) float64 {
+	if len(line.AssociatedCVItems) == 0 {
+		return 0
+	}
+	var confidence float64
+	for _, cvItem := range line.AssociatedCVItems {
+		if cvItem.Confidence > 0 {
+			confidence += cvItem.Confidence
+		} else {
+			confidence++
+		}
+	}
+	return eventsProcessing.quantityShare(line, len(line.AssociatedCVItems)) * confidence / float64(len(line.AssociatedCVItems))
+}
+
+// scaleFitScore tells how well the weight of the readings matched with the line fits its quantity,
+// the scale gives no evidence while it is degraded
+func (eventsProcessing *EventsProcessor) scaleFitScore(line RTTLogEventEntry) (float64, bool) {
+	if eventsProcessing.isScaleDegraded() {
+		return 0, false
+	}
+	if line.ScaleConfirmed {
+		return 1, true
+	}
+
+	var weight float64
+	for _, scaleItem := range line.AssociatedScaleItems {
+		weight += scaleItem.Delta
+	}
+	if weight <= floatingPointTolerance {
+		return 0, true
+	}
+
+	expectedMin, expectedMax := line.Quantity, line.Quantity
+	if eventsProcessing.rttlQuantityIsEach(line) {
+		expectedMin = line.ProductDetails.ExpectedMinWeight * line.Quantity
+		expectedMax = line.ProductDetails.ExpectedMaxWeight * line.Quantity
+	}
+	switch {
+	case weight < expectedMin:
+		return weight / expectedMin, true
+	case weight > expectedMax:
+		return expectedMax / weight, true
+	}
+	return 1, true
+}
+
+// timingScore tells how close in time the sensor items of the line are to its scan, the timing
+// gives no evidence without sensor items or when FusionTimeWindow is zero
+func (eventsProcessing *EventsProcessor) timingScore(line RTTLogEventEntry) (float64, bool) {
+	if eventsProcessing.processConfig == nil {
+		return 0, false
+	}
+	window, err := eventsProcessing.processConfig.GetFusionTimeWindow()
+	if err != nil || window <= 0 {
+		return 0, false
+	}
+
+	gaps := []int64{}
+	for _, cvItem := range line.AssociatedCVItems {
+		gaps = append(gaps, line.EventTime-cvItem.ROIs[ScannerROI].LastAtLocation)
+	}
+	for _, scaleItem := range line.AssociatedScaleItems {
+		gaps = append(gaps, scaleItem.EventTime-line.EventTime)
+	}
+	if len(gaps) == 0 {
+		return 0, false
+	}
+
+	var score float64
+	for _, gap := range gaps {
+		score += math.Max(0, 1-math.Abs(float64(gap))/float64(window.Milliseconds()))
+	}
+	return score / float64(len(gaps)), true
+}
+
+func logit(probability float64) float64 {
+	probability = math.Min(math.Max(probability, fusionScoreBound), 1-fusionScoreBound)
+	return math.Log(probability / (1 - probability))
+}
+
+// lineConfidence combines the evidence of the sensors into the confidence of the line. Starting from
+// the prior, the sensors are taken as independent and each adds its weighted log-odds, so a score of
+// 0.5 or a weight of 0 leaves the confidence unchanged.
+func (eventsProcessing *EventsProcessor) lineConfidence(lineIndex int, line RTTLogEventEntry) LineConfidence {
+	prior, weights := eventsProcessing.fusionSettings()
+	confidence := LineConfidence{
+		LineIndex:   lineIndex,
+		ProductId:   line.ProductId,
+		ProductName: line.ProductName,
+		Quantity:    line.Quantity,
+		PaidPrice:   line.PaidPrice,
+		EventTime:   line.EventTime,
+		Evidence:    []SensorEvidence{},
+	}
+	addEvidence := func(sensor string, score float64, applicable bool) {
+		if applicable && weights[sensor] > 0 {
+			confidence.Evidence = append(confidence.Evidence, SensorEvidence{Sensor: sensor, Score: score, Weight: weights[sensor]})
+		}
+	}
+
+	scaleScore, scaleApplicable := eventsProcessing.scaleFitScore(line)
+	addEvidence(DetectorScale, scaleScore, scaleApplicable)
+	addEvidence(DetectorCV, eventsProcessing.cvScore(line), true)
+	addEvidence(DetectorRFID, eventsProcessing.quantityShare(line, len(line.AssociatedRFIDItems)), eventsProcessing.isRFIDEligible(line))
+	timingScore, timingApplicable := eventsProcessing.timingScore(line)
+	addEvidence(SensorTiming, timingScore, timingApplicable)
+
+	logOdds := logit(prior)
+	for _, evidence := range confidence.Evidence {
+		logOdds += evidence.Weight * logit(evidence.Score)
+	}
+	confidence.Confidence = 1 / (1 + math.Exp(-logOdds))
+	return confidence
+}
+
+// basketConfidence scores the POS item lines of the current basket
+func (eventsProcessing *EventsProcessor) basketConfidence() BasketConfidence {
+	basket := BasketConfidence{Confidence: 1, Threshold: eventsProcessing.fusionThreshold(), Lines: []LineConfidence{}}
+	for lineIndex, line := range eventsProcessing.rttlogData {
+		if line.EventType != posItemEvent || line.Quantity <= floatingPointTolerance {
+			continue
+		}
+		confidence := eventsProcessing.lineConfidence(lineIndex, line)
+		basket.Lines = append(basket.Lines, confidence)
+		basket.Confidence = math.Min(basket.Confidence, confidence.Confidence)
+		if confidence.Confidence < basket.Threshold {
+			basket.LowConfidenceLines++
+		}
+	}
+	return basket
+}
+
+// getLowConfidenceLines returns the lines below FusionConfidenceThreshold, none when it is zero
+func (eventsProcessing *EventsProcessor) getLowConfidenceLines() []LineConfidence {
+	lowConfidenceLines := []LineConfidence{}
+	basket := eventsProcessing.basketConfidence()
+	for _, line := range basket.Lines {
+		if line.Confidence < basket.Threshold {
+			lowConfidenceLines = append(lowConfidenceLines, line)
+		}
+	}
+	return lowConfidenceLines
+}
+
+// GetBasketConfidence returns the confidence of the current basket and of each of its lines
+func (eventsProcessing *EventsProcessor) GetBasketConfidence() BasketConfidence {
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+
+	return eventsProcessing.basketConfidence()
+}
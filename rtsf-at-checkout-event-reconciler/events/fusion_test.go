@@ -0,0 +1,168 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"event-reconciler/config"
+)
+
+func newFusionTestProcessor(threshold float64) *EventsProcessor {
+	return NewEventsProcessor(time.Second, &config.ReconcilerConfig{
+		FusionPrior:               0.5,
+		FusionScaleWeight:         1,
+		FusionCVWeight:            1,
+		FusionRFIDWeight:          1,
+		FusionTimingWeight:        0.5,
+		FusionTimeWindow:          "10s",
+		FusionConfidenceThreshold: threshold,
+	})
+}
+
+func fusionTestLine(productID string, eventTime int64, scaleDelta float64, cvSeen bool) RTTLogEventEntry {
+	line := RTTLogEventEntry{EventType: posItemEvent, ProductId: productID, ProductName: "Soda", Quantity: 1, QuantityUnit: quantityUnitEA,
+		PaidPrice: 20, EventTime: eventTime, ProductDetails: ProductDetails{ExpectedMinWeight: 10, ExpectedMaxWeight: 11}}
+	if scaleDelta > 0 {
+		line.AssociatedScaleItems = []*ScaleEventEntry{{ScaleId: "bagging", Delta: scaleDelta, EventTime: eventTime + 1000}}
+	}
+	if cvSeen {
+		line.AssociatedCVItems = []*CVEventEntry{{ObjectName: "Soda", ROIs: map[string]ROILocation{ScannerROI: {LastAtLocation: eventTime}}}}
+	}
+	return line
+}
+
+func TestLineConfidence(t *testing.T) {
+	tables := []struct {
+		name            string
+		scaleDelta      float64
+		cvSeen          bool
+		rfidEligible    bool
+		scaleDegraded   bool
+		expectedSensors []string
+		minConfidence   float64
+		maxConfidence   float64
+	}{
+		{
+			name:            "confirmed by scale and CV",
+			scaleDelta:      10.5,
+			cvSeen:          true,
+			expectedSensors: []string{DetectorScale, DetectorCV, SensorTiming},
+			minConfidence:   0.99,
+			maxConfidence:   1,
+		},
+		{
+			name:            "not seen by CV",
+			scaleDelta:      10.5,
+			expectedSensors: []string{DetectorScale, DetectorCV, SensorTiming},
+			minConfidence:   0.74,
+			maxConfidence:   0.76,
+		},
+		{
+			name:            "not seen by any sensor",
+			expectedSensors: []string{DetectorScale, DetectorCV},
+			minConfidence:   0,
+			maxConfidence:   0.01,
+		},
+		{
+			name:            "light item",
+			scaleDelta:      2,
+			cvSeen:          true,
+			expectedSensors: []string{DetectorScale, DetectorCV, SensorTiming},
+			minConfidence:   0.98,
+			maxConfidence:   0.995,
+		},
+		{
+			name:            "RFID tag not read",
+			scaleDelta:      10.5,
+			cvSeen:          true,
+			rfidEligible:    true,
+			expectedSensors: []string{DetectorScale, DetectorCV, DetectorRFID, SensorTiming},
+			minConfidence:   0.99,
+			maxConfidence:   0.999,
+		},
+		{
+			name:            "scale degraded",
+			scaleDelta:      5,
+			cvSeen:          true,
+			scaleDegraded:   true,
+			expectedSensors: []string{DetectorCV, SensorTiming},
+			minConfidence:   0.99,
+			maxConfidence:   1,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newFusionTestProcessor(0)
+			if table.scaleDegraded {
+				eventsProcessor.scaleHealth["bagging"] = ScaleHealthEntry{ScaleId: "bagging", Healthy: false}
+			}
+			line := fusionTestLine("123", 10000, table.scaleDelta, table.cvSeen)
+			line.ProductDetails.RFIDEligible = table.rfidEligible
+
+			confidence := eventsProcessor.lineConfidence(1, line)
+
+			sensors := []string{}
+			for _, evidence := range confidence.Evidence {
+				sensors = append(sensors, evidence.Sensor)
+			}
+			assert.Equal(t, table.expectedSensors, sensors)
+			assert.GreaterOrEqual(t, confidence.Confidence, table.minConfidence)
+			assert.LessOrEqual(t, confidence.Confidence, table.maxConfidence)
+		})
+	}
+}
+
+func TestLineConfidenceWeights(t *testing.T) {
+	eventsProcessor := newFusionTestProcessor(0)
+	line := fusionTestLine("123", 10000, 10.5, false)
+
+	// without weight the sensor is left out, and a line without evidence is at the prior
+	eventsProcessor.processConfig.FusionCVWeight = 0
+	eventsProcessor.processConfig.FusionScaleWeight = 0
+	eventsProcessor.processConfig.FusionTimingWeight = 0
+	eventsProcessor.processConfig.FusionPrior = 0.8
+	confidence := eventsProcessor.lineConfidence(1, line)
+	assert.Empty(t, confidence.Evidence)
+	assert.InDelta(t, 0.8, confidence.Confidence, 0.0001)
+}
+
+func TestLowConfidenceLines(t *testing.T) {
+	eventsProcessor := newFusionTestProcessor(0.9)
+	eventsProcessor.rttlogData = []RTTLogEventEntry{
+		{EventType: basketOpenEvent, LaneId: "1"},
+		fusionTestLine("123", 10000, 10.5, true),
+		fusionTestLine("456", 20000, 10.5, false),
+	}
+
+	basket := eventsProcessor.GetBasketConfidence()
+	require.Len(t, basket.Lines, 2)
+	assert.Equal(t, 1, basket.LowConfidenceLines)
+	assert.Equal(t, basket.Lines[1].Confidence, basket.Confidence)
+	assert.Equal(t, 0.9, basket.Threshold)
+
+	assert.True(t, eventsProcessor.hasReportableSuspects([]CVEventEntry{}, []RFIDEventEntry{}))
+	suspectList := eventsProcessor.getSuspectLists()
+	require.Len(t, suspectList.LowConfidence, 1)
+	assert.Equal(t, "456", suspectList.LowConfidence[0].ProductId)
+	assert.Equal(t, basket.Confidence, suspectList.Confidence)
+
+	findings := eventsProcessor.buildFindings(suspectList, RTTLogEventEntry{LaneId: "1", TransactionId: "t-1"})
+	require.Len(t, findings, 1)
+	assert.Equal(t, DetectorFusion, findings[0].Detector)
+	assert.Equal(t, ReasonLowConfidenceLine, findings[0].ReasonCode)
+	assert.Equal(t, "456", findings[0].GTIN)
+	assert.Equal(t, 20.0, findings[0].EstimatedPrice)
+	assert.Equal(t, []FindingEvidence{{Event: posItemEvent, Reference: "456", EventTime: 20000}}, findings[0].Evidence)
+
+	// without threshold the confidence is reported but no line is a suspect
+	eventsProcessor.processConfig.FusionConfidenceThreshold = 0
+	assert.Empty(t, eventsProcessor.getLowConfidenceLines())
+	assert.False(t, eventsProcessor.hasReportableSuspects([]CVEventEntry{}, []RFIDEventEntry{}))
+}
@@ -32,8 +32,22 @@ func (eventsProcessing *EventsProcessor) appendToPreviousPosItem(newItem RTTLogE
 	// add the new item to the existing collection
 	previousItem.Collection = append(previousItem.Collection, newItem)
 	previousItem.Quantity = previousItem.Quantity + newItem.Quantity
+	previousItem.Decisions = append(previousItem.Decisions, newItem.Decisions...)
+
+	// the sensor items matched with the new scan now account for the collapsed line
+	previousItem.AssociatedCVItems = append(previousItem.AssociatedCVItems, newItem.AssociatedCVItems...)
+	previousItem.AssociatedRFIDItems = append(previousItem.AssociatedRFIDItems, newItem.AssociatedRFIDItems...)
+	previousItem.CVConfirmed = math.Abs(float64(len(previousItem.AssociatedCVItems))-previousItem.Quantity) <= floatingPointTolerance
+	previousItem.RFIDConfirmed = math.Abs(float64(len(previousItem.AssociatedRFIDItems))-previousItem.Quantity) <= floatingPointTolerance
 
 	eventsProcessing.rttlogData[len(eventsProcessing.rttlogData)-1] = previousItem
+	line := &eventsProcessing.rttlogData[len(eventsProcessing.rttlogData)-1]
+	for _, cvItem := range line.AssociatedCVItems {
+		cvItem.AssociatedRTTLEntry = line
+	}
+	for _, rfidItem := range line.AssociatedRFIDItems {
+		rfidItem.AssociatedRTTLEntry = line
+	}
 }
 func (eventsProcessing *EventsProcessor) deleteRTTLItemAtIndex(list *[]RTTLogEventEntry, index int) {
 	if index == len(*list)-1 {
@@ -79,6 +93,8 @@ func (eventsProcessing *EventsProcessor) removeRTTLItemFromBuffer(rttlogReading
 					item.Collection[0] = collectionItem //store updated quantity back into collection
 
 				}
+				// item is a copy, store the shortened collection back
+				eventsProcessing.rttlogData[rttlogIndex].Collection = item.Collection
 				if len(item.Collection) == 0 {
 					eventsProcessing.deleteRTTLItemAtIndex(&eventsProcessing.rttlogData, rttlogIndex)
 				}
@@ -100,13 +116,25 @@ func (eventsProcessing *EventsProcessor) deleteLastScaleItem(list *[]*ScaleEvent
 	*list = (*list)[:len(*list)-1]
 }
 
-func (eventsProcessing *EventsProcessor) wrapSuspectItems() ([]byte, error) {
+func (eventsProcessing *EventsProcessor) getSuspectLists() SuspectLists {
 	suspectList := SuspectLists{
-		CVSuspect:    eventsProcessing.getSuspectCVItems(),
-		RFIDSuspect:  eventsProcessing.getSuspectRFIDItems(),
-		ScaleSuspect: eventsProcessing.getSuspectScaleItems(),
+		CVSuspect:          eventsProcessing.getSuspectCVItems(),
+		RFIDSuspect:        eventsProcessing.getSuspectRFIDItems(),
+		RFIDSoldTwice:      eventsProcessing.getSoldTwiceRFIDItems(),
+		RFIDPreviouslySold: eventsProcessing.getPreviouslySoldRFIDItems(),
+		ScaleSuspect:       eventsProcessing.getReportedSuspectScaleItems(),
+		PriceOverride:      eventsProcessing.getHighValueOverrides(),
+		CVUncertain:        eventsProcessing.getLowConfidenceCVItems(),
+		LowConfidence:      eventsProcessing.getLowConfidenceLines(),
+		ScaleDegraded:      eventsProcessing.isScaleDegraded(),
+		Confidence:         eventsProcessing.basketConfidence().Confidence,
+		Findings:           []Finding{},
 	}
+	eventsProcessing.priceSuspectItems(&suspectList)
+	return suspectList
+}
 
+func (eventsProcessing *EventsProcessor) wrapSuspectItems(suspectList SuspectLists) ([]byte, error) {
 	byteSuspects, err := json.MarshalIndent(suspectList, "", "   ")
 	if err != nil {
 		return nil, err
@@ -139,7 +167,8 @@ func (eventsProcessing *EventsProcessor) persistRFIDGoBack() {
 
 func (eventsProcessing *EventsProcessor) persistRFIDSuspectItems() {
 	for _, rfidItem := range eventsProcessing.currentRFIDData {
-		if rfidItem.AssociatedRTTLEntry == nil && !eventsProcessing.atROILocation(GoBackROI, rfidItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, rfidItem.ROIs) {
+		if rfidItem.AssociatedRTTLEntry == nil && !previouslySold(rfidItem) &&
+			!eventsProcessing.atROILocation(GoBackROI, rfidItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, rfidItem.ROIs) {
 			eventsProcessing.nextRFIDData = append(eventsProcessing.nextRFIDData, rfidItem)
 		}
 	}
@@ -163,7 +192,7 @@ func (eventsProcessing *EventsProcessor) persistCVGoBack() {
 
 func (eventsProcessing *EventsProcessor) persistCVSuspectItems() {
 	for _, cvItem := range eventsProcessing.currentCVData {
-		if cvItem.AssociatedRTTLEntry == nil && !eventsProcessing.atROILocation(GoBackROI, cvItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, cvItem.ROIs) {
+		if cvItem.AssociatedRTTLEntry == nil && !cvItem.LowConfidence && !eventsProcessing.atROILocation(GoBackROI, cvItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, cvItem.ROIs) {
 			eventsProcessing.nextCVData = append(eventsProcessing.nextCVData, cvItem)
 		}
 	}
@@ -180,16 +209,17 @@ func (eventsProcessing *EventsProcessor) checkRTTLForPOSItems() bool {
 
 func (eventsProcessing *EventsProcessor) getExistingCVDataByObjectName(cvReading CVEventEntry) *CVEventEntry {
 	for cvIndex, cvItem := range eventsProcessing.currentCVData {
-		if cvReading.ObjectName == cvItem.ObjectName {
+		// tracked items are looked up by track
+		if cvReading.ObjectName == cvItem.ObjectName && len(cvItem.TrackIds) == 0 {
 			return &eventsProcessing.currentCVData[cvIndex]
 		}
 	}
 	return nil
 }
 
-func (eventsProcessing *EventsProcessor) getExistingRFIDDataByEPC(rfidReading RFIDEventEntry) *RFIDEventEntry {
+func (eventsProcessing *EventsProcessor) getExistingRFIDDataBySerial(rfidReading RFIDEventEntry) *RFIDEventEntry {
 	for rfidIndex, rfidItem := range eventsProcessing.currentRFIDData {
-		if rfidReading.EPC == rfidItem.EPC {
+		if rfidReading.Serial == rfidItem.Serial {
 			return &eventsProcessing.currentRFIDData[rfidIndex]
 		}
 	}
@@ -222,7 +252,7 @@ func (eventsProcessing *EventsProcessor) getSuspectScaleItems() map[int64]*Scale
 func (eventsProcessing *EventsProcessor) getSuspectCVItems() []CVEventEntry {
 	suspectItems := []CVEventEntry{}
 	for _, cvItem := range eventsProcessing.currentCVData {
-		if cvItem.AssociatedRTTLEntry == nil && !eventsProcessing.atROILocation(GoBackROI, cvItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, cvItem.ROIs) {
+		if cvItem.AssociatedRTTLEntry == nil && !cvItem.LowConfidence && !eventsProcessing.atROILocation(GoBackROI, cvItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, cvItem.ROIs) {
 			suspectItems = append(suspectItems, cvItem)
 		}
 	}
@@ -232,7 +262,9 @@ func (eventsProcessing *EventsProcessor) getSuspectCVItems() []CVEventEntry {
 func (eventsProcessing *EventsProcessor) getSuspectRFIDItems() []RFIDEventEntry {
 	suspectItems := []RFIDEventEntry{}
 	for _, rfidItem := range eventsProcessing.currentRFIDData {
-		if rfidItem.AssociatedRTTLEntry == nil && !eventsProcessing.atROILocation(GoBackROI, rfidItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, rfidItem.ROIs) {
+		// previously sold items are reported apart
+		if rfidItem.AssociatedRTTLEntry == nil && !previouslySold(rfidItem) &&
+			!eventsProcessing.atROILocation(GoBackROI, rfidItem.ROIs) && !eventsProcessing.atROILocation(EntranceROI, rfidItem.ROIs) {
 			suspectItems = append(suspectItems, rfidItem)
 		}
 	}
@@ -240,7 +272,8 @@ func (eventsProcessing *EventsProcessor) getSuspectRFIDItems() []RFIDEventEntry
 }
 
 func (eventsProcessing *EventsProcessor) updateSuspectRFIDItems() {
-	for rfidIndex, rfidItem := range eventsProcessing.currentRFIDData {
+	for _, rfidIndex := range eventsProcessing.rfidCandidateOrder() {
+		rfidItem := eventsProcessing.currentRFIDData[rfidIndex]
 		if eventsProcessing.currentRFIDData[rfidIndex].AssociatedRTTLEntry != nil ||
 			eventsProcessing.atROILocation(GoBackROI, eventsProcessing.currentRFIDData[rfidIndex].ROIs) ||
 			eventsProcessing.atROILocation(EntranceROI, eventsProcessing.currentRFIDData[rfidIndex].ROIs) {
@@ -254,8 +287,12 @@ func (eventsProcessing *EventsProcessor) updateSuspectRFIDItems() {
 			//wont be a fractional quantity if its rfid-eligible - it will be "EA"
 			if int(rttlItem.Quantity) > len(rttlItem.AssociatedRFIDItems) && rttlItem.ProductId == rfidItem.UPC {
 				//cross-associate
+				eventsProcessing.traceDecision(&eventsProcessing.rttlogData[rttlIndex], Decision{Detector: DetectorRFID, Reference: rfidItem.EPC,
+					EventTime: rfidItem.EventTime, Accepted: true, Reason: DecisionRFIDMatched})
 				eventsProcessing.rttlogData[rttlIndex].AssociatedRFIDItems = append(eventsProcessing.rttlogData[rttlIndex].AssociatedRFIDItems, &eventsProcessing.currentRFIDData[rfidIndex])
 				eventsProcessing.currentRFIDData[rfidIndex].AssociatedRTTLEntry = &eventsProcessing.rttlogData[rttlIndex]
+				// a tag accounts for a single item
+				break
 			}
 		}
 
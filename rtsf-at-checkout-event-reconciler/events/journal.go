@@ -0,0 +1,250 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"event-reconciler/journal"
+)
+
+// ErrJournalDisabled is returned when the transactions are queried while no journal is set
+var ErrJournalDisabled = errors.New("transaction journal is disabled")
+
+// TransactionRecord is the journal line of a completed transaction. The RTT log entries and sensor
+// items cross-reference each other, so the record holds flat copies and the associations between them.
+type TransactionRecord struct {
+	journal.Entry
+	BasketId         string               `json:"basket_id"`
+	POSLines         []JournalPOSLine     `json:"pos_lines"`
+	ScaleDeltas      []JournalScaleDelta  `json:"scale_deltas"`
+	CVObservations   []JournalObservation `json:"cv_observations"`
+	RFIDObservations []JournalObservation `json:"rfid_observations"`
+	Associations     []JournalAssociation `json:"associations"`
+	Findings         []Finding            `json:"findings"`
+	Confidence       float64              `json:"basket_confidence"`
+}
+
+// JournalPOSLine is a POS event of the RTT log, consecutive scans of a product being a single line
+type JournalPOSLine struct {
+	EventType       string     `json:"event_type"`
+	ProductId       string     `json:"product_id"`
+	ProductName     string     `json:"product_name"`
+	Quantity        float64    `json:"quantity"`
+	QuantityUnit    string     `json:"quantity_unit"`
+	ListPrice       float64    `json:"list_price"`
+	PaidPrice       float64    `json:"paid_price"`
+	DiscountAmount  float64    `json:"discount_amount"`
+	PriceOverridden bool       `json:"price_overridden"`
+	CustomerId      string     `json:"customer_id"`
+	EmployeeId      string     `json:"employee_id"`
+	EventTime       int64      `json:"event_time"`
+	ScaleConfirmed  bool       `json:"scale_confirmed"`
+	CVConfirmed     bool       `json:"cv_confirmed"`
+	RFIDConfirmed   bool       `json:"rfid_confirmed"`
+	Confidence      float64    `json:"confidence"`
+	Decisions       []Decision `json:"decisions"`
+}
+
+// JournalScaleDelta is a weight change of a scale of the lane
+type JournalScaleDelta struct {
+	ScaleId   string  `json:"scale_id"`
+	Delta     float64 `json:"delta"`
+	Total     float64 `json:"total"`
+	Units     string  `json:"units"`
+	Status    string  `json:"status"`
+	EventTime int64   `json:"event_time"`
+	Suspect   bool    `json:"suspect"`
+}
+
+// JournalObservation is an item seen by the CV or RFID sensors of the lane
+type JournalObservation struct {
+	Reference   string   `json:"reference"`
+	ProductName string   `json:"product_name"`
+	GTIN        string   `json:"gtin"`
+	ROIName     string   `json:"roi_name"`
+	EventTime   int64    `json:"event_time"`
+	Serial      string   `json:"serial"`
+	Confidence  float64  `json:"confidence"`
+	TrackIds    []string `json:"track_ids"`
+	Associated  bool     `json:"associated"`
+}
+
+// JournalAssociation is the decision to account for a sensor item with a POS line, the reference
+// being the scale id, the CV object name or the RFID EPC
+type JournalAssociation struct {
+	LineIndex int    `json:"line_index"`
+	ProductId string `json:"product_id"`
+	Detector  string `json:"detector"`
+	Reference string `json:"reference"`
+	EventTime int64  `json:"event_time"`
+}
+
+// SetJournal writes every completed transaction and every finding update to the journal, the serials
+// sold in the transactions already journaled are looked up when their tags are read again, the recent
+// sales are put back in the sold registry and the findings not resolved yet are stored again
+func (eventsProcessing *EventsProcessor) SetJournal(journal *journal.Journal) error {
+	eventsProcessing.journal = journal
+	if err := eventsProcessing.loadSoldSerials(); err != nil {
+		return err
+	}
+	return eventsProcessing.loadFindings()
+}
+
+// buildTransactionRecord copies the basket being closed, it is called before the basket is reset
+func (eventsProcessing *EventsProcessor) buildTransactionRecord(basketClose RTTLogEventEntry) TransactionRecord {
+	record := TransactionRecord{
+		Entry: journal.Entry{
+			LaneId:        basketClose.LaneId,
+			TransactionId: transactionID(basketClose),
+			StartTime:     basketClose.EventTime,
+			EndTime:       basketClose.EventTime,
+		},
+		BasketId:         basketClose.BasketId,
+		POSLines:         []JournalPOSLine{},
+		ScaleDeltas:      []JournalScaleDelta{},
+		CVObservations:   []JournalObservation{},
+		RFIDObservations: []JournalObservation{},
+		Associations:     []JournalAssociation{},
+		Findings:         eventsProcessing.getTransactionFindings(),
+	}
+	if eventsProcessing.afterPaymentSuccess {
+		record.SoldSerials = rfidSerialsOf(eventsProcessing.rttlogData)
+	}
+	productIDs := make(map[string]bool)
+	basketConfidence := eventsProcessing.basketConfidence()
+	record.Confidence = basketConfidence.Confidence
+	lineConfidences := make(map[int]float64)
+	for _, line := range basketConfidence.Lines {
+		lineConfidences[line.LineIndex] = line.Confidence
+	}
+
+	for index, line := range eventsProcessing.rttlogData {
+		if line.EventType == basketOpenEvent {
+			record.StartTime = line.EventTime
+		}
+		if len(record.LaneId) == 0 {
+			record.LaneId = line.LaneId
+		}
+		if len(line.ProductId) > 0 {
+			productIDs[line.ProductId] = true
+		}
+		record.POSLines = append(record.POSLines, JournalPOSLine{
+			EventType:       line.EventType,
+			ProductId:       line.ProductId,
+			ProductName:     line.ProductName,
+			Quantity:        line.Quantity,
+			QuantityUnit:    line.QuantityUnit,
+			ListPrice:       line.ListPrice,
+			PaidPrice:       line.PaidPrice,
+			DiscountAmount:  line.DiscountAmount,
+			PriceOverridden: line.PriceOverridden,
+			CustomerId:      line.CustomerId,
+			EmployeeId:      line.EmployeeId,
+			EventTime:       line.EventTime,
+			ScaleConfirmed:  line.ScaleConfirmed,
+			CVConfirmed:     line.CVConfirmed,
+			RFIDConfirmed:   line.RFIDConfirmed,
+			Confidence:      lineConfidences[index],
+			Decisions:       line.Decisions,
+		})
+
+		for _, scaleItem := range line.AssociatedScaleItems {
+			record.Associations = append(record.Associations, JournalAssociation{LineIndex: index, ProductId: line.ProductId,
+				Detector: DetectorScale, Reference: scaleItem.ScaleId, EventTime: scaleItem.EventTime})
+		}
+		for _, cvItem := range line.AssociatedCVItems {
+			record.Associations = append(record.Associations, JournalAssociation{LineIndex: index, ProductId: line.ProductId,
+				Detector: DetectorCV, Reference: cvItem.ObjectName, EventTime: cvItem.EventTime})
+		}
+		for _, rfidItem := range line.AssociatedRFIDItems {
+			record.Associations = append(record.Associations, JournalAssociation{LineIndex: index, ProductId: line.ProductId,
+				Detector: DetectorRFID, Reference: rfidItem.EPC, EventTime: rfidItem.EventTime})
+		}
+	}
+
+	for _, scaleItem := range eventsProcessing.scaleData {
+		_, suspect := eventsProcessing.suspectScaleItems[scaleItem.EventTime]
+		record.ScaleDeltas = append(record.ScaleDeltas, JournalScaleDelta{
+			ScaleId:   scaleItem.ScaleId,
+			Delta:     scaleItem.Delta,
+			Total:     scaleItem.Total,
+			Units:     scaleItem.Units,
+			Status:    scaleItem.Status,
+			EventTime: scaleItem.EventTime,
+			Suspect:   suspect,
+		})
+	}
+
+	for _, cvItem := range eventsProcessing.currentCVData {
+		record.CVObservations = append(record.CVObservations, JournalObservation{Reference: cvItem.ObjectName, ProductName: cvItem.ObjectName,
+			GTIN: cvItemGTIN(cvItem), ROIName: cvItem.ROIName, EventTime: cvItem.EventTime, Confidence: cvItem.Confidence,
+			TrackIds: cvItem.TrackIds, Associated: cvItem.AssociatedRTTLEntry != nil})
+		if len(cvItem.GTIN) > 0 {
+			productIDs[cvItem.GTIN] = true
+		}
+		for _, gtin := range cvItem.CandidateGTINs {
+			productIDs[gtin] = true
+		}
+	}
+
+	for _, rfidItem := range eventsProcessing.currentRFIDData {
+		record.RFIDObservations = append(record.RFIDObservations, JournalObservation{Reference: rfidItem.EPC, ProductName: rfidItem.ProductName,
+			GTIN: rfidItem.UPC, Serial: rfidItem.Serial, ROIName: rfidItem.ROIName, EventTime: rfidItem.EventTime, Associated: rfidItem.AssociatedRTTLEntry != nil})
+		if len(rfidItem.UPC) > 0 {
+			productIDs[rfidItem.UPC] = true
+		}
+	}
+
+	for _, finding := range record.Findings {
+		if len(finding.GTIN) > 0 {
+			productIDs[finding.GTIN] = true
+		}
+	}
+
+	// the products seen by the sensors are indexed too, so that unscanned items can be looked up
+	record.ProductIds = []string{}
+	for productID := range productIDs {
+		record.ProductIds = append(record.ProductIds, productID)
+	}
+	sort.Strings(record.ProductIds)
+	return record
+}
+
+// journalTransaction appends the transaction being closed to the journal, if any
+func (eventsProcessing *EventsProcessor) journalTransaction(basketClose RTTLogEventEntry) error {
+	if eventsProcessing.journal == nil {
+		return nil
+	}
+	record := eventsProcessing.buildTransactionRecord(basketClose)
+	if err := eventsProcessing.journal.Append(record); err != nil {
+		return err
+	}
+	eventsProcessing.recordSoldSerials(record)
+	return nil
+}
+
+// GetTransactions returns the journaled transactions matching the query, oldest first
+func (eventsProcessing *EventsProcessor) GetTransactions(query journal.Query) ([]TransactionRecord, error) {
+	if eventsProcessing.journal == nil {
+		return nil, ErrJournalDisabled
+	}
+
+	lines, err := eventsProcessing.journal.Find(query)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]TransactionRecord, 0, len(lines))
+	for _, line := range lines {
+		record := TransactionRecord{}
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -0,0 +1,98 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"event-reconciler/journal"
+)
+
+func TestJournalTransaction(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+	eventsProcessor.processConfig.SeverityMediumValue = 10
+	eventsProcessor.processConfig.SeverityHighValue = 50
+	eventsProcessor.catalog = testCatalog
+	transactionJournal, err := journal.NewJournal(filepath.Join(t.TempDir(), "transactions.ndjson"))
+	require.NoError(t, err)
+	defer transactionJournal.Close()
+	require.NoError(t, eventsProcessor.SetJournal(transactionJournal))
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+	eventsProcessor.scaleData = append(eventsProcessor.scaleData, ScaleEventEntry{LaneId: "1", ScaleId: "bagging", Delta: 1.5, Total: 1.5, EventTime: 2100})
+	eventsProcessor.rttlogData[1].AssociatedScaleItems = []*ScaleEventEntry{&eventsProcessor.scaleData[0]}
+	eventsProcessor.rttlogData[1].ScaleConfirmed = true
+	eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, CVEventEntry{LaneId: "1", ObjectName: "Red Wine", EventTime: 2500, ROIs: map[string]ROILocation{}})
+	processPosEvent(t, eventsProcessor, paymentStartEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-1", "event_time": 3000})
+	processPosEvent(t, eventsProcessor, paymentSuccessEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-1", "event_time": 4000})
+	processPosEvent(t, eventsProcessor, basketCloseEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-1", "event_time": 5000})
+
+	records, err := eventsProcessor.GetTransactions(journal.Query{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	record := records[0]
+
+	assert.Equal(t, "1", record.LaneId)
+	assert.Equal(t, "t-1", record.TransactionId)
+	assert.Equal(t, "abc", record.BasketId)
+	assert.Equal(t, int64(1000), record.StartTime)
+	assert.Equal(t, int64(5000), record.EndTime)
+
+	lineTypes := []string{}
+	for _, line := range record.POSLines {
+		lineTypes = append(lineTypes, line.EventType)
+	}
+	assert.Equal(t, []string{basketOpenEvent, posItemEvent, paymentStartEvent, paymentSuccessEvent}, lineTypes)
+	assert.Equal(t, "00000000004011", record.POSLines[1].ProductId)
+	assert.True(t, record.POSLines[1].ScaleConfirmed)
+
+	require.Len(t, record.ScaleDeltas, 1)
+	assert.Equal(t, 1.5, record.ScaleDeltas[0].Delta)
+	require.Len(t, record.CVObservations, 1)
+	assert.Equal(t, "Red Wine", record.CVObservations[0].Reference)
+	assert.False(t, record.CVObservations[0].Associated)
+	assert.Empty(t, record.RFIDObservations)
+	assert.Equal(t, []JournalAssociation{{LineIndex: 1, ProductId: "00000000004011", Detector: DetectorScale, Reference: "bagging", EventTime: 2100}}, record.Associations)
+	require.Len(t, record.Findings, 1)
+	assert.Equal(t, ReasonUnscannedCVItem, record.Findings[0].ReasonCode)
+	assert.Equal(t, []string{"00000000004011", "00000000884389"}, record.ProductIds)
+
+	// the basket is reset once journaled, the RTT log only holds the basket-close
+	assert.Len(t, eventsProcessor.rttlogData, 1)
+	assert.Empty(t, eventsProcessor.scaleData)
+
+	tables := []struct {
+		name     string
+		query    journal.Query
+		expected int
+	}{
+		{name: "scanned product", query: journal.Query{ProductId: "00000000004011"}, expected: 1},
+		{name: "unscanned product", query: journal.Query{ProductId: "00000000884389"}, expected: 1},
+		{name: "other lane", query: journal.Query{LaneId: "2"}, expected: 0},
+		{name: "later transactions", query: journal.Query{From: 5001}, expected: 0},
+		{name: "transaction id", query: journal.Query{TransactionId: "t-1", From: 1000, To: 1000}, expected: 1},
+	}
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			found, err := eventsProcessor.GetTransactions(table.query)
+			require.NoError(t, err)
+			assert.Len(t, found, table.expected)
+		})
+	}
+}
+
+func TestJournalDisabled(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, basketCloseEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 2000})
+
+	_, err := eventsProcessor.GetTransactions(journal.Query{})
+	assert.ErrorIs(t, err, ErrJournalDisabled)
+}
@@ -4,31 +4,58 @@
 package events
 
 import (
+	"event-reconciler/capture"
+	"event-reconciler/clock"
 	"event-reconciler/config"
+	"event-reconciler/cvlabel"
+	"event-reconciler/journal"
+	"event-reconciler/reorder"
+	"event-reconciler/soldepc"
+	"event-reconciler/statemachine"
 	"fmt"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
 	"github.com/gorilla/websocket"
 )
 
 type EventsProcessor struct {
 	afterPaymentSuccess     bool
-	conn                    *websocket.Conn
+	catalog                 []CatalogProduct
+	checkoutState           *statemachine.Machine
+	clock                   clock.Clock
+	conns                   map[*websocket.Conn]bool
+	cvLabelMapping          cvlabel.Mapping
 	currentCVData           []CVEventEntry
 	currentRFIDData         []RFIDEventEntry
 	currentStateMessage     []byte
+	findingIDs              []string
+	findings                map[string]*Finding
 	cvTimeAlignment         time.Duration
-	eventOccurred           map[string]bool
 	firstBasketOpenComplete bool
+	hasPOSItems             bool
+	journal                 *journal.Journal
 	mu                      *sync.Mutex
 	nextCVData              []CVEventEntry
 	nextRFIDData            []RFIDEventEntry
 	processConfig           *config.ReconcilerConfig
+	paymentTransactionID    string
+	pendingPaymentSuccess   *dtos.BaseReading
+	processMu               sync.Mutex
+	recorder                *capture.Recorder
+	rejectedCount           int64
+	rejectedMu              sync.Mutex
+	rejectedTransitions     []statemachine.RejectedError
+	reorderBuffer           *reorder.Buffer
 	rttlogData              []RTTLogEventEntry
 	scaleData               []ScaleEventEntry
+	scaleHealth             map[string]ScaleHealthEntry
+	soldEPCs                *soldepc.Registry
+	soldSerials             map[string]SoldSerial
 	suspectScaleItems       map[int64]*ScaleEventEntry
+	suspendedTransactions   map[string]*SuspendedTransaction
 	upgrader                websocket.Upgrader
 }
 
@@ -38,9 +65,15 @@ type RTTLogEventEntry struct {
 	ProductName          string  `json:"product_name"`
 	LaneId               string  `json:"lane_id"`
 	BasketId             string  `json:"basket_id"`
+	TransactionId        string  `json:"transaction_id"`
 	Quantity             float64 `json:"quantity"`
 	QuantityUnit         string  `json:"quantity_unit"`
 	UnitPrice            float64 `json:"unit_price"`
+	ListPrice            float64 `json:"list_price"`
+	PaidPrice            float64 `json:"paid_price"`
+	DiscountAmount       float64 `json:"discount_amount"`
+	PriceOverridden      bool    `json:"price_overridden"`
+	HighValueOverride    bool    `json:"high_value_override"`
 	EventTime            int64   `json:"event_time"`
 	ScaleConfirmed       bool    `json:"scale_confirmed"`
 	RFIDConfirmed        bool    `json:"rfid_confirmed"`
@@ -54,6 +87,7 @@ type RTTLogEventEntry struct {
 	AssociatedCVItems    []*CVEventEntry
 	AssociatedRFIDItems  []*RFIDEventEntry
 	ProductDetails       ProductDetails
+	Decisions            []Decision
 }
 
 type ProductDetails struct {
@@ -75,27 +109,73 @@ type ScaleEventEntry struct {
 	ScaleId             string  `json:"scale_id"`
 	EventTime           int64   `json:"event_time"`
 	Status              string  `json:"status"`
+	ProductName         string  `json:"product_name"`
+	GTIN                string  `json:"gtin"`
+	EstimatedPrice      float64 `json:"estimated_price"`
+	Severity            string  `json:"severity"`
+	Removed             bool    `json:"-"`
 	AssociatedRTTLEntry *RTTLogEventEntry
 }
 
+type ScaleHealthEntry struct {
+	LaneId             string   `json:"lane_id"`
+	ScaleId            string   `json:"scale_id"`
+	Healthy            bool     `json:"healthy"`
+	Connected          bool     `json:"connected"`
+	DegradationReasons []string `json:"degradation_reasons"`
+	ReadLatencyMs      int64    `json:"read_latency_ms"`
+	TimeoutCount       int64    `json:"timeout_count"`
+	ErrorRate          float64  `json:"error_rate"`
+	LastOKTime         int64    `json:"last_ok_time"`
+	TimeAtZeroMs       int64    `json:"time_at_zero_ms"`
+	ZeroDrift          float64  `json:"zero_drift"`
+	EventTime          int64    `json:"event_time"`
+}
+
 type CVEventEntry struct {
-	LaneId              string `json:"lane_id"`
-	ObjectName          string `json:"product_name"`
-	ROIName             string `json:"roi_name"`
-	ROIAction           string `json:"roi_action"`
-	EventTime           int64  `json:"event_time"`
+	LaneId              string      `json:"lane_id"`
+	ObjectName          string      `json:"product_name"`
+	TrackId             string      `json:"track_id"`
+	TrackIds            []string    `json:"track_ids"`
+	ClassLabel          string      `json:"class_label"`
+	Confidence          float64     `json:"confidence"`
+	BoundingBox         BoundingBox `json:"bounding_box"`
+	FrameId             int64       `json:"frame_id"`
+	CandidateGTINs      []string    `json:"candidate_gtins"`
+	LowConfidence       bool        `json:"low_confidence"`
+	ROIName             string      `json:"roi_name"`
+	ROIAction           string      `json:"roi_action"`
+	EventTime           int64       `json:"event_time"`
+	FirstSeenTime       int64       `json:"first_seen_time"`
+	GTIN                string      `json:"gtin"`
+	EstimatedPrice      float64     `json:"estimated_price"`
+	Severity            string      `json:"severity"`
 	ROIs                map[string]ROILocation
 	AssociatedRTTLEntry *RTTLogEventEntry
 }
 
+// BoundingBox locates a CV detection in its frame
+type BoundingBox struct {
+	XMin float64 `json:"x_min"`
+	YMin float64 `json:"y_min"`
+	XMax float64 `json:"x_max"`
+	YMax float64 `json:"y_max"`
+}
+
 type RFIDEventEntry struct {
-	ProductName         string `json:"product_name"`
-	LaneId              string `json:"lane_id"`
-	EPC                 string `json:"epc"`
-	UPC                 string `json:"upc"`
-	ROIName             string `json:"roi_name"`
-	ROIAction           string `json:"roi_action"`
-	EventTime           int64  `json:"event_time"`
+	ProductName         string  `json:"product_name"`
+	LaneId              string  `json:"lane_id"`
+	EPC                 string  `json:"epc"`
+	UPC                 string  `json:"upc"`
+	Serial              string  `json:"serial"`
+	ROIName             string  `json:"roi_name"`
+	ROIAction           string  `json:"roi_action"`
+	EventTime           int64   `json:"event_time"`
+	GTIN                string  `json:"gtin"`
+	EstimatedPrice      float64 `json:"estimated_price"`
+	Severity            string  `json:"severity"`
+	SoldTransactionId   string  `json:"sold_transaction_id"`
+	SoldTime            int64   `json:"sold_time"`
 	ROIs                map[string]ROILocation
 	AssociatedRTTLEntry *RTTLogEventEntry
 }
@@ -105,26 +185,57 @@ type ROILocation struct {
 	LastAtLocation int64
 }
 
+type PriceOverrideEntry struct {
+	ProductId      string  `json:"product_id"`
+	ProductName    string  `json:"product_name"`
+	Quantity       float64 `json:"quantity"`
+	ListPrice      float64 `json:"list_price"`
+	PaidPrice      float64 `json:"paid_price"`
+	DiscountAmount float64 `json:"discount_amount"`
+	EmployeeId     string  `json:"employee_id"`
+	EventTime      int64   `json:"event_time"`
+	ValueAtRisk    float64 `json:"value_at_risk"`
+	Severity       string  `json:"severity"`
+}
+
 type SuspectLists struct {
-	CVSuspect    []CVEventEntry             `json:"cv_suspect_list"`
-	RFIDSuspect  []RFIDEventEntry           `json:"rfid_suspect_list"`
-	ScaleSuspect map[int64]*ScaleEventEntry `json:"scale_suspect_list"`
+	CVSuspect          []CVEventEntry             `json:"cv_suspect_list"`
+	RFIDSuspect        []RFIDEventEntry           `json:"rfid_suspect_list"`
+	RFIDSoldTwice      []RFIDEventEntry           `json:"rfid_sold_twice_list"`
+	RFIDPreviouslySold []RFIDEventEntry           `json:"rfid_previously_sold_list"`
+	ScaleSuspect       map[int64]*ScaleEventEntry `json:"scale_suspect_list"`
+	PriceOverride      []PriceOverrideEntry       `json:"price_override_list"`
+	CVUncertain        []CVEventEntry             `json:"cv_low_confidence_list"`
+	LowConfidence      []LineConfidence           `json:"low_confidence_list"`
+	ScaleDegraded      bool                       `json:"scale_degraded"`
+	ValueAtRisk        float64                    `json:"value_at_risk"`
+	Severity           string                     `json:"severity"`
+	Confidence         float64                    `json:"basket_confidence"`
+	Findings           []Finding                  `json:"findings"`
 }
 
 func NewEventsProcessor(cvTimeAlignment time.Duration, config *config.ReconcilerConfig) *EventsProcessor {
 	processor := &EventsProcessor{
 		afterPaymentSuccess:     false,
+		clock:                   clock.New(config.ClockSource),
 		currentCVData:           []CVEventEntry{},
 		currentRFIDData:         []RFIDEventEntry{},
+		conns:                   make(map[*websocket.Conn]bool),
+		findings:                make(map[string]*Finding),
 		cvTimeAlignment:         cvTimeAlignment,
 		firstBasketOpenComplete: false,
 		mu:                      &sync.Mutex{},
 		nextCVData:              []CVEventEntry{},
 		nextRFIDData:            []RFIDEventEntry{},
 		processConfig:           config,
+		scaleHealth:             make(map[string]ScaleHealthEntry),
+		soldEPCs:                soldepc.NewRegistry(soldEPCTTL(config)),
 		suspectScaleItems:       make(map[int64]*ScaleEventEntry),
+		suspendedTransactions:   make(map[string]*SuspendedTransaction),
 		upgrader:                websocket.Upgrader{},
 	}
+	// the embedded checkout lifecycle only uses the guards and actions of the processor
+	_ = processor.SetStateMachineDefinition(statemachine.CheckoutDefinition())
 
 	return processor
 }
@@ -132,6 +243,17 @@ func NewEventsProcessor(cvTimeAlignment time.Duration, config *config.Reconciler
 func (eventsProcessing *EventsProcessor) GetScaleToScaleTolerance() float64 {
 	return eventsProcessing.processConfig.ScaleToScaleTolerance
 }
+
+// SetRecorder records every checkout event processed to the capture file of the recorder
+func (eventsProcessing *EventsProcessor) SetRecorder(recorder *capture.Recorder) {
+	eventsProcessing.recorder = recorder
+}
+
+// SetClock replaces the clock the checkout events are reconciled against
+func (eventsProcessing *EventsProcessor) SetClock(clock clock.Clock) {
+	eventsProcessing.clock = clock
+}
+
 func (eventsProcessing *EventsProcessor) GetCurrentStateMessage() []byte {
 	return eventsProcessing.currentStateMessage
 }
@@ -146,6 +268,11 @@ func (rttl RTTLogEventEntry) toJSONString() string {
 			"quantity":  ` + fmt.Sprintf("%f", rttl.Quantity) + `,
 			"quantity_unit": "` + rttl.QuantityUnit + `",
 			"unit_price": ` + fmt.Sprintf("%f", rttl.UnitPrice) + `,
+			"list_price": ` + fmt.Sprintf("%f", rttl.ListPrice) + `,
+			"paid_price": ` + fmt.Sprintf("%f", rttl.PaidPrice) + `,
+			"discount_amount": ` + fmt.Sprintf("%f", rttl.DiscountAmount) + `,
+			"price_overridden": ` + strconv.FormatBool(rttl.PriceOverridden) + `,
+			"high_value_override": ` + strconv.FormatBool(rttl.HighValueOverride) + `,
 			"customer_id": "` + rttl.CustomerId + `",
 			"employee_id": "` + rttl.EmployeeId + `",
 			"event_time": ` + fmt.Sprintf("%d", rttl.EventTime) + `,
@@ -32,3 +32,35 @@ func productLookup(productID string, productLookupEndpoint string) (ProductDetai
 
 	return prodDetails, nil
 }
+
+// CatalogProduct is a product of the product lookup inventory
+type CatalogProduct struct {
+	Barcode   string  `json:"barcode"`
+	Name      string  `json:"name"`
+	MinWeight float64 `json:"min_weight"`
+	MaxWeight float64 `json:"max_weight"`
+	Price     float64 `json:"price"`
+}
+
+func catalogLookup(productLookupEndpoint string) ([]CatalogProduct, error) {
+
+	resp, err := http.Get("http://" + productLookupEndpoint + "/products")
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errString, _ := io.ReadAll(resp.Body)
+		return nil, errors.New(string(errString))
+	}
+
+	var catalog []CatalogProduct
+	err = json.NewDecoder(resp.Body).Decode(&catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
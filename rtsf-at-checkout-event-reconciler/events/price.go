@@ -0,0 +1,158 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"fmt"
+	"math"
+)
+
+// findRTTLItem returns the index of the latest RTT log line of the product
+func (eventsProcessing *EventsProcessor) findRTTLItem(productID string) (int, error) {
+	for rttlIndex := len(eventsProcessing.rttlogData) - 1; rttlIndex >= 0; rttlIndex-- {
+		if productID != "" && eventsProcessing.rttlogData[rttlIndex].ProductId == productID {
+			return rttlIndex, nil
+		}
+	}
+	return -1, fmt.Errorf("product %s is not in the basket", productID)
+}
+
+// changeRTTLItemQuantity sets the quantity of the latest line of the product to the quantity of the reading
+func (eventsProcessing *EventsProcessor) changeRTTLItemQuantity(rttlogReading RTTLogEventEntry) error {
+	if rttlogReading.Quantity < floatingPointTolerance {
+		return fmt.Errorf("quantity of %s must be positive, use %s to remove it", rttlogReading.ProductId, removeItemEvent)
+	}
+
+	rttlIndex, err := eventsProcessing.findRTTLItem(rttlogReading.ProductId)
+	if err != nil {
+		return err
+	}
+
+	item := &eventsProcessing.rttlogData[rttlIndex]
+	quantityChange := rttlogReading.Quantity - item.Quantity
+	if quantityChange < -floatingPointTolerance {
+		decreaseRTTLItemQuantity(item, -quantityChange)
+	} else if quantityChange > floatingPointTolerance {
+		if len(item.Collection) > 0 {
+			addition := item.Collection[len(item.Collection)-1]
+			addition.Quantity = quantityChange
+			addition.EventTime = rttlogReading.EventTime
+			item.Collection = append(item.Collection, addition)
+		}
+		item.Quantity = item.Quantity + quantityChange
+	}
+
+	eventsProcessing.reconcileRTTLItemQuantity(item)
+	eventsProcessing.flagHighValueOverride(item)
+	return nil
+}
+
+// decreaseRTTLItemQuantity takes the quantity off the line, from the oldest items consolidated into its
+// collection first. The quantity left is positive, so the line is kept.
+func decreaseRTTLItemQuantity(item *RTTLogEventEntry, quantity float64) {
+	item.Quantity = item.Quantity - quantity
+	for quantity > floatingPointTolerance && len(item.Collection) > 0 {
+		if item.Collection[0].Quantity <= quantity+floatingPointTolerance {
+			quantity = quantity - item.Collection[0].Quantity
+			item.Collection = item.Collection[1:]
+			continue
+		}
+		item.Collection[0].Quantity = item.Collection[0].Quantity - quantity
+		quantity = 0
+	}
+}
+
+// reconcileRTTLItemQuantity releases the CV and RFID items beyond the new quantity of the line, so that
+// they are reported as suspect, and checks the line against the sensors again
+func (eventsProcessing *EventsProcessor) reconcileRTTLItemQuantity(item *RTTLogEventEntry) {
+	for float64(len(item.AssociatedCVItems)) > item.Quantity+floatingPointTolerance {
+		released := item.AssociatedCVItems[len(item.AssociatedCVItems)-1]
+		eventsProcessing.traceDecision(item, Decision{Detector: DetectorCV, Reference: released.ObjectName, EventTime: released.EventTime, Reason: DecisionQuantityDecreased})
+		released.AssociatedRTTLEntry = nil
+		item.AssociatedCVItems = item.AssociatedCVItems[:len(item.AssociatedCVItems)-1]
+	}
+	for float64(len(item.AssociatedRFIDItems)) > item.Quantity+floatingPointTolerance {
+		released := item.AssociatedRFIDItems[len(item.AssociatedRFIDItems)-1]
+		eventsProcessing.traceDecision(item, Decision{Detector: DetectorRFID, Reference: released.EPC, EventTime: released.EventTime, Reason: DecisionQuantityDecreased})
+		released.AssociatedRTTLEntry = nil
+		item.AssociatedRFIDItems = item.AssociatedRFIDItems[:len(item.AssociatedRFIDItems)-1]
+	}
+
+	item.CVConfirmed = math.Abs(float64(len(item.AssociatedCVItems))-item.Quantity) <= floatingPointTolerance
+	item.RFIDConfirmed = math.Abs(float64(len(item.AssociatedRFIDItems))-item.Quantity) <= floatingPointTolerance
+
+	eventsProcessing.matchScaleItems()
+}
+
+// overrideRTTLItemPrice sets the paid unit price of the latest line of the product to the unit price of the reading
+func (eventsProcessing *EventsProcessor) overrideRTTLItemPrice(rttlogReading RTTLogEventEntry) error {
+	if rttlogReading.UnitPrice < 0 {
+		return fmt.Errorf("price of %s can not be negative", rttlogReading.ProductId)
+	}
+
+	rttlIndex, err := eventsProcessing.findRTTLItem(rttlogReading.ProductId)
+	if err != nil {
+		return err
+	}
+
+	item := &eventsProcessing.rttlogData[rttlIndex]
+	item.PaidPrice = rttlogReading.UnitPrice
+	item.PriceOverridden = true
+	eventsProcessing.flagHighValueOverride(item)
+	return nil
+}
+
+// applyRTTLItemDiscount takes the discount amount of the reading off the total of the latest line of the product
+func (eventsProcessing *EventsProcessor) applyRTTLItemDiscount(rttlogReading RTTLogEventEntry) error {
+	if rttlogReading.DiscountAmount <= 0 {
+		return fmt.Errorf("discount of %s must be positive", rttlogReading.ProductId)
+	}
+
+	rttlIndex, err := eventsProcessing.findRTTLItem(rttlogReading.ProductId)
+	if err != nil {
+		return err
+	}
+
+	item := &eventsProcessing.rttlogData[rttlIndex]
+	item.DiscountAmount = item.DiscountAmount + rttlogReading.DiscountAmount
+	eventsProcessing.flagHighValueOverride(item)
+	return nil
+}
+
+// markdown is the difference between the list price and the paid price of the whole line
+func (rttl RTTLogEventEntry) markdown() float64 {
+	return (rttl.ListPrice-rttl.PaidPrice)*rttl.Quantity + rttl.DiscountAmount
+}
+
+// flagHighValueOverride flags the line when its price overrides and discounts reach PriceOverrideLimit
+func (eventsProcessing *EventsProcessor) flagHighValueOverride(item *RTTLogEventEntry) {
+	var limit float64
+	if eventsProcessing.processConfig != nil {
+		limit = eventsProcessing.processConfig.PriceOverrideLimit
+	}
+
+	markdown := item.markdown()
+	item.HighValueOverride = markdown > floatingPointTolerance && markdown >= limit-floatingPointTolerance
+}
+
+func (eventsProcessing *EventsProcessor) getHighValueOverrides() []PriceOverrideEntry {
+	overrides := []PriceOverrideEntry{}
+	for _, rttlItem := range eventsProcessing.rttlogData {
+		if !rttlItem.HighValueOverride {
+			continue
+		}
+		overrides = append(overrides, PriceOverrideEntry{
+			ProductId:      rttlItem.ProductId,
+			ProductName:    rttlItem.ProductName,
+			Quantity:       rttlItem.Quantity,
+			ListPrice:      rttlItem.ListPrice,
+			PaidPrice:      rttlItem.PaidPrice,
+			DiscountAmount: rttlItem.DiscountAmount,
+			EmployeeId:     rttlItem.EmployeeId,
+			EventTime:      rttlItem.EventTime,
+			ValueAtRisk:    rttlItem.markdown(),
+		})
+	}
+	return overrides
+}
@@ -0,0 +1,197 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"event-reconciler/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pricedBananas(quantity float64, unitPrice float64) map[string]interface{} {
+	data := scannedBananas("1", 2000)
+	data["quantity"] = quantity
+	data["unit_price"] = unitPrice
+	return data
+}
+
+func TestPriceEvents(t *testing.T) {
+	tables := []struct {
+		name                 string
+		events               []string
+		data                 []map[string]interface{}
+		expectedQuantity     float64
+		expectedPaidPrice    float64
+		expectedDiscount     float64
+		expectedHighOverride bool
+	}{
+		{
+			name:              "quantity increase",
+			events:            []string{quantityChangeEvent},
+			data:              []map[string]interface{}{{"product_id": "4011", "quantity": 3.0}},
+			expectedQuantity:  3,
+			expectedPaidPrice: 2,
+		},
+		{
+			name:              "quantity decrease",
+			events:            []string{quantityChangeEvent},
+			data:              []map[string]interface{}{{"product_id": "4011", "quantity": 0.5}},
+			expectedQuantity:  0.5,
+			expectedPaidPrice: 2,
+		},
+		{
+			name:              "quantity change to zero",
+			events:            []string{quantityChangeEvent},
+			data:              []map[string]interface{}{{"product_id": "4011", "quantity": 0.0}},
+			expectedQuantity:  2,
+			expectedPaidPrice: 2,
+		},
+		{
+			name:              "low value override",
+			events:            []string{priceOverrideEvent},
+			data:              []map[string]interface{}{{"product_id": "4011", "unit_price": 1.0}},
+			expectedQuantity:  2,
+			expectedPaidPrice: 1,
+		},
+		{
+			name:                 "high value override",
+			events:               []string{priceOverrideEvent},
+			data:                 []map[string]interface{}{{"product_id": "4011", "unit_price": 0.0}},
+			expectedQuantity:     2,
+			expectedPaidPrice:    0,
+			expectedHighOverride: true,
+		},
+		{
+			name:                 "override and discount",
+			events:               []string{priceOverrideEvent, discountAppliedEvent},
+			data:                 []map[string]interface{}{{"product_id": "4011", "unit_price": 1.0}, {"product_id": "4011", "discount_amount": 1.0}},
+			expectedQuantity:     2,
+			expectedPaidPrice:    1,
+			expectedDiscount:     1,
+			expectedHighOverride: true,
+		},
+		{
+			name:                 "quantity increase after override",
+			events:               []string{priceOverrideEvent, quantityChangeEvent},
+			data:                 []map[string]interface{}{{"product_id": "4011", "unit_price": 1.0}, {"product_id": "4011", "quantity": 4.0}},
+			expectedQuantity:     4,
+			expectedPaidPrice:    1,
+			expectedHighOverride: true,
+		},
+		{
+			name:              "unknown product",
+			events:            []string{priceOverrideEvent},
+			data:              []map[string]interface{}{{"product_id": "1234", "unit_price": 0.0}},
+			expectedQuantity:  2,
+			expectedPaidPrice: 2,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newTransactionTestProcessor()
+			eventsProcessor.processConfig.PriceOverrideLimit = 3
+
+			processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+			processPosEvent(t, eventsProcessor, posItemEvent, pricedBananas(2, 2))
+			for index, event := range table.events {
+				processPosEvent(t, eventsProcessor, event, table.data[index])
+			}
+
+			require.Len(t, eventsProcessor.rttlogData, 2)
+			line := eventsProcessor.rttlogData[1]
+			assert.InDelta(t, table.expectedQuantity, line.Quantity, floatingPointTolerance)
+			assert.InDelta(t, 2, line.ListPrice, floatingPointTolerance)
+			assert.InDelta(t, table.expectedPaidPrice, line.PaidPrice, floatingPointTolerance)
+			assert.InDelta(t, table.expectedDiscount, line.DiscountAmount, floatingPointTolerance)
+			assert.Equal(t, table.expectedHighOverride, line.HighValueOverride)
+			assert.Equal(t, table.expectedHighOverride, eventsProcessor.hasReportableSuspects([]CVEventEntry{}, []RFIDEventEntry{}))
+		})
+	}
+}
+
+func TestQuantityChangeCollection(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, posItemEvent, pricedBananas(1, 2))
+	processPosEvent(t, eventsProcessor, posItemEvent, pricedBananas(1, 2))
+	processPosEvent(t, eventsProcessor, quantityChangeEvent, map[string]interface{}{"product_id": "4011", "quantity": 3.0})
+	require.Len(t, eventsProcessor.rttlogData[1].Collection, 3)
+
+	// the collection keeps adding up to the quantity of the line
+	processPosEvent(t, eventsProcessor, quantityChangeEvent, map[string]interface{}{"product_id": "4011", "quantity": 1.5})
+	line := eventsProcessor.rttlogData[1]
+	var collectionQuantity float64
+	for _, item := range line.Collection {
+		collectionQuantity += item.Quantity
+	}
+	assert.InDelta(t, 1.5, line.Quantity, floatingPointTolerance)
+	assert.InDelta(t, 1.5, collectionQuantity, floatingPointTolerance)
+}
+
+func TestQuantityChangeLatestLine(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, posItemEvent, pricedBananas(2, 2))
+	processPosEvent(t, eventsProcessor, posItemEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "product_id": "4046", "product_name": "Avocados", "quantity": 1.0, "quantity_unit": "lbs", "event_time": 2100})
+	processPosEvent(t, eventsProcessor, posItemEvent, pricedBananas(3, 2))
+	require.Len(t, eventsProcessor.rttlogData, 4)
+
+	// the decrease applies to the latest line of the product, as the increase does
+	processPosEvent(t, eventsProcessor, quantityChangeEvent, map[string]interface{}{"product_id": "4011", "quantity": 1.0})
+	require.Len(t, eventsProcessor.rttlogData, 4)
+	assert.InDelta(t, 2, eventsProcessor.rttlogData[1].Quantity, floatingPointTolerance)
+	assert.InDelta(t, 1, eventsProcessor.rttlogData[2].Quantity, floatingPointTolerance)
+	assert.InDelta(t, 1, eventsProcessor.rttlogData[3].Quantity, floatingPointTolerance)
+}
+
+func TestQuantityChangeReleasesCVItems(t *testing.T) {
+	eventsProcessor := NewEventsProcessor(-1, &config.ReconcilerConfig{})
+	eventsProcessor.currentCVData = []CVEventEntry{
+		{ObjectName: "Bananas", ROIs: map[string]ROILocation{}},
+		{ObjectName: "Bananas", ROIs: map[string]ROILocation{}},
+	}
+	line := RTTLogEventEntry{ProductId: "00000000004011", ProductName: "Bananas", Quantity: 2}
+	eventsProcessor.cvBasketReconciliation(&line)
+	eventsProcessor.rttlogData = []RTTLogEventEntry{line}
+	for index := range eventsProcessor.currentCVData {
+		eventsProcessor.currentCVData[index].AssociatedRTTLEntry = &eventsProcessor.rttlogData[0]
+	}
+	require.True(t, eventsProcessor.rttlogData[0].CVConfirmed)
+
+	err := eventsProcessor.changeRTTLItemQuantity(RTTLogEventEntry{ProductId: "00000000004011", Quantity: 1})
+	require.NoError(t, err)
+
+	// the banana seen but no longer paid for is a suspect
+	assert.True(t, eventsProcessor.rttlogData[0].CVConfirmed)
+	assert.Len(t, eventsProcessor.getSuspectCVItems(), 1)
+}
+
+func TestWrapSuspectItemsPriceOverride(t *testing.T) {
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{PriceOverrideLimit: 5})
+	eventsProcessor.rttlogData = []RTTLogEventEntry{
+		{ProductId: "00000000004011", ProductName: "Bananas", Quantity: 2, ListPrice: 2, PaidPrice: 2, DiscountAmount: 1},
+		{ProductId: "00000000000001", ProductName: "Steak", Quantity: 1, ListPrice: 20, PaidPrice: 10, PriceOverridden: true, EmployeeId: "e-1"},
+	}
+	for index := range eventsProcessor.rttlogData {
+		eventsProcessor.flagHighValueOverride(&eventsProcessor.rttlogData[index])
+	}
+
+	output, err := eventsProcessor.wrapSuspectItems(eventsProcessor.getSuspectLists())
+	require.NoError(t, err)
+
+	suspectList := SuspectLists{}
+	require.NoError(t, json.Unmarshal(output, &suspectList))
+	require.Len(t, suspectList.PriceOverride, 1)
+	assert.Equal(t, "Steak", suspectList.PriceOverride[0].ProductName)
+	assert.Equal(t, "e-1", suspectList.PriceOverride[0].EmployeeId)
+	assert.InDelta(t, 10, suspectList.PriceOverride[0].ValueAtRisk, floatingPointTolerance)
+	assert.InDelta(t, 10, suspectList.ValueAtRisk, floatingPointTolerance)
+}
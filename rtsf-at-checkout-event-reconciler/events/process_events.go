@@ -12,6 +12,7 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
 
+	"event-reconciler/clock"
 	"event-reconciler/rfidgtin"
 )
 
@@ -26,6 +27,41 @@ const (
 func (eventsProcessing *EventsProcessor) ProcessCheckoutEvents(edgexcontext interfaces.AppFunctionContext, data interface{}) (bool, interface{}) {
 	lc := edgexcontext.LoggingClient()
 
+	event, ok := data.(dtos.Event)
+	if !ok {
+		return false, errors.New("unable to cast event to dtos.Event")
+	}
+
+	if eventsProcessing.recorder != nil {
+		if err := eventsProcessing.recorder.Record(event, eventsProcessing.clock.Now()); err != nil {
+			lc.Errorf("Failed to record checkout event: %v", err)
+		}
+	}
+	// the readings are released by the reorder buffer and processed under the same lock, so that a
+	// batch released by the flush ticker cannot be processed after a later batch released here
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+
+	readings := []dtos.BaseReading{}
+	for _, reading := range event.Readings {
+		eventsProcessing.observeEventTime(reading)
+		if eventsProcessing.reorderBuffer == nil {
+			readings = append(readings, reading)
+			continue
+		}
+		readings = append(readings, eventsProcessing.reorderBuffer.Add(reading)...)
+	}
+
+	eventsProcessing.processReadings(readings, edgexcontext)
+
+	return false, nil
+}
+
+// processReadings reconciles the readings one after the other, the caller holds processMu from the
+// release of the readings by the reorder buffer
+func (eventsProcessing *EventsProcessor) processReadings(readings []dtos.BaseReading, edgexcontext interfaces.AppFunctionContext) {
+	lc := edgexcontext.LoggingClient()
+
 	devicePos := eventsProcessing.processConfig.DevicePos
 
 	deviceScale := eventsProcessing.processConfig.DeviceScale
@@ -34,48 +70,68 @@ func (eventsProcessing *EventsProcessor) ProcessCheckoutEvents(edgexcontext inte
 
 	deviceRFID := eventsProcessing.processConfig.DeviceRFID
 
-	event, ok := data.(dtos.Event)
-	if !ok {
-		return false, errors.New("unable to cast event to dtos.Event")
-	}
-	for _, reading := range event.Readings {
+	for _, reading := range readings {
 		readingData := reading
 		resourceName := readingData.ResourceName
 		lc.Debugf("Processing Checkout Event: %s", resourceName)
-		eventOk := eventsProcessing.checkEventOrderValid(resourceName, edgexcontext)
-		if !eventOk {
-			lc.Errorf("Error: event occurred out of order: %v", resourceName)
+		state := eventsProcessing.checkoutState.State()
+		if err := eventsProcessing.checkEventTransition(resourceName); err != nil {
+			if eventsProcessing.holdBlockedPaymentSuccess(readingData, err) {
+				lc.Infof("Payment success held until the high severity findings of transaction %s are resolved", eventsProcessing.paymentTransactionID)
+				continue
+			}
+			lc.Errorf("Error: event occurred out of order: %v", err)
 			continue
 		}
+		if eventsProcessing.checkoutState.State() != state {
+			// the held payment-success belongs to the state the checkout left
+			eventsProcessing.pendingPaymentSuccess = nil
+		}
 
 		switch readingData.DeviceName {
 		case devicePos + "-rest", devicePos + "-mqtt":
 			eventsProcessing.processDevicePosReading(readingData, edgexcontext)
 
 		case deviceScale, deviceScale + "-rest", deviceScale + "-mqtt":
-			eventsProcessing.processDeviceScaleReading(readingData, lc)
+			if resourceName == scaleHealthEvent {
+				eventsProcessing.processDeviceScaleHealthReading(readingData, lc)
+			} else {
+				eventsProcessing.processDeviceScaleReading(readingData, lc)
+			}
 
 		case deviceCV + "-rest", deviceCV + "-mqtt":
 			eventsProcessing.processDeviceCVReading(readingData, lc)
 
 		case deviceRFID + "-rest", deviceRFID + "-mqtt":
-			eventsProcessing.processDeviceRFIDReading(readingData, lc)
+			eventsProcessing.processDeviceRFIDReading(readingData, edgexcontext)
 
 		default:
 			lc.Errorf("Did not recognize Device: %s", readingData.DeviceName)
 			continue
 		}
 
-		msg := eventsProcessing.formatWebsocketMessage(resourceName)
-		eventsProcessing.sendWebsocketMessage(msg, edgexcontext)
+		msg := eventsProcessing.formatWebsocketMessage(readingData)
+		eventsProcessing.sendWebsocketMessage(msg, lc)
 	}
 
 	lc.Tracef("RTTLog: %v", eventsProcessing.rttlogData)
 	lc.Tracef("scaleData: %v", eventsProcessing.scaleData)
 	lc.Tracef("CvData: %v", eventsProcessing.currentCVData)
 	lc.Tracef("RfidData: %v", eventsProcessing.currentRFIDData)
+}
 
-	return false, nil
+func (eventsProcessing *EventsProcessor) observeEventTime(reading dtos.BaseReading) {
+	observer, ok := eventsProcessing.clock.(clock.Observer)
+	if !ok {
+		return
+	}
+
+	eventDetails := struct {
+		EventTime int64 `json:"event_time"`
+	}{}
+	if err := eventsProcessing.unmarshalObjValue(reading.ObjectValue, &eventDetails); err == nil && eventDetails.EventTime > 0 {
+		observer.Observe(clock.FromEventTime(eventDetails.EventTime))
+	}
 }
 
 func (eventsProcessing *EventsProcessor) processDeviceCVReading(reading dtos.BaseReading, lc logger.LoggingClient) {
@@ -87,11 +143,16 @@ func (eventsProcessing *EventsProcessor) processDeviceCVReading(reading dtos.Bas
 		lc.Errorf("CV unmarshal failure: %v", err)
 		return
 	}
+	eventsProcessing.resolveCVDetection(&cvReading)
 
-	cvObject := eventsProcessing.getExistingCVDataByObjectName(cvReading)
+	cvObject := eventsProcessing.getExistingCVItem(cvReading)
 
 	if cvObject == nil {
 		//object does not exist in currentCVData
+		if len(cvReading.TrackId) > 0 {
+			cvReading.TrackIds = []string{cvReading.TrackId}
+		}
+		cvReading.FirstSeenTime = cvReading.EventTime
 		updateCVObjectLocation(cvReading, &cvReading, lc)
 		if eventsProcessing.afterPaymentSuccess {
 			eventsProcessing.nextCVData = append(eventsProcessing.nextCVData, cvReading)
@@ -99,6 +160,7 @@ func (eventsProcessing *EventsProcessor) processDeviceCVReading(reading dtos.Bas
 			eventsProcessing.currentCVData = append(eventsProcessing.currentCVData, cvReading)
 		}
 	} else {
+		updateCVDetection(cvReading, cvObject)
 		updateCVObjectLocation(cvReading, cvObject, lc)
 	}
 
@@ -109,7 +171,8 @@ func (eventsProcessing *EventsProcessor) processDeviceCVReading(reading dtos.Bas
 	}
 }
 
-func (eventsProcessing *EventsProcessor) processDeviceRFIDReading(reading dtos.BaseReading, lc logger.LoggingClient) {
+func (eventsProcessing *EventsProcessor) processDeviceRFIDReading(reading dtos.BaseReading, edgexcontext interfaces.AppFunctionContext) {
+	lc := edgexcontext.LoggingClient()
 	rfidReading := RFIDEventEntry{}
 	err := eventsProcessing.unmarshalObjValue(reading.ObjectValue, &rfidReading)
 	if err != nil {
@@ -133,11 +196,22 @@ func (eventsProcessing *EventsProcessor) processDeviceRFIDReading(reading dtos.B
 	}
 	rfidReading.UPC = upc
 	rfidReading.ProductName = prodDetails.Name
+	rfidReading.Serial = rfidSerial(rfidReading.EPC)
+
+	if rfidReading.ROIName == DepartureROI {
+		// the exit of the store is not part of a lane, the tags read there are not basket items
+		eventsProcessing.processDepartureRFIDReading(rfidReading, edgexcontext)
+		return
+	}
 
-	rfidObject := eventsProcessing.getExistingRFIDDataByEPC(rfidReading)
+	rfidObject := eventsProcessing.getExistingRFIDDataBySerial(rfidReading)
 
 	if rfidObject == nil {
 		//Add new RFID Entry to currentRFIDData
+		eventsProcessing.markPreviouslySold(&rfidReading)
+		if previouslySold(rfidReading) {
+			lc.Warnf("RFID tag %s was already sold in transaction %s", rfidReading.Serial, rfidReading.SoldTransactionId)
+		}
 		updateRFIDObjectLocation(rfidReading, &rfidReading, lc)
 		if eventsProcessing.afterPaymentSuccess {
 			eventsProcessing.nextRFIDData = append(eventsProcessing.nextRFIDData, rfidReading)
@@ -192,6 +266,7 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 	switch resourceName {
 	case basketOpenEvent:
 		eventsProcessing.resetRTTLBasket()
+		eventsProcessing.paymentTransactionID = ""
 		//only reset Baskets after first basketOpen
 		if eventsProcessing.firstBasketOpenComplete {
 			eventsProcessing.resetCVBasket()
@@ -201,6 +276,9 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 		}
 
 	case basketCloseEvent:
+		if err := eventsProcessing.journalTransaction(rttLogReading); err != nil {
+			lc.Errorf("Failed to journal transaction %s: %v", transactionID(rttLogReading), err)
+		}
 		eventsProcessing.resetRTTLBasket()
 
 		// Adding these two basket resets to clear the 'blacklists' after a payment for the demo
@@ -212,8 +290,55 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 		if err != nil {
 			lc.Errorf("Remove Item Error: %v", err)
 		}
+		eventsProcessing.matchScaleItems()
+
+		eventsProcessing.hasPOSItems = eventsProcessing.checkRTTLForPOSItems()
+		return
 
-		eventsProcessing.eventOccurred[posItemEvent] = eventsProcessing.checkRTTLForPOSItems()
+	case voidTransactionEvent:
+		eventsProcessing.voidTransaction()
+		lc.Infof("Transaction %s voided", transactionID(rttLogReading))
+		return
+
+	case suspendTransactionEvent:
+		if err := eventsProcessing.suspendTransaction(rttLogReading); err != nil {
+			lc.Errorf("Suspend Transaction Error: %v", err)
+			return
+		}
+		lc.Infof("Transaction %s suspended", transactionID(rttLogReading))
+		return
+
+	case resumeTransactionEvent:
+		if err := eventsProcessing.resumeTransaction(rttLogReading); err != nil {
+			// nothing was resumed, the lane is ready for a new basket
+			lc.Errorf("Resume Transaction Error: %v", err)
+			eventsProcessing.ResetCheckoutState()
+			return
+		}
+		lc.Infof("Transaction %s resumed on lane %s", transactionID(rttLogReading), rttLogReading.LaneId)
+		return
+
+	case quantityChangeEvent:
+		if err := eventsProcessing.changeRTTLItemQuantity(rttLogReading); err != nil {
+			lc.Errorf("Quantity Change Error: %v", err)
+		}
+		return
+
+	case priceOverrideEvent:
+		if err := eventsProcessing.overrideRTTLItemPrice(rttLogReading); err != nil {
+			lc.Errorf("Price Override Error: %v", err)
+		}
+		return
+
+	case discountAppliedEvent:
+		if err := eventsProcessing.applyRTTLItemDiscount(rttLogReading); err != nil {
+			lc.Errorf("Discount Error: %v", err)
+		}
+		return
+
+	case paymentFailureEvent, attendantOverrideEvent:
+		// these events only move the checkout through its lifecycle
+		lc.Infof("Checkout is %s after %s", eventsProcessing.checkoutState.State(), resourceName)
 		return
 
 	case posItemEvent:
@@ -228,6 +353,8 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 		} else {
 			rttLogReading.ProductDetails = ProductDetails{"", rttLogReading.Quantity, rttLogReading.Quantity, false}
 		}
+		rttLogReading.ListPrice = rttLogReading.UnitPrice
+		rttLogReading.PaidPrice = rttLogReading.UnitPrice
 
 		eventsProcessing.cvBasketReconciliation(&rttLogReading)
 
@@ -239,17 +366,24 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 		}
 
 	case paymentStartEvent:
+		eventsProcessing.paymentTransactionID = transactionID(rttLogReading)
 		eventsProcessing.updateSuspectRFIDItems()
 
 		suspectCVItems := eventsProcessing.getSuspectCVItems()
 		suspectRFIDItems := eventsProcessing.getSuspectRFIDItems()
 
-		if len(eventsProcessing.suspectScaleItems) > 0 || len(suspectCVItems) > 0 || len(suspectRFIDItems) > 0 {
-			outputData, err := eventsProcessing.wrapSuspectItems()
+		if eventsProcessing.hasReportableSuspects(suspectCVItems, suspectRFIDItems) {
+			eventsProcessing.refreshCatalog(lc)
+			suspectList := eventsProcessing.getSuspectLists()
+			suspectList.Findings = eventsProcessing.recordFindings(eventsProcessing.buildFindings(suspectList, rttLogReading), lc)
+			outputData, err := eventsProcessing.wrapSuspectItems(suspectList)
 			if err != nil {
 				lc.Error("Failed to marshal suspect items for output")
 			}
 			lc.Info("Suspect items detected, sending to message bus")
+			if err := eventsProcessing.publishFindings(suspectList.Findings, edgexcontext); err != nil {
+				lc.Errorf("Findings Error: %v", err)
+			}
 			//export suspect  items
 			// Not using logger so that it pretty prints
 			fmt.Println(string(outputData))
@@ -261,6 +395,7 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 
 	case paymentSuccessEvent:
 		eventsProcessing.afterPaymentSuccess = true
+		eventsProcessing.registerSoldEPCs(rttLogReading)
 
 	default:
 		lc.Errorf("Unkown POS event: %s", resourceName)
@@ -279,4 +414,8 @@ func (eventsProcessing *EventsProcessor) processDevicePosReading(reading dtos.Ba
 		}
 	}
 
+	if resourceName == posItemEvent {
+		// the readings dropped before the scan may belong to the new line
+		eventsProcessing.matchScaleItems()
+	}
 }
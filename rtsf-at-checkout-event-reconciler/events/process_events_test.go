@@ -5,6 +5,7 @@ package events
 
 import (
 	"encoding/json"
+	"event-reconciler/clock"
 	"event-reconciler/config"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
@@ -393,21 +395,20 @@ func TestProcessDeviceRFIDReading(t *testing.T) {
 			ProductLookupEndpoint: tsURL.Hostname() + ":" + tsURL.Port(),
 		},
 	}
-	lc := logger.MockLogger{}
 
 	reading := initRFIDReadingApplesEnterBagging()
-	eventsProcessor.processDeviceRFIDReading(reading, lc)
+	eventsProcessor.processDeviceRFIDReading(reading, context)
 	assert.Equal(t, len(eventsProcessor.currentRFIDData), 1)
 	assert.Contains(t, eventsProcessor.currentRFIDData[0].ROIs, BaggingROI)
 	assert.True(t, eventsProcessor.currentRFIDData[0].ROIs[BaggingROI].AtLocation)
 
 	reading = initRFIDReadingApplesExitedBagging()
-	eventsProcessor.processDeviceRFIDReading(reading, lc)
+	eventsProcessor.processDeviceRFIDReading(reading, context)
 	assert.Equal(t, len(eventsProcessor.currentRFIDData), 1)
 	assert.False(t, eventsProcessor.currentRFIDData[0].ROIs[BaggingROI].AtLocation)
 
 	reading = initRFIDReadingSteakEnterBagging()
-	eventsProcessor.processDeviceRFIDReading(reading, lc)
+	eventsProcessor.processDeviceRFIDReading(reading, context)
 	assert.Equal(t, len(eventsProcessor.currentRFIDData), 2)
 	assert.Equal(t, len(eventsProcessor.nextRFIDData), 0)
 	assert.True(t, eventsProcessor.currentRFIDData[1].ROIs[BaggingROI].AtLocation)
@@ -415,7 +416,7 @@ func TestProcessDeviceRFIDReading(t *testing.T) {
 	eventsProcessor.afterPaymentSuccess = true
 
 	reading = initRFIDReadingSalsaEnterBagging()
-	eventsProcessor.processDeviceRFIDReading(reading, lc)
+	eventsProcessor.processDeviceRFIDReading(reading, context)
 
 	assert.Equal(t, len(eventsProcessor.currentRFIDData), 2)
 	assert.Equal(t, len(eventsProcessor.nextRFIDData), 1)
@@ -508,3 +509,23 @@ func TestProcessDevicePosReading(t *testing.T) {
 	assert.Equal(t, eventsProcessor.rttlogData[len(eventsProcessor.rttlogData)-1].EventTime, int64(1559679789))
 
 }
+
+func TestObserveEventTime(t *testing.T) {
+	eventClock := clock.NewEventClock()
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{})
+	eventsProcessor.SetClock(eventClock)
+
+	eventsProcessor.observeEventTime(initCVReadingScannerENTER())
+	assert.Equal(t, int64(1559679684), clock.ToEventTime(eventsProcessor.clock.Now()))
+
+	// readings without event time leave the clock as is
+	reading, err := dtos.NewSimpleReading("", "", "weight", "Int64", int64(1))
+	assert.NoError(t, err)
+	eventsProcessor.observeEventTime(reading)
+	assert.Equal(t, int64(1559679684), clock.ToEventTime(eventsProcessor.clock.Now()))
+
+	manualClock := clock.NewManualClock(time.Unix(10, 0))
+	eventsProcessor.SetClock(manualClock)
+	eventsProcessor.observeEventTime(initCVReadingScannerENTER())
+	assert.Equal(t, time.Unix(10, 0), eventsProcessor.clock.Now())
+}
@@ -4,70 +4,21 @@
 package events
 
 import (
-	"fmt"
 	"math"
+
+	"event-reconciler/clock"
 )
 
 func (eventsProcessing *EventsProcessor) scaleBasketReconciliation(scaleReading *ScaleEventEntry) {
 
 	//if scale reading is negative
 	if scaleReading.Delta < 0 {
-		//attempt to match with item in eventsProcessing.suspectScaleItems
-		for eventTime, suspectItem := range eventsProcessing.suspectScaleItems {
-			if math.Abs(math.Abs(scaleReading.Delta)-suspectItem.Delta) < scalePrecision {
-				//remove from eventsProcessing.suspectScaleItems
-				delete(eventsProcessing.suspectScaleItems, eventTime)
-				break
-			}
-		}
-		//on negative scale drop, we don't want to add it to suspect list
-		return
-	}
-
-	eventsProcessing.suspectScaleItems[scaleReading.EventTime] = scaleReading //add to eventsProcessing.suspectScaleItems first
-	if len(eventsProcessing.rttlogData) == 0 {                                // if scale event occurs before basketOpen
-		return
+		//attempt to match with a suspect item taken back off the scale, on negative scale drop we don't want to add it to suspect list
+		eventsProcessing.removeSuspectScaleItem(scaleReading)
 	}
-	if eventsProcessing.rttlogData[len(eventsProcessing.rttlogData)-1].ProductId != "" {
-		currentRTTLEntry := &eventsProcessing.rttlogData[len(eventsProcessing.rttlogData)-1]
-
-		if eventsProcessing.rttlQuantityIsEach(*currentRTTLEntry) {
-			weightRange := eventsProcessing.calculateCurrentWeightRange(currentRTTLEntry)
-			if weightRange.ExpectedMinWeight > floatingPointTolerance { //scale is not confirmed. Compare vs. .000001 and not 0 due to floating point precision
 
-				// divide by zero check, followed by check if scale delta is less than (rttl expected min weight / quantity)
-				if currentRTTLEntry.Quantity < 1 || scaleReading.Delta < weightRange.ExpectedMinWeight/currentRTTLEntry.Quantity {
-					currentRTTLEntry.ScaleConfirmed = false
-					return
-				}
-			}
-		}
-
-		if !eventsProcessing.checkScaleConfirmed(currentRTTLEntry) {
-			//reverse iterate through scaleBuffer till associatedRTTLEntry != nil
-			for scaleBufferIterator := len(eventsProcessing.scaleData) - 1; eventsProcessing.scaleData[scaleBufferIterator].AssociatedRTTLEntry == nil; scaleBufferIterator-- {
-				//if current iteration fits within allowed under the umbrella of allotted weight (due to multiple drops)
-				if eventsProcessing.scaleData[scaleBufferIterator].Delta <= currentRTTLEntry.CurrentWeightRange.ExpectedMaxWeight {
-					//check if assoc.Buffer is full
-					if eventsProcessing.checkScaleConfirmed(currentRTTLEntry) {
-						break
-					}
-					//cross associate, remove from unassoc. buffer
-					lastScaleReading := &eventsProcessing.scaleData[scaleBufferIterator]
-					lastScaleReading.AssociatedRTTLEntry = currentRTTLEntry
-					currentRTTLEntry.AssociatedScaleItems = append(currentRTTLEntry.AssociatedScaleItems, lastScaleReading)
-					delete(eventsProcessing.suspectScaleItems, (*lastScaleReading).EventTime)
-					eventsProcessing.checkScaleConfirmed(currentRTTLEntry)
-					if scaleBufferIterator == 0 {
-						break
-					}
-
-				} else if scaleBufferIterator == 0 {
-					break
-				}
-			}
-		}
-	}
+	// the whole basket is matched again, scale events before basketOpen are left as suspects
+	eventsProcessing.matchScaleItems()
 }
 
 func (eventsProcessing *EventsProcessor) rttlQuantityIsEach(rttlogEventEntry RTTLogEventEntry) bool {
@@ -99,6 +50,8 @@ func (eventsProcessing *EventsProcessor) checkScaleConfirmed(rttlogEventEntry *R
 		for weightRange.ExpectedMinWeight <= ((rttlogEventEntry.ProductDetails.ExpectedMinWeight * -1) + floatingPointTolerance) { //if overpopulated RTTL due to UPDATE in Quantity
 			//pop latest out of assoc.ScaleBuffer, re-add to suspectItems
 			lastAssociatedScaleItem := rttlogEventEntry.AssociatedScaleItems[len(rttlogEventEntry.AssociatedScaleItems)-1]
+			eventsProcessing.traceDecision(rttlogEventEntry, scaleDecision(lastAssociatedScaleItem, weightRange.ExpectedMinWeight,
+				weightRange.ExpectedMaxWeight, false, DecisionWeightOverQuantity))
 			eventsProcessing.suspectScaleItems[lastAssociatedScaleItem.EventTime] = lastAssociatedScaleItem
 			eventsProcessing.deleteLastScaleItem(&(rttlogEventEntry.AssociatedScaleItems))
 			weightRange = eventsProcessing.calculateCurrentWeightRange(rttlogEventEntry)
@@ -109,37 +62,49 @@ func (eventsProcessing *EventsProcessor) checkScaleConfirmed(rttlogEventEntry *R
 
 	// Quantity unit is not "EA"/"EACH"
 
-	totalAvailableWeight := rttlogEventEntry.Quantity
 	var scaleWeight float64
 	for _, associatedScaleItem := range rttlogEventEntry.AssociatedScaleItems {
 		scaleWeight = scaleWeight + associatedScaleItem.Delta
 	}
-	var percentChange float64
-	if scaleWeight != 0 {
-		percentChange = math.Abs(((totalAvailableWeight - scaleWeight) / scaleWeight))
-	} else {
-		percentChange = 0
-	}
-
-	tolerance := eventsProcessing.GetScaleToScaleTolerance()
-	fmt.Printf("Quantity unit %v, weight diff: %v (%v%%), scale-to-scale tolerance: %v (%v%%)",
-		rttlogEventEntry.QuantityUnit, percentChange, percentChange*100, tolerance, tolerance*100)
-
-	if percentChange < tolerance || percentChange == 0 {
-		rttlogEventEntry.ScaleConfirmed = true
-		return rttlogEventEntry.ScaleConfirmed
-	}
-	rttlogEventEntry.ScaleConfirmed = false
+	rttlogEventEntry.ScaleConfirmed = eventsProcessing.scaleWeightConfirmed(*rttlogEventEntry, scaleWeight)
 	return rttlogEventEntry.ScaleConfirmed
 }
 
 func (eventsProcessing *EventsProcessor) cvBasketReconciliation(rttlReading *RTTLogEventEntry) {
 
 	for cvIndex, cvItem := range eventsProcessing.currentCVData {
-		if rttlReading.ProductName == cvItem.ObjectName {
+		if eventsProcessing.cvItemMatchesLine(cvItem, *rttlReading) {
+			// every tracked object accounts for a single item of a single line
+			if cvItem.AssociatedRTTLEntry != nil {
+				if !cvItemOnLine(cvItem, *rttlReading) {
+					eventsProcessing.traceDecision(rttlReading, Decision{Detector: DetectorCV, Reference: cvItem.ObjectName, EventTime: cvItem.EventTime,
+						Reason: DecisionCVAlreadyAssociated})
+				}
+				continue
+			}
+			if float64(len(rttlReading.AssociatedCVItems)) >= rttlReading.Quantity-floatingPointTolerance {
+				eventsProcessing.traceDecision(rttlReading, Decision{Detector: DetectorCV, Reference: cvItem.ObjectName, EventTime: cvItem.EventTime,
+					Reason: DecisionCVQuantityReached})
+				continue
+			}
+			// low confidence detections are reported apart, they do not confirm the line
+			if cvItem.LowConfidence {
+				eventsProcessing.traceDecision(rttlReading, Decision{Detector: DetectorCV, Reference: cvItem.ObjectName, EventTime: cvItem.EventTime,
+					Reason: DecisionCVLowConfidence, Measured: cvItem.Confidence, Tolerance: eventsProcessing.cvConfidenceThreshold()})
+				continue
+			}
 			// check that the cvItem was at the scanner when the rttl was scanned
 			// if CvTimeAlignment is negative ignore time alignment entirely
-			if (math.Abs(float64(rttlReading.EventTime-cvItem.ROIs[ScannerROI].LastAtLocation)) < float64(eventsProcessing.cvTimeAlignment)) || eventsProcessing.cvTimeAlignment < 0 {
+			decision := Decision{
+				Detector:  DetectorCV,
+				Reference: cvItem.ObjectName,
+				EventTime: cvItem.EventTime,
+				Measured:  math.Abs(float64(rttlReading.EventTime - cvItem.ROIs[ScannerROI].LastAtLocation)),
+				Tolerance: float64(eventsProcessing.cvTimeAlignment.Milliseconds()),
+			}
+			if eventsProcessing.cvTimeAlignment < 0 || eventsProcessing.withinCVTimeAlignment(rttlReading.EventTime, cvItem.ROIs[ScannerROI].LastAtLocation) {
+				decision.Accepted, decision.Reason = true, DecisionCVTimeAligned
+				eventsProcessing.traceDecision(rttlReading, decision)
 				//cross-associate
 				rttlReading.AssociatedCVItems = append(rttlReading.AssociatedCVItems, &eventsProcessing.currentCVData[cvIndex])
 				eventsProcessing.currentCVData[cvIndex].AssociatedRTTLEntry = rttlReading
@@ -147,22 +112,46 @@ func (eventsProcessing *EventsProcessor) cvBasketReconciliation(rttlReading *RTT
 				if math.Abs(float64(len(rttlReading.AssociatedCVItems))-rttlReading.Quantity) <= floatingPointTolerance {
 					rttlReading.CVConfirmed = true
 				}
+			} else {
+				decision.Reason = DecisionCVTimeMisaligned
+				eventsProcessing.traceDecision(rttlReading, decision)
 			}
 		}
 	}
 }
 
+// withinCVTimeAlignment checks that the event times, in milliseconds, are closer than CvTimeAlignment
+func (eventsProcessing *EventsProcessor) withinCVTimeAlignment(rttlEventTime int64, cvEventTime int64) bool {
+	gap := clock.FromEventTime(rttlEventTime).Sub(clock.FromEventTime(cvEventTime))
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap < eventsProcessing.cvTimeAlignment
+}
+
 func (eventsProcessing *EventsProcessor) rfidBasketReconciliation(rttlReading *RTTLogEventEntry) error {
 	rttlQuantity := rttlReading.Quantity
-	for rfidIndex, rfidItem := range eventsProcessing.currentRFIDData {
+	for _, rfidIndex := range eventsProcessing.rfidCandidateOrder() {
+		rfidItem := eventsProcessing.currentRFIDData[rfidIndex]
+		if rfidItem.UPC != rttlReading.ProductId {
+			continue
+		}
+		decision := Decision{Detector: DetectorRFID, Reference: rfidItem.EPC, EventTime: rfidItem.EventTime}
 		if rttlQuantity == 0 {
+			decision.Reason = DecisionRFIDQuantityReached
+			eventsProcessing.traceDecision(rttlReading, decision)
 			break
 		}
 
 		//todo - && !AtGoBack && !AtEntrance
 		//todo - priority of removing suspect RFID items (Bagging area first, then scanner, etc.)
-		if rfidItem.AssociatedRTTLEntry == nil && rfidItem.UPC == rttlReading.ProductId {
+		if rfidItem.AssociatedRTTLEntry != nil {
+			decision.Reason = DecisionRFIDAlreadyAssociated
+			eventsProcessing.traceDecision(rttlReading, decision)
+		} else {
 			//cross associate
+			decision.Accepted, decision.Reason = true, DecisionRFIDMatched
+			eventsProcessing.traceDecision(rttlReading, decision)
 			rttlReading.AssociatedRFIDItems = append(rttlReading.AssociatedRFIDItems, &eventsProcessing.currentRFIDData[rfidIndex])
 			eventsProcessing.currentRFIDData[rfidIndex].AssociatedRTTLEntry = rttlReading
 			rttlQuantity--
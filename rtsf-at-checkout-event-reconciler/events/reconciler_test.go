@@ -52,7 +52,7 @@ func BasketOpen(processor *EventsProcessor) {
 	processor.scaleData = []ScaleEventEntry{}
 	processor.rttlogData = append(processor.rttlogData, RTTLogEventEntry{})
 	processor.suspectScaleItems = make(map[int64]*ScaleEventEntry)
-	processor.eventOccurred = make(map[string]bool)
+	processor.hasPOSItems = false
 }
 
 func TestScaleBasketReconciliationDropDropScan4DropDrop(t *testing.T) {
@@ -396,3 +396,33 @@ func TestRFIDBasketReconciliation(t *testing.T) {
 	assert.Equal(t, len(rttlEntry.AssociatedRFIDItems), 1)
 	assert.True(t, rttlEntry.RFIDConfirmed)
 }
+
+func TestCVTimeAlignment(t *testing.T) {
+	tests := []struct {
+		name            string
+		cvTimeAlignment time.Duration
+		cvEventTime     int64
+		cvConfirmed     bool
+	}{
+		{name: "within alignment", cvTimeAlignment: 5 * time.Second, cvEventTime: 1559679673000 - 4999, cvConfirmed: true},
+		{name: "cv after the scan", cvTimeAlignment: 5 * time.Second, cvEventTime: 1559679673000 + 4999, cvConfirmed: true},
+		{name: "outside alignment", cvTimeAlignment: 5 * time.Second, cvEventTime: 1559679673000 - 5000, cvConfirmed: false},
+		{name: "alignment ignored", cvTimeAlignment: -1, cvEventTime: 1559679000000, cvConfirmed: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eventsProcessing := EventsProcessor{cvTimeAlignment: test.cvTimeAlignment}
+			BasketOpen(&eventsProcessing)
+			eventsProcessing.currentCVData = []CVEventEntry{{
+				ObjectName: "123",
+				ROIs:       map[string]ROILocation{ScannerROI: {LastAtLocation: test.cvEventTime}},
+			}}
+
+			posEntry := RTTLogEventEntry{ProductName: "123", EventTime: 1559679673000, Quantity: 1}
+			eventsProcessing.cvBasketReconciliation(&posEntry)
+
+			assert.Equal(t, test.cvConfirmed, posEntry.CVConfirmed)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+
+	"event-reconciler/reorder"
+)
+
+// EnableReorderBuffer holds the readings of each lane for the lateness window before processing
+// them in event_time order, the buffer follows the clock of the processor
+func (eventsProcessing *EventsProcessor) EnableReorderBuffer(lateness time.Duration) {
+	eventsProcessing.reorderBuffer = reorder.NewBuffer(lateness, eventsProcessing.clock)
+}
+
+// FlushReorderBuffer processes the readings held for the lateness window, it needs to be called
+// periodically since no later reading may arrive on the lane to release them
+func (eventsProcessing *EventsProcessor) FlushReorderBuffer(edgexcontext interfaces.AppFunctionContext) {
+	if eventsProcessing.reorderBuffer == nil {
+		return
+	}
+	eventsProcessing.processMu.Lock()
+	defer eventsProcessing.processMu.Unlock()
+	eventsProcessing.processReadings(eventsProcessing.reorderBuffer.Flush(), edgexcontext)
+}
+
+// GetReorderMetrics returns the counts of the readings that went through the reorder buffer
+func (eventsProcessing *EventsProcessor) GetReorderMetrics() reorder.Metrics {
+	if eventsProcessing.reorderBuffer == nil {
+		return reorder.Metrics{}
+	}
+	return eventsProcessing.reorderBuffer.Metrics()
+}
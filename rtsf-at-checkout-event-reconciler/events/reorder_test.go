@@ -0,0 +1,112 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"event-reconciler/clock"
+	"event-reconciler/config"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCheckoutEvent(deviceName string, resourceName string, data map[string]interface{}) dtos.Event {
+	reading := dtos.NewObjectReading(deviceName, deviceName, resourceName, data)
+	simulateJson, _ := json.Marshal(reading)
+	simulateStruct := dtos.BaseReading{}
+	_ = json.Unmarshal(simulateJson, &simulateStruct)
+
+	event := dtos.NewEvent(deviceName, deviceName, resourceName)
+	event.Readings = []dtos.BaseReading{simulateStruct}
+	return event
+}
+
+func TestProcessCheckoutEventsReorderBuffer(t *testing.T) {
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{
+		DevicePos:   "pos",
+		DeviceScale: "scale",
+		DeviceCV:    "cv-roi",
+		DeviceRFID:  "rfid-roi",
+	})
+	eventsProcessor.ResetCheckoutState()
+	eventsProcessor.SetClock(manualClock)
+	eventsProcessor.EnableReorderBuffer(500 * time.Millisecond)
+
+	events := []dtos.Event{
+		testCheckoutEvent("pos-rest", basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1700000001000}),
+		// the second weight arrives before the first one
+		testCheckoutEvent("scale-rest", scaleItemEvent, map[string]interface{}{"lane_id": "1", "total": 1.0, "units": "lbs", "event_time": 1700000001400}),
+		testCheckoutEvent("scale-rest", scaleItemEvent, map[string]interface{}{"lane_id": "1", "total": 0.5, "units": "lbs", "event_time": 1700000001200}),
+	}
+	for _, event := range events {
+		_, err := eventsProcessor.ProcessCheckoutEvents(context, event)
+		assert.Nil(t, err)
+	}
+	assert.Empty(t, eventsProcessor.scaleData)
+	assert.Equal(t, 3, eventsProcessor.GetReorderMetrics().Pending)
+
+	manualClock.Advance(500 * time.Millisecond)
+	eventsProcessor.FlushReorderBuffer(context)
+
+	require.Len(t, eventsProcessor.scaleData, 2)
+	assert.Equal(t, 0.5, eventsProcessor.scaleData[0].Total)
+	assert.Equal(t, 1.0, eventsProcessor.scaleData[1].Total)
+
+	metrics := eventsProcessor.GetReorderMetrics()
+	assert.Equal(t, int64(3), metrics.Released)
+	assert.Equal(t, int64(1), metrics.Reordered)
+	assert.Equal(t, 0, metrics.Pending)
+}
+
+func TestProcessCheckoutEventsReorderBufferConcurrentFlush(t *testing.T) {
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{
+		DevicePos:   "pos",
+		DeviceScale: "scale",
+		DeviceCV:    "cv-roi",
+		DeviceRFID:  "rfid-roi",
+	})
+	eventsProcessor.ResetCheckoutState()
+	eventsProcessor.SetClock(manualClock)
+	eventsProcessor.EnableReorderBuffer(50 * time.Millisecond)
+
+	_, err := eventsProcessor.ProcessCheckoutEvents(context, testCheckoutEvent("pos-rest", basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1700000000000}))
+	require.Nil(t, err)
+
+	// the readings are released by the ingest and by the flush ticker at the same time
+	const readings = 200
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for index := 1; index <= readings; index++ {
+			_, err := eventsProcessor.ProcessCheckoutEvents(context, testCheckoutEvent("scale-rest", scaleItemEvent, map[string]interface{}{
+				"lane_id": "1", "total": float64(index), "units": "lbs", "event_time": 1700000000000 + int64(index)*10,
+			}))
+			assert.Nil(t, err)
+		}
+	}()
+	flushing := true
+	for flushing {
+		select {
+		case <-done:
+			flushing = false
+		default:
+			manualClock.Advance(5 * time.Millisecond)
+			eventsProcessor.FlushReorderBuffer(context)
+		}
+	}
+	manualClock.Advance(50 * time.Millisecond)
+	eventsProcessor.FlushReorderBuffer(context)
+
+	require.Len(t, eventsProcessor.scaleData, readings)
+	for index, scaleItem := range eventsProcessor.scaleData {
+		assert.Equal(t, float64(index+1), scaleItem.Total)
+	}
+	assert.Zero(t, eventsProcessor.GetReorderMetrics().Late)
+}
@@ -0,0 +1,118 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"event-reconciler/journal"
+	"event-reconciler/rfidgtin"
+)
+
+// SoldSerial is the sale of an RFID tagged item, the serial being the SGTIN pure identity URI of its tag
+type SoldSerial struct {
+	Serial        string `json:"serial"`
+	LaneId        string `json:"lane_id"`
+	TransactionId string `json:"transaction_id"`
+	EventTime     int64  `json:"event_time"`
+}
+
+// rfidSerial returns the SGTIN pure identity URI of the EPC, which identifies the item whatever the
+// filter value the tag is encoded with, or the EPC itself when it is not SGTIN encoded
+func rfidSerial(epc string) string {
+	serial, err := rfidgtin.GetSGTINPureURI(epc)
+	if err != nil {
+		return epc
+	}
+	return serial
+}
+
+// loadSoldSerials indexes the serials sold in the journaled transactions, the tags sold within the sold EPC
+// time to live are registered again
+func (eventsProcessing *EventsProcessor) loadSoldSerials() error {
+	records, err := eventsProcessing.GetTransactions(journal.Query{})
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		eventsProcessing.recordSoldSerials(record)
+		eventsProcessing.registerJournaledSoldEPCs(record)
+	}
+	return nil
+}
+
+// recordSoldSerials indexes the serials sold in the transaction, the latest sale of a serial being kept
+func (eventsProcessing *EventsProcessor) recordSoldSerials(record TransactionRecord) {
+	if eventsProcessing.soldSerials == nil {
+		eventsProcessing.soldSerials = make(map[string]SoldSerial)
+	}
+	for _, serial := range record.SoldSerials {
+		eventsProcessing.soldSerials[serial] = SoldSerial{Serial: serial, LaneId: record.LaneId, TransactionId: record.TransactionId, EventTime: record.EndTime}
+	}
+}
+
+// markPreviouslySold references the transaction the serial of the RFID item was sold in, if any
+func (eventsProcessing *EventsProcessor) markPreviouslySold(rfidItem *RFIDEventEntry) {
+	sale, ok := eventsProcessing.soldSerials[rfidItem.Serial]
+	if !ok {
+		return
+	}
+	rfidItem.SoldTransactionId = sale.TransactionId
+	rfidItem.SoldTime = sale.EventTime
+}
+
+func previouslySold(rfidItem RFIDEventEntry) bool {
+	return len(rfidItem.SoldTransactionId) > 0
+}
+
+// rfidCandidateOrder returns the indexes of the RFID items, the items never sold first so that a
+// line is matched with a previously sold item only when no other tag of the product was read
+func (eventsProcessing *EventsProcessor) rfidCandidateOrder() []int {
+	order := []int{}
+	for _, sold := range []bool{false, true} {
+		for rfidIndex, rfidItem := range eventsProcessing.currentRFIDData {
+			if previouslySold(rfidItem) == sold {
+				order = append(order, rfidIndex)
+			}
+		}
+	}
+	return order
+}
+
+// getSoldTwiceRFIDItems returns the RFID items matched with a line while their serial was already sold.
+// The items are copies detached from their line, which references them back and cannot be marshaled.
+func (eventsProcessing *EventsProcessor) getSoldTwiceRFIDItems() []RFIDEventEntry {
+	soldTwiceItems := []RFIDEventEntry{}
+	for _, rfidItem := range eventsProcessing.currentRFIDData {
+		if previouslySold(rfidItem) && rfidItem.AssociatedRTTLEntry != nil {
+			rfidItem.AssociatedRTTLEntry = nil
+			soldTwiceItems = append(soldTwiceItems, rfidItem)
+		}
+	}
+	return soldTwiceItems
+}
+
+// getPreviouslySoldRFIDItems returns the RFID items not matched with a line whose serial was already
+// sold, they are items brought back in the store rather than unscanned items
+func (eventsProcessing *EventsProcessor) getPreviouslySoldRFIDItems() []RFIDEventEntry {
+	previouslySoldItems := []RFIDEventEntry{}
+	for _, rfidItem := range eventsProcessing.currentRFIDData {
+		if previouslySold(rfidItem) && rfidItem.AssociatedRTTLEntry == nil {
+			previouslySoldItems = append(previouslySoldItems, rfidItem)
+		}
+	}
+	return previouslySoldItems
+}
+
+// rfidSerialsOf returns the serials of the RFID items matched with the POS item lines
+func rfidSerialsOf(lines []RTTLogEventEntry) []string {
+	serials := []string{}
+	for _, line := range lines {
+		if line.EventType != posItemEvent {
+			continue
+		}
+		for _, rfidItem := range line.AssociatedRFIDItems {
+			serials = append(serials, rfidItem.Serial)
+		}
+	}
+	return serials
+}
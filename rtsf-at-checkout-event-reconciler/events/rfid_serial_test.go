@@ -0,0 +1,159 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"event-reconciler/config"
+	"event-reconciler/journal"
+)
+
+const (
+	testSteakEPC = "30140000001FB28000003039"
+	// the same tag encoded with another filter value
+	testSteakEPCFilter1 = "30340000001FB28000003039"
+	testSteakSerial     = "urn:epc:id:sgtin:0000000.032458.12345"
+)
+
+func newRFIDSerialTestProcessor(t *testing.T) *EventsProcessor {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(ProductDetails{Name: "Steak", ExpectedMinWeight: 1.3, ExpectedMaxWeight: 1.4, RFIDEligible: true})
+	}))
+	t.Cleanup(server.Close)
+
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{
+		DevicePos:             "pos",
+		DeviceScale:           "scale",
+		DeviceCV:              "cv-roi",
+		DeviceRFID:            "rfid-roi",
+		ProductLookupEndpoint: strings.TrimPrefix(server.URL, "http://"),
+	})
+	eventsProcessor.ResetCheckoutState()
+	transactionJournal, err := journal.NewJournal(filepath.Join(t.TempDir(), "transactions.ndjson"))
+	require.NoError(t, err)
+	t.Cleanup(func() { transactionJournal.Close() })
+	require.NoError(t, eventsProcessor.SetJournal(transactionJournal))
+	return eventsProcessor
+}
+
+func processRFIDEvent(t *testing.T, eventsProcessor *EventsProcessor, epc string, roiName string, eventTime int64) {
+	_, err := eventsProcessor.ProcessCheckoutEvents(context, testCheckoutEvent("rfid-roi-rest", rfidRoiEvent, map[string]interface{}{
+		"epc":        epc,
+		"roi_name":   roiName,
+		"roi_action": ROIActionEnter,
+		"event_time": eventTime,
+	}))
+	assert.Nil(t, err)
+}
+
+func scannedSteak(eventTime int64) map[string]interface{} {
+	return map[string]interface{}{
+		"lane_id":       "1",
+		"basket_id":     "abc",
+		"product_id":    "324588",
+		"product_name":  "Steak",
+		"quantity":      1,
+		"quantity_unit": quantityUnitEA,
+		"event_time":    eventTime,
+	}
+}
+
+func sellSteak(t *testing.T, eventsProcessor *EventsProcessor, transactionID string, epc string, eventTime int64) {
+	transaction := func(eventTime int64) map[string]interface{} {
+		return map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": transactionID, "event_time": eventTime}
+	}
+	processPosEvent(t, eventsProcessor, basketOpenEvent, transaction(eventTime))
+	processRFIDEvent(t, eventsProcessor, epc, BaggingROI, eventTime+100)
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedSteak(eventTime+200))
+	processPosEvent(t, eventsProcessor, paymentStartEvent, transaction(eventTime+300))
+	processPosEvent(t, eventsProcessor, paymentSuccessEvent, transaction(eventTime+400))
+	processPosEvent(t, eventsProcessor, basketCloseEvent, transaction(eventTime+500))
+}
+
+func TestRFIDSerialSoldTwice(t *testing.T) {
+	eventsProcessor := newRFIDSerialTestProcessor(t)
+	sellSteak(t, eventsProcessor, "t-1", testSteakEPC, 1000)
+	assert.Empty(t, eventsProcessor.GetFindings("", true))
+
+	records, err := eventsProcessor.GetTransactions(journal.Query{Serial: testSteakSerial})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "t-1", records[0].TransactionId)
+	require.Len(t, records[0].RFIDObservations, 1)
+	assert.Equal(t, testSteakSerial, records[0].RFIDObservations[0].Serial)
+
+	// the tag is read with another filter value, its serial is the same
+	sellSteak(t, eventsProcessor, "t-2", testSteakEPCFilter1, 2000)
+	findings := eventsProcessor.GetFindings("", true)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "t-2", findings[0].TransactionId)
+	assert.Equal(t, ReasonSerialSoldTwice, findings[0].ReasonCode)
+	assert.Equal(t, testSteakSerial, findings[0].Serial)
+	assert.Equal(t, FindingEvidence{Event: basketCloseEvent, Reference: "t-1", EventTime: 1500}, findings[0].Evidence[1])
+
+	// the latest sale is kept
+	records, err = eventsProcessor.GetTransactions(journal.Query{Serial: testSteakSerial})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "t-2", eventsProcessor.soldSerials[testSteakSerial].TransactionId)
+}
+
+func TestRFIDSerialSoldTwiceOutput(t *testing.T) {
+	eventsProcessor := newRFIDSerialTestProcessor(t)
+	sellSteak(t, eventsProcessor, "t-1", testSteakEPC, 1000)
+
+	transaction := map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-2", "event_time": 2000}
+	processPosEvent(t, eventsProcessor, basketOpenEvent, transaction)
+	processRFIDEvent(t, eventsProcessor, testSteakEPCFilter1, BaggingROI, 2100)
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedSteak(2200))
+	context.SetResponseData(nil)
+	processPosEvent(t, eventsProcessor, paymentStartEvent, transaction)
+
+	// the sold twice item is detached from its line, which references it back
+	require.NotEmpty(t, context.ResponseData())
+	var suspectList SuspectLists
+	require.NoError(t, json.Unmarshal(context.ResponseData(), &suspectList))
+	require.Len(t, suspectList.RFIDSoldTwice, 1)
+	assert.Equal(t, testSteakSerial, suspectList.RFIDSoldTwice[0].Serial)
+	assert.Equal(t, "t-1", suspectList.RFIDSoldTwice[0].SoldTransactionId)
+	require.Len(t, suspectList.Findings, 1)
+	assert.Equal(t, ReasonSerialSoldTwice, suspectList.Findings[0].ReasonCode)
+
+	// the item is still matched with the line
+	require.NotNil(t, eventsProcessor.currentRFIDData[0].AssociatedRTTLEntry)
+}
+
+func TestRFIDSoldTagReentered(t *testing.T) {
+	eventsProcessor := newRFIDSerialTestProcessor(t)
+	sellSteak(t, eventsProcessor, "t-1", testSteakEPC, 1000)
+
+	// the sold steak is brought back in the store while another steak is bought
+	transaction := map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-2", "event_time": 2000}
+	processPosEvent(t, eventsProcessor, basketOpenEvent, transaction)
+	processRFIDEvent(t, eventsProcessor, testSteakEPC, EntranceROI, 2100)
+	processRFIDEvent(t, eventsProcessor, "30140000001FB2800000303A", BaggingROI, 2200)
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedSteak(2300))
+
+	// the steak never sold is matched with the line
+	line := eventsProcessor.rttlogData[len(eventsProcessor.rttlogData)-1]
+	require.Len(t, line.AssociatedRFIDItems, 1)
+	assert.Equal(t, "urn:epc:id:sgtin:0000000.032458.12346", line.AssociatedRFIDItems[0].Serial)
+	assert.Empty(t, eventsProcessor.getSuspectRFIDItems())
+
+	processPosEvent(t, eventsProcessor, paymentStartEvent, transaction)
+	findings := eventsProcessor.GetFindings("", true)
+	require.Len(t, findings, 1)
+	assert.Equal(t, ReasonSoldTagReentered, findings[0].ReasonCode)
+	assert.Equal(t, testSteakSerial, findings[0].Serial)
+}
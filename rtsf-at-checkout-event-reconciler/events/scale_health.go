@@ -0,0 +1,70 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"event-reconciler/config"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+)
+
+func (eventsProcessing *EventsProcessor) processDeviceScaleHealthReading(reading dtos.BaseReading, lc logger.LoggingClient) {
+	healthReading := ScaleHealthEntry{}
+	err := eventsProcessing.unmarshalObjValue(reading.ObjectReading.ObjectValue, &healthReading)
+	if err != nil {
+		lc.Errorf("Scale health unmarshal failure: %v", err)
+		return
+	}
+
+	if eventsProcessing.scaleHealth == nil {
+		eventsProcessing.scaleHealth = make(map[string]ScaleHealthEntry)
+	}
+
+	previous, ok := eventsProcessing.scaleHealth[healthReading.ScaleId]
+	if !healthReading.Healthy && (!ok || previous.Healthy) {
+		lc.Warnf("Scale %s on lane %s is degraded: %v", healthReading.ScaleId, healthReading.LaneId, healthReading.DegradationReasons)
+	} else if healthReading.Healthy && ok && !previous.Healthy {
+		lc.Infof("Scale %s on lane %s recovered", healthReading.ScaleId, healthReading.LaneId)
+	}
+
+	eventsProcessing.scaleHealth[healthReading.ScaleId] = healthReading
+}
+
+// isScaleDegraded checks if any scale of the lane reported itself as unhealthy
+func (eventsProcessing *EventsProcessor) isScaleDegraded() bool {
+	for _, health := range eventsProcessing.scaleHealth {
+		if !health.Healthy {
+			return true
+		}
+	}
+	return false
+}
+
+// scaleSuspectsSuppressed checks if scale suspects should be left out entirely
+func (eventsProcessing *EventsProcessor) scaleSuspectsSuppressed() bool {
+	if !eventsProcessing.isScaleDegraded() {
+		return false
+	}
+	return eventsProcessing.processConfig == nil || eventsProcessing.processConfig.ScaleDegradedSuspects != config.ScaleDegradedDownWeight
+}
+
+// getReportedSuspectScaleItems returns the scale suspects taking the health of the scales into account
+func (eventsProcessing *EventsProcessor) getReportedSuspectScaleItems() map[int64]*ScaleEventEntry {
+	if eventsProcessing.scaleSuspectsSuppressed() {
+		return make(map[int64]*ScaleEventEntry)
+	}
+	return eventsProcessing.getSuspectScaleItems()
+}
+
+// hasReportableSuspects checks if the suspects are reliable enough to be reported,
+// scale suspects of a degraded scale alone do not trigger a report
+func (eventsProcessing *EventsProcessor) hasReportableSuspects(suspectCVItems []CVEventEntry, suspectRFIDItems []RFIDEventEntry) bool {
+	if len(suspectCVItems) > 0 || len(suspectRFIDItems) > 0 || len(eventsProcessing.getHighValueOverrides()) > 0 ||
+		len(eventsProcessing.getLowConfidenceLines()) > 0 || len(eventsProcessing.getSoldTwiceRFIDItems()) > 0 ||
+		len(eventsProcessing.getPreviouslySoldRFIDItems()) > 0 {
+		return true
+	}
+	return len(eventsProcessing.suspectScaleItems) > 0 && !eventsProcessing.isScaleDegraded()
+}
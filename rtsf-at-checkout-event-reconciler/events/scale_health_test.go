@@ -0,0 +1,130 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"event-reconciler/config"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func initScaleHealthReading(scaleId string, healthy bool) dtos.BaseReading {
+	reasons := []string{}
+	if !healthy {
+		reasons = append(reasons, "zero drift too high")
+	}
+	reading := dtos.NewObjectReading(
+		"",
+		"",
+		scaleHealthEvent,
+		ScaleHealthEntry{
+			LaneId:             "1",
+			ScaleId:            scaleId,
+			Healthy:            healthy,
+			Connected:          true,
+			DegradationReasons: reasons,
+			ZeroDrift:          0.04,
+			EventTime:          1559679665,
+		},
+	)
+	simulateJson, _ := json.Marshal(reading)
+	simulateStruct := dtos.BaseReading{}
+	_ = json.Unmarshal(simulateJson, &simulateStruct)
+
+	return simulateStruct
+}
+
+func TestProcessDeviceScaleHealthReading(t *testing.T) {
+	lc := logger.NewMockClient()
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{})
+
+	eventsProcessor.processDeviceScaleHealthReading(initScaleHealthReading("bagging", true), lc)
+	eventsProcessor.processDeviceScaleHealthReading(initScaleHealthReading("scanner", true), lc)
+	assert.False(t, eventsProcessor.isScaleDegraded())
+
+	eventsProcessor.processDeviceScaleHealthReading(initScaleHealthReading("scanner", false), lc)
+	assert.True(t, eventsProcessor.isScaleDegraded())
+	assert.Equal(t, []string{"zero drift too high"}, eventsProcessor.scaleHealth["scanner"].DegradationReasons)
+
+	eventsProcessor.processDeviceScaleHealthReading(initScaleHealthReading("scanner", true), lc)
+	assert.False(t, eventsProcessor.isScaleDegraded())
+}
+
+func TestScaleDegradedSuspects(t *testing.T) {
+	tables := []struct {
+		name                  string
+		scaleDegradedSuspects string
+		healthy               bool
+		withCVSuspect         bool
+		expectedReportable    bool
+		expectedScaleSuspects int
+	}{
+		{
+			name:                  "healthy scale",
+			scaleDegradedSuspects: config.ScaleDegradedSuppress,
+			healthy:               true,
+			expectedReportable:    true,
+			expectedScaleSuspects: 1,
+		},
+		{
+			name:                  "degraded scale suppressed",
+			scaleDegradedSuspects: config.ScaleDegradedSuppress,
+			healthy:               false,
+			expectedReportable:    false,
+			expectedScaleSuspects: 0,
+		},
+		{
+			name:                  "degraded scale suppressed with CV suspect",
+			scaleDegradedSuspects: config.ScaleDegradedSuppress,
+			healthy:               false,
+			withCVSuspect:         true,
+			expectedReportable:    true,
+			expectedScaleSuspects: 0,
+		},
+		{
+			name:                  "degraded scale down-weighted",
+			scaleDegradedSuspects: config.ScaleDegradedDownWeight,
+			healthy:               false,
+			expectedReportable:    false,
+			expectedScaleSuspects: 1,
+		},
+		{
+			name:                  "degraded scale down-weighted with CV suspect",
+			scaleDegradedSuspects: config.ScaleDegradedDownWeight,
+			healthy:               false,
+			withCVSuspect:         true,
+			expectedReportable:    true,
+			expectedScaleSuspects: 1,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			lc := logger.NewMockClient()
+			eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{ScaleDegradedSuspects: table.scaleDegradedSuspects})
+			eventsProcessor.processDeviceScaleHealthReading(initScaleHealthReading("bagging", table.healthy), lc)
+			eventsProcessor.suspectScaleItems[1559679665] = &ScaleEventEntry{Delta: 1.5, Total: 1.5, EventTime: 1559679665}
+
+			suspectCVItems := []CVEventEntry{}
+			if table.withCVSuspect {
+				suspectCVItems = append(suspectCVItems, CVEventEntry{ObjectName: "Red Apples"})
+			}
+
+			assert.Equal(t, table.expectedReportable, eventsProcessor.hasReportableSuspects(suspectCVItems, []RFIDEventEntry{}))
+
+			output, err := eventsProcessor.wrapSuspectItems(eventsProcessor.getSuspectLists())
+			require.NoError(t, err)
+			suspectLists := SuspectLists{}
+			require.NoError(t, json.Unmarshal(output, &suspectLists))
+			assert.Equal(t, table.expectedScaleSuspects, len(suspectLists.ScaleSuspect))
+			assert.Equal(t, !table.healthy, suspectLists.ScaleDegraded)
+		})
+	}
+}
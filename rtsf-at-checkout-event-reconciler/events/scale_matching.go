@@ -0,0 +1,336 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// scaleMatchingStepsPerReading bounds the search for the scale matching by the size of the basket, as
+	// the basket is matched again on every scale, scan and remove event. The search backtracks from the
+	// latest readings first, so the best matching found within the bound revisits the readings that
+	// changed since the last event.
+	scaleMatchingStepsPerReading = 200
+	// minScaleMatchingSteps lets the search of the small baskets run to the end
+	minScaleMatchingSteps = 5000
+	// unmatchedLine is the line of a scale reading left as a suspect
+	unmatchedLine = -1
+)
+
+// scaleMatchingLine is an RTT log line that scale readings can be matched with
+type scaleMatchingLine struct {
+	rttlIndex int
+	// minDelta is the lightest reading the line accepts, the minimum weight of a single item
+	minDelta float64
+	// capacity is the heaviest total weight the line accounts for
+	capacity float64
+}
+
+// scaleMatchingScore ranks the matchings, the fields are compared in order. The fewer suspects
+// the better, then the fewer readings moved away from the line they were matched with, the more
+// confirmed lines and the closer in time the readings are to the scan of their line.
+type scaleMatchingScore struct {
+	suspects   int
+	reassigned int
+	confirmed  int
+	timeCost   int64
+}
+
+func (score scaleMatchingScore) betterThan(other scaleMatchingScore) bool {
+	if score.suspects != other.suspects {
+		return score.suspects < other.suspects
+	}
+	if score.reassigned != other.reassigned {
+		return score.reassigned < other.reassigned
+	}
+	if score.confirmed != other.confirmed {
+		return score.confirmed > other.confirmed
+	}
+	return score.timeCost < other.timeCost
+}
+
+// scaleMatcher searches the matching of the scale readings with the lines of the basket
+type scaleMatcher struct {
+	processor  *EventsProcessor
+	lines      []scaleMatchingLine
+	readings   []int
+	previous   []int
+	candidates [][]int
+	// unmatchableAfter counts the readings without candidate line from a position onwards
+	unmatchableAfter []int
+	assignment       []int
+	weights          []float64
+	best             []int
+	bestScore        scaleMatchingScore
+	found            bool
+	steps            int
+	maxSteps         int
+}
+
+// scaleMatchingLines returns the lines of the basket with the weight window of their readings
+func (eventsProcessing *EventsProcessor) scaleMatchingLines() []scaleMatchingLine {
+	lines := []scaleMatchingLine{}
+	for rttlIndex, line := range eventsProcessing.rttlogData {
+		if line.ProductId == "" || line.Quantity <= floatingPointTolerance {
+			continue
+		}
+
+		matchingLine := scaleMatchingLine{rttlIndex: rttlIndex}
+		if eventsProcessing.rttlQuantityIsEach(line) {
+			matchingLine.minDelta = line.ProductDetails.ExpectedMinWeight
+			matchingLine.capacity = line.ProductDetails.ExpectedMaxWeight * line.Quantity
+			// the weight of one more item would be released by checkScaleConfirmed
+			if overQuantity := line.ProductDetails.ExpectedMinWeight*(line.Quantity+1) - scalePrecision; line.ProductDetails.ExpectedMinWeight > 0 && overQuantity < matchingLine.capacity {
+				matchingLine.capacity = overQuantity
+			}
+		} else {
+			tolerance := eventsProcessing.GetScaleToScaleTolerance()
+			matchingLine.capacity = math.Inf(1)
+			if tolerance < 1 {
+				matchingLine.capacity = line.Quantity / (1 - tolerance)
+			}
+		}
+		lines = append(lines, matchingLine)
+	}
+	return lines
+}
+
+// matchedLine returns the position, among the matching lines, of the line the reading is matched with
+func (eventsProcessing *EventsProcessor) matchedLine(scaleItem ScaleEventEntry, lines []scaleMatchingLine) int {
+	if scaleItem.AssociatedRTTLEntry == nil {
+		return unmatchedLine
+	}
+	// the entry may be a copy left behind when the RTT log grew, so it is looked up by product and scan time
+	for position, line := range lines {
+		rttlEntry := eventsProcessing.rttlogData[line.rttlIndex]
+		if rttlEntry.ProductId == scaleItem.AssociatedRTTLEntry.ProductId && rttlEntry.EventTime == scaleItem.AssociatedRTTLEntry.EventTime {
+			return position
+		}
+	}
+	return unmatchedLine
+}
+
+func newScaleMatcher(eventsProcessing *EventsProcessor) *scaleMatcher {
+	matcher := &scaleMatcher{processor: eventsProcessing, lines: eventsProcessing.scaleMatchingLines()}
+
+	for scaleIndex, scaleItem := range eventsProcessing.scaleData {
+		if scaleItem.Delta <= scalePrecision || scaleItem.Removed {
+			continue
+		}
+		matcher.readings = append(matcher.readings, scaleIndex)
+		matcher.previous = append(matcher.previous, eventsProcessing.matchedLine(scaleItem, matcher.lines))
+
+		candidates := []int{}
+		for position, line := range matcher.lines {
+			if scaleItem.Delta >= line.minDelta-floatingPointTolerance && scaleItem.Delta <= line.capacity+floatingPointTolerance {
+				candidates = append(candidates, position)
+			}
+		}
+		// the line previously matched is tried first, then the lines closest in time
+		previous := matcher.previous[len(matcher.previous)-1]
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if (candidates[i] == previous) != (candidates[j] == previous) {
+				return candidates[i] == previous
+			}
+			return matcher.timeCost(scaleIndex, candidates[i]) < matcher.timeCost(scaleIndex, candidates[j])
+		})
+		matcher.candidates = append(matcher.candidates, candidates)
+	}
+
+	matcher.unmatchableAfter = make([]int, len(matcher.readings)+1)
+	for position := len(matcher.readings) - 1; position >= 0; position-- {
+		matcher.unmatchableAfter[position] = matcher.unmatchableAfter[position+1]
+		if len(matcher.candidates[position]) == 0 {
+			matcher.unmatchableAfter[position]++
+		}
+	}
+
+	matcher.assignment = make([]int, len(matcher.readings))
+	matcher.weights = make([]float64, len(matcher.lines))
+	matcher.maxSteps = scaleMatchingStepsPerReading * len(matcher.readings)
+	if matcher.maxSteps < minScaleMatchingSteps {
+		matcher.maxSteps = minScaleMatchingSteps
+	}
+	return matcher
+}
+
+func (matcher *scaleMatcher) timeCost(scaleIndex int, position int) int64 {
+	gap := matcher.processor.scaleData[scaleIndex].EventTime - matcher.processor.rttlogData[matcher.lines[position].rttlIndex].EventTime
+	if gap < 0 {
+		return -gap
+	}
+	return gap
+}
+
+// solve returns, for every reading, the position of its line or unmatchedLine
+func (matcher *scaleMatcher) solve() []int {
+	matcher.search(0, 0, 0)
+	return matcher.best
+}
+
+func (matcher *scaleMatcher) search(position int, suspects int, reassigned int) {
+	matcher.steps++
+	if matcher.steps > matcher.maxSteps && matcher.found {
+		return
+	}
+
+	// the readings without candidate line are suspects whatever the matching of the others
+	bound := scaleMatchingScore{suspects: suspects + matcher.unmatchableAfter[position], reassigned: reassigned}
+	if matcher.found && (bound.suspects > matcher.bestScore.suspects ||
+		(bound.suspects == matcher.bestScore.suspects && bound.reassigned > matcher.bestScore.reassigned)) {
+		return
+	}
+
+	if position == len(matcher.readings) {
+		score := matcher.score(suspects, reassigned)
+		if !matcher.found || score.betterThan(matcher.bestScore) {
+			matcher.best = append([]int{}, matcher.assignment...)
+			matcher.bestScore = score
+			matcher.found = true
+		}
+		return
+	}
+
+	delta := matcher.processor.scaleData[matcher.readings[position]].Delta
+	previous := matcher.previous[position]
+	for _, line := range matcher.candidates[position] {
+		if matcher.weights[line]+delta > matcher.lines[line].capacity+floatingPointTolerance {
+			continue
+		}
+		moved := 0
+		if previous != unmatchedLine && previous != line {
+			moved = 1
+		}
+		matcher.assignment[position] = line
+		matcher.weights[line] += delta
+		matcher.search(position+1, suspects, reassigned+moved)
+		matcher.weights[line] -= delta
+	}
+
+	moved := 0
+	if previous != unmatchedLine {
+		moved = 1
+	}
+	matcher.assignment[position] = unmatchedLine
+	matcher.search(position+1, suspects+1, reassigned+moved)
+}
+
+func (matcher *scaleMatcher) score(suspects int, reassigned int) scaleMatchingScore {
+	score := scaleMatchingScore{suspects: suspects, reassigned: reassigned}
+	for position, line := range matcher.lines {
+		if matcher.weights[position] > 0 && matcher.processor.scaleWeightConfirmed(matcher.processor.rttlogData[line.rttlIndex], matcher.weights[position]) {
+			score.confirmed++
+		}
+	}
+	for position, line := range matcher.assignment {
+		if line != unmatchedLine {
+			score.timeCost += matcher.timeCost(matcher.readings[position], line)
+		}
+	}
+	return score
+}
+
+// scaleWeightConfirmed tells whether the weight on the scale accounts for the quantity of the line
+func (eventsProcessing *EventsProcessor) scaleWeightConfirmed(line RTTLogEventEntry, weight float64) bool {
+	if eventsProcessing.rttlQuantityIsEach(line) {
+		return line.ProductDetails.ExpectedMinWeight*line.Quantity-weight <= floatingPointTolerance
+	}
+
+	var percentChange float64
+	if weight != 0 {
+		percentChange = math.Abs((line.Quantity - weight) / weight)
+	}
+	return percentChange < eventsProcessing.GetScaleToScaleTolerance() || percentChange == 0
+}
+
+// matchScaleItems matches the scale readings of the basket with its lines all over again, so that a
+// reading matched on arrival moves to another line when that leaves fewer suspects. The readings
+// left unmatched are the suspect scale items.
+func (eventsProcessing *EventsProcessor) matchScaleItems() {
+	if len(eventsProcessing.scaleData) == 0 {
+		return
+	}
+
+	matcher := newScaleMatcher(eventsProcessing)
+	assignment := matcher.solve()
+
+	for _, line := range matcher.lines {
+		eventsProcessing.rttlogData[line.rttlIndex].AssociatedScaleItems = []*ScaleEventEntry{}
+	}
+	for scaleIndex := range eventsProcessing.scaleData {
+		eventsProcessing.scaleData[scaleIndex].AssociatedRTTLEntry = nil
+	}
+
+	eventsProcessing.suspectScaleItems = make(map[int64]*ScaleEventEntry)
+	for position, scaleIndex := range matcher.readings {
+		scaleItem := &eventsProcessing.scaleData[scaleIndex]
+		if assignment[position] == unmatchedLine {
+			eventsProcessing.suspectScaleItems[scaleItem.EventTime] = scaleItem
+			continue
+		}
+		rttlEntry := &eventsProcessing.rttlogData[matcher.lines[assignment[position]].rttlIndex]
+		scaleItem.AssociatedRTTLEntry = rttlEntry
+		rttlEntry.AssociatedScaleItems = append(rttlEntry.AssociatedScaleItems, scaleItem)
+	}
+
+	for position, line := range matcher.lines {
+		rttlEntry := &eventsProcessing.rttlogData[line.rttlIndex]
+		if len(rttlEntry.AssociatedScaleItems) > 0 {
+			eventsProcessing.checkScaleConfirmed(rttlEntry)
+		} else {
+			rttlEntry.ScaleConfirmed = false
+		}
+		eventsProcessing.traceScaleMatching(rttlEntry, line, position, matcher, assignment)
+	}
+}
+
+// traceScaleMatching adds the decisions of the matching about the readings of the line, and about the
+// suspect readings it was not matched with. Lines no reading was considered for are left untraced.
+func (eventsProcessing *EventsProcessor) traceScaleMatching(rttlEntry *RTTLogEventEntry, line scaleMatchingLine, linePosition int, matcher *scaleMatcher, assignment []int) {
+	var weight float64
+	for _, scaleItem := range rttlEntry.AssociatedScaleItems {
+		weight += scaleItem.Delta
+	}
+
+	considered := false
+	for position, scaleIndex := range matcher.readings {
+		scaleItem := &eventsProcessing.scaleData[scaleIndex]
+		if assignment[position] != linePosition && matcher.previous[position] != linePosition && assignment[position] != unmatchedLine {
+			// matched with another line
+			continue
+		}
+		considered = true
+		switch {
+		case assignment[position] == linePosition:
+			eventsProcessing.traceDecision(rttlEntry, scaleDecision(scaleItem, line.minDelta, line.capacity, true, DecisionWeightInRange))
+		case matcher.previous[position] == linePosition:
+			eventsProcessing.traceDecision(rttlEntry, scaleDecision(scaleItem, line.minDelta, line.capacity, false, DecisionWeightReassigned))
+		case scaleItem.Delta < line.minDelta-floatingPointTolerance:
+			eventsProcessing.traceDecision(rttlEntry, scaleDecision(scaleItem, line.minDelta, line.capacity, false, DecisionWeightBelowMinimum))
+		case rttlEntry.ScaleConfirmed:
+			eventsProcessing.traceDecision(rttlEntry, scaleDecision(scaleItem, line.minDelta, line.capacity-weight, false, DecisionLineConfirmed))
+		default:
+			eventsProcessing.traceDecision(rttlEntry, scaleDecision(scaleItem, line.minDelta, line.capacity-weight, false, DecisionWeightAboveMaximum))
+		}
+	}
+	if considered {
+		eventsProcessing.traceScaleOutcome(rttlEntry)
+	}
+}
+
+// removeSuspectScaleItem drops the suspect reading taken back off the scale by the negative reading
+func (eventsProcessing *EventsProcessor) removeSuspectScaleItem(scaleReading *ScaleEventEntry) {
+	for scaleIndex := len(eventsProcessing.scaleData) - 1; scaleIndex >= 0; scaleIndex-- {
+		scaleItem := &eventsProcessing.scaleData[scaleIndex]
+		if scaleItem.Delta <= 0 || scaleItem.Removed || scaleItem.AssociatedRTTLEntry != nil {
+			continue
+		}
+		if math.Abs(math.Abs(scaleReading.Delta)-scaleItem.Delta) < scalePrecision {
+			scaleItem.Removed = true
+			return
+		}
+	}
+}
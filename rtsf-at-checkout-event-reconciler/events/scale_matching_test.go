@@ -0,0 +1,181 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scanEachItem(processor *EventsProcessor, productID string, minWeight float64, maxWeight float64, eventTime int64) {
+	processor.rttlogData = append(processor.rttlogData, RTTLogEventEntry{ProductId: productID, Quantity: 1, QuantityUnit: quantityUnitEA,
+		EventTime: eventTime, ProductDetails: ProductDetails{ExpectedMinWeight: minWeight, ExpectedMaxWeight: maxWeight}})
+}
+
+func dropOnScale(processor *EventsProcessor, delta float64, eventTime int64) {
+	scaleEvent := ScaleDrop(delta, processor)
+	scaleEvent.EventTime = eventTime
+	processor.scaleBasketReconciliation(scaleEvent)
+}
+
+func TestMatchScaleItems(t *testing.T) {
+	tables := []struct {
+		name              string
+		drops             []float64
+		expectedSuspects  int
+		expectedConfirmed []bool
+	}{
+		{
+			name:              "drops in scan order",
+			drops:             []float64{10.5, 11.8},
+			expectedSuspects:  0,
+			expectedConfirmed: []bool{true, true},
+		},
+		{
+			name:              "drops in reverse scan order",
+			drops:             []float64{11.8, 10.5},
+			expectedSuspects:  0,
+			expectedConfirmed: []bool{true, true},
+		},
+		{
+			name:              "extra drop",
+			drops:             []float64{10.5, 11.8, 10.2},
+			expectedSuspects:  1,
+			expectedConfirmed: []bool{true, true},
+		},
+		{
+			name:              "heavy drop",
+			drops:             []float64{10.5, 20},
+			expectedSuspects:  1,
+			expectedConfirmed: []bool{false, true},
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessing := EventsProcessor{}
+			BasketOpen(&eventsProcessing)
+			// two items of similar weight scanned quickly, before either is dropped
+			scanEachItem(&eventsProcessing, "123", 10, 11, 1000)
+			scanEachItem(&eventsProcessing, "456", 10, 12, 1100)
+
+			for index, drop := range table.drops {
+				dropOnScale(&eventsProcessing, drop, int64(1200+100*index))
+			}
+
+			assert.Len(t, eventsProcessing.suspectScaleItems, table.expectedSuspects)
+			assert.Equal(t, table.expectedConfirmed[0], eventsProcessing.rttlogData[1].ScaleConfirmed)
+			assert.Equal(t, table.expectedConfirmed[1], eventsProcessing.rttlogData[2].ScaleConfirmed)
+			for _, line := range eventsProcessing.rttlogData[1:] {
+				for _, scaleItem := range line.AssociatedScaleItems {
+					assert.Equal(t, line.ProductId, scaleItem.AssociatedRTTLEntry.ProductId)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchScaleItemsReassigned(t *testing.T) {
+	eventsProcessing := EventsProcessor{}
+	BasketOpen(&eventsProcessing)
+	scanEachItem(&eventsProcessing, "123", 10, 11, 1000)
+	scanEachItem(&eventsProcessing, "456", 10, 12, 1100)
+
+	// the first drop fits both lines and goes to the closest scan, until the second drop only fits that line
+	dropOnScale(&eventsProcessing, 10.5, 1200)
+	require.Len(t, eventsProcessing.rttlogData[2].AssociatedScaleItems, 1)
+	assert.True(t, eventsProcessing.rttlogData[2].ScaleConfirmed)
+
+	dropOnScale(&eventsProcessing, 11.8, 1300)
+	require.Len(t, eventsProcessing.rttlogData[1].AssociatedScaleItems, 1)
+	assert.Equal(t, 10.5, eventsProcessing.rttlogData[1].AssociatedScaleItems[0].Delta)
+	require.Len(t, eventsProcessing.rttlogData[2].AssociatedScaleItems, 1)
+	assert.Equal(t, 11.8, eventsProcessing.rttlogData[2].AssociatedScaleItems[0].Delta)
+	assert.Empty(t, eventsProcessing.suspectScaleItems)
+
+	assert.Equal(t, []string{DecisionWeightInRange, DecisionScaleConfirmed}, decisionReasons(eventsProcessing.rttlogData[1].Decisions))
+	assert.Equal(t, []string{DecisionWeightInRange, DecisionScaleConfirmed, DecisionWeightReassigned, DecisionWeightInRange, DecisionScaleConfirmed},
+		decisionReasons(eventsProcessing.rttlogData[2].Decisions))
+}
+
+func TestMatchScaleItemsDroppedBeforeScan(t *testing.T) {
+	eventsProcessing := EventsProcessor{}
+	BasketOpen(&eventsProcessing)
+	scanEachItem(&eventsProcessing, "123", 10, 11, 1000)
+	dropOnScale(&eventsProcessing, 10.5, 1100)
+	dropOnScale(&eventsProcessing, 11.8, 1200)
+	assert.Len(t, eventsProcessing.suspectScaleItems, 1)
+
+	scanEachItem(&eventsProcessing, "456", 10, 12, 1300)
+	eventsProcessing.matchScaleItems()
+
+	assert.Empty(t, eventsProcessing.suspectScaleItems)
+	assert.True(t, eventsProcessing.rttlogData[1].ScaleConfirmed)
+	assert.True(t, eventsProcessing.rttlogData[2].ScaleConfirmed)
+}
+
+func TestRemoveSuspectScaleItem(t *testing.T) {
+	eventsProcessing := EventsProcessor{}
+	BasketOpen(&eventsProcessing)
+	scanEachItem(&eventsProcessing, "123", 10, 11, 1000)
+	dropOnScale(&eventsProcessing, 10.5, 1100)
+	dropOnScale(&eventsProcessing, 30, 1200)
+	assert.Len(t, eventsProcessing.suspectScaleItems, 1)
+
+	// taking back an item matched with a line leaves the suspect
+	dropOnScale(&eventsProcessing, -10.5, 1300)
+	assert.Len(t, eventsProcessing.suspectScaleItems, 1)
+	assert.True(t, eventsProcessing.rttlogData[1].ScaleConfirmed)
+
+	dropOnScale(&eventsProcessing, -30, 1400)
+	assert.Empty(t, eventsProcessing.suspectScaleItems)
+	assert.True(t, eventsProcessing.scaleData[1].Removed)
+	assert.True(t, eventsProcessing.rttlogData[1].ScaleConfirmed)
+}
+
+// scaleMatchingBasket scans the items of a basket and drops each on the scale after its scan, the weight
+// windows of the products overlapping as in a grocery basket, along with a few items dropped unscanned
+func scaleMatchingBasket(items int) *EventsProcessor {
+	eventsProcessing := &EventsProcessor{}
+	BasketOpen(eventsProcessing)
+	for item := 0; item < items; item++ {
+		minWeight := 1 + float64(item%5)*0.5
+		eventTime := int64(1000 * (item + 1))
+		scanEachItem(eventsProcessing, fmt.Sprintf("product-%d", item), minWeight, minWeight+1, eventTime)
+		dropOnScale(eventsProcessing, minWeight+0.5, eventTime+500)
+		if item%10 == 9 {
+			dropOnScale(eventsProcessing, minWeight+0.25, eventTime+700)
+		}
+	}
+	return eventsProcessing
+}
+
+func TestMatchScaleItemsLargeBasket(t *testing.T) {
+	for _, items := range []int{30, 50} {
+		t.Run(fmt.Sprintf("%d items", items), func(t *testing.T) {
+			eventsProcessing := scaleMatchingBasket(items)
+
+			// only the items dropped unscanned are left as suspects within the bound of the search
+			assert.Len(t, eventsProcessing.suspectScaleItems, items/10)
+			for _, line := range eventsProcessing.rttlogData[1:] {
+				assert.True(t, line.ScaleConfirmed, line.ProductId)
+			}
+		})
+	}
+}
+
+func BenchmarkMatchScaleItems(b *testing.B) {
+	for _, items := range []int{30, 50} {
+		b.Run(fmt.Sprintf("%d items", items), func(b *testing.B) {
+			eventsProcessing := scaleMatchingBasket(items)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				eventsProcessing.matchScaleItems()
+			}
+		})
+	}
+}
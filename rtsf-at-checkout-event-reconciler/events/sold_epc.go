@@ -0,0 +1,127 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"time"
+
+	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
+
+	"event-reconciler/clock"
+	"event-reconciler/config"
+	"event-reconciler/soldepc"
+)
+
+// defaultSoldEPCTTL keeps the sold tags for a day when SoldEPCTTL is not set
+const defaultSoldEPCTTL = 24 * time.Hour
+
+func soldEPCTTL(reconcilerConfig *config.ReconcilerConfig) time.Duration {
+	if reconcilerConfig == nil {
+		return defaultSoldEPCTTL
+	}
+	ttl, err := reconcilerConfig.GetSoldEPCTTL()
+	if err != nil {
+		return defaultSoldEPCTTL
+	}
+	return ttl
+}
+
+// registerSoldEPCs registers the RFID tags matched with the POS item lines of the basket being paid
+func (eventsProcessing *EventsProcessor) registerSoldEPCs(paymentSuccess RTTLogEventEntry) {
+	if eventsProcessing.soldEPCs == nil {
+		return
+	}
+
+	now := eventsProcessing.clock.Now()
+	for _, line := range eventsProcessing.rttlogData {
+		if line.EventType != posItemEvent {
+			continue
+		}
+		for _, rfidItem := range line.AssociatedRFIDItems {
+			eventsProcessing.soldEPCs.Add(soldepc.Sale{
+				EPC:           rfidItem.EPC,
+				Serial:        rfidItem.Serial,
+				ProductId:     line.ProductId,
+				ProductName:   line.ProductName,
+				LaneId:        paymentSuccess.LaneId,
+				TransactionId: transactionID(paymentSuccess),
+				SoldTime:      paymentSuccess.EventTime,
+			}, now)
+		}
+	}
+}
+
+// registerJournaledSoldEPCs registers the RFID tags sold in the journaled transaction, so that the sales
+// made before a restart are still in the registry for the rest of their time to live
+func (eventsProcessing *EventsProcessor) registerJournaledSoldEPCs(record TransactionRecord) {
+	if eventsProcessing.soldEPCs == nil || len(record.SoldSerials) == 0 {
+		return
+	}
+
+	soldTime := record.EndTime
+	for _, line := range record.POSLines {
+		if line.EventType == paymentSuccessEvent {
+			soldTime = line.EventTime
+		}
+	}
+	// the time to live of the sale runs from its payment-success
+	soldAt := clock.FromEventTime(soldTime)
+	if !soldAt.Add(soldEPCTTL(eventsProcessing.processConfig)).After(eventsProcessing.clock.Now()) {
+		return
+	}
+
+	for _, association := range record.Associations {
+		if association.Detector != DetectorRFID {
+			continue
+		}
+		sale := soldepc.Sale{
+			EPC:           association.Reference,
+			Serial:        rfidSerial(association.Reference),
+			ProductId:     association.ProductId,
+			LaneId:        record.LaneId,
+			TransactionId: record.TransactionId,
+			SoldTime:      soldTime,
+		}
+		if association.LineIndex >= 0 && association.LineIndex < len(record.POSLines) {
+			sale.ProductName = record.POSLines[association.LineIndex].ProductName
+		}
+		eventsProcessing.soldEPCs.Add(sale, soldAt)
+	}
+}
+
+// LookupSoldEPC returns the sale of the tag, by EPC or serial, while it is in the sold registry
+func (eventsProcessing *EventsProcessor) LookupSoldEPC(epc string) (soldepc.Sale, bool) {
+	if eventsProcessing.soldEPCs == nil {
+		return soldepc.Sale{}, false
+	}
+	return eventsProcessing.soldEPCs.Lookup(rfidSerial(epc), eventsProcessing.clock.Now())
+}
+
+// processDepartureRFIDReading reports a tag entering the Departure ROI while it is not in the sold
+// registry as an item leaving the store unpaid, as soon as it is read
+func (eventsProcessing *EventsProcessor) processDepartureRFIDReading(rfidReading RFIDEventEntry, edgexcontext interfaces.AppFunctionContext) {
+	lc := edgexcontext.LoggingClient()
+	if rfidReading.ROIAction != ROIActionEnter {
+		return
+	}
+	if _, sold := eventsProcessing.LookupSoldEPC(rfidReading.EPC); sold {
+		return
+	}
+
+	// the finding is keyed on the tag, so that the tag read again at the exit is the same finding
+	finding := newFinding(rfidReading.LaneId, "", DetectorRFID, ReasonUnpaidItemLeaving, "",
+		FindingEvidence{Event: rfidRoiEvent, Reference: rfidReading.EPC, EventTime: rfidReading.EventTime})
+	finding.ProductName, finding.GTIN, finding.Serial = rfidReading.ProductName, rfidReading.UPC, rfidReading.Serial
+	if product, ok := eventsProcessing.catalogProductByGTIN(rfidReading.UPC); ok {
+		finding.EstimatedPrice = product.Price
+	}
+	finding.Severity = eventsProcessing.severity(finding.EstimatedPrice)
+	finding.DetectedTime = clock.ToEventTime(eventsProcessing.clock.Now())
+
+	findings := eventsProcessing.recordFindings([]Finding{finding}, lc)
+	lc.Warnf("Unpaid item %s (%s) leaving the store", rfidReading.ProductName, rfidReading.Serial)
+	if err := eventsProcessing.publishFindings(findings, edgexcontext); err != nil {
+		lc.Errorf("Findings Error: %v", err)
+	}
+}
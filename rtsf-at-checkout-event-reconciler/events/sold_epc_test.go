@@ -0,0 +1,130 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"event-reconciler/clock"
+)
+
+func processDepartureEvent(t *testing.T, eventsProcessor *EventsProcessor, epc string, roiAction string, eventTime int64) {
+	_, err := eventsProcessor.ProcessCheckoutEvents(context, testCheckoutEvent("rfid-roi-rest", rfidRoiEvent, map[string]interface{}{
+		"lane_id":    "exit-1",
+		"epc":        epc,
+		"roi_name":   DepartureROI,
+		"roi_action": roiAction,
+		"event_time": eventTime,
+	}))
+	assert.Nil(t, err)
+}
+
+func TestLookupSoldEPC(t *testing.T) {
+	eventsProcessor := newRFIDSerialTestProcessor(t)
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	eventsProcessor.SetClock(manualClock)
+	sellSteak(t, eventsProcessor, "t-1", testSteakEPC, 1000)
+
+	// the tag is looked up by EPC, whatever its filter value, or by serial
+	for _, epc := range []string{testSteakEPC, testSteakEPCFilter1, testSteakSerial} {
+		sale, sold := eventsProcessor.LookupSoldEPC(epc)
+		require.True(t, sold, epc)
+		assert.Equal(t, "t-1", sale.TransactionId)
+		assert.Equal(t, "1", sale.LaneId)
+		assert.Equal(t, "00000000324588", sale.ProductId)
+		assert.Equal(t, int64(1400), sale.SoldTime)
+	}
+
+	_, sold := eventsProcessor.LookupSoldEPC("30140000001FB2800000303A")
+	assert.False(t, sold)
+
+	manualClock.Advance(defaultSoldEPCTTL)
+	_, sold = eventsProcessor.LookupSoldEPC(testSteakEPC)
+	assert.False(t, sold)
+}
+
+func TestDepartureUnpaidItem(t *testing.T) {
+	tables := []struct {
+		name             string
+		epc              string
+		roiAction        string
+		elapsed          time.Duration
+		expectedFindings int
+	}{
+		{name: "sold tag", epc: testSteakEPCFilter1, roiAction: ROIActionEnter},
+		{name: "unpaid tag", epc: "30140000001FB2800000303A", roiAction: ROIActionEnter, expectedFindings: 1},
+		{name: "unpaid tag exiting", epc: "30140000001FB2800000303A", roiAction: ROIActionExit},
+		{name: "sale expired", epc: testSteakEPC, roiAction: ROIActionEnter, elapsed: 25 * time.Hour, expectedFindings: 1},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newRFIDSerialTestProcessor(t)
+			manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+			eventsProcessor.SetClock(manualClock)
+			sellSteak(t, eventsProcessor, "t-1", testSteakEPC, 1000)
+			require.Empty(t, eventsProcessor.GetFindings("", true))
+
+			manualClock.Advance(table.elapsed)
+			processDepartureEvent(t, eventsProcessor, table.epc, table.roiAction, 2000)
+
+			findings := eventsProcessor.GetFindings("exit-1", false)
+			require.Len(t, findings, table.expectedFindings)
+			// the exit reads are not basket items
+			assert.Empty(t, eventsProcessor.currentRFIDData)
+			if table.expectedFindings == 0 {
+				return
+			}
+			assert.Equal(t, ReasonUnpaidItemLeaving, findings[0].ReasonCode)
+			assert.Equal(t, DetectorRFID, findings[0].Detector)
+			assert.Equal(t, rfidSerial(table.epc), findings[0].Serial)
+			assert.Equal(t, "Steak", findings[0].ProductName)
+			assert.Equal(t, FindingOpen, findings[0].Status)
+			assert.Empty(t, findings[0].TransactionId)
+		})
+	}
+}
+
+func TestSoldEPCsReloadedFromJournal(t *testing.T) {
+	tables := []struct {
+		name             string
+		elapsed          time.Duration
+		expectedSold     bool
+		expectedFindings int
+	}{
+		{name: "sale within ttl", elapsed: time.Hour, expectedSold: true},
+		{name: "sale expired", elapsed: 25 * time.Hour, expectedFindings: 1},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			start := time.UnixMilli(1700000000000)
+			eventsProcessor := newRFIDSerialTestProcessor(t)
+			eventsProcessor.SetClock(clock.NewManualClock(start))
+			sellSteak(t, eventsProcessor, "t-1", testSteakEPC, clock.ToEventTime(start))
+
+			// the reconciler restarts with the same journal
+			restarted := newRFIDSerialTestProcessor(t)
+			restarted.SetClock(clock.NewManualClock(start.Add(table.elapsed)))
+			require.NoError(t, restarted.SetJournal(eventsProcessor.journal))
+
+			sale, sold := restarted.LookupSoldEPC(testSteakEPCFilter1)
+			require.Equal(t, table.expectedSold, sold)
+			if sold {
+				assert.Equal(t, "t-1", sale.TransactionId)
+				assert.Equal(t, "Steak", sale.ProductName)
+				assert.Equal(t, clock.ToEventTime(start)+400, sale.SoldTime)
+			}
+
+			// the tag read twice at the exit is a single finding
+			processDepartureEvent(t, restarted, testSteakEPC, ROIActionEnter, clock.ToEventTime(start.Add(table.elapsed)))
+			processDepartureEvent(t, restarted, testSteakEPC, ROIActionEnter, clock.ToEventTime(start.Add(table.elapsed))+1000)
+			assert.Len(t, restarted.GetFindings("exit-1", false), table.expectedFindings)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"event-reconciler/config"
+)
+
+// defaultSuspendedTransactionTTL keeps the suspended transactions for an hour when SuspendedTransactionTTL is not set
+const defaultSuspendedTransactionTTL = time.Hour
+
+func suspendedTransactionTTL(reconcilerConfig *config.ReconcilerConfig) time.Duration {
+	if reconcilerConfig == nil {
+		return defaultSuspendedTransactionTTL
+	}
+	ttl, err := reconcilerConfig.GetSuspendedTransactionTTL()
+	if err != nil {
+		return defaultSuspendedTransactionTTL
+	}
+	return ttl
+}
+
+// SuspendedTransaction is the basket state parked while its transaction is suspended
+type SuspendedTransaction struct {
+	LaneId            string
+	SuspendedTime     time.Time
+	RTTLogData        []RTTLogEventEntry
+	ScaleData         []ScaleEventEntry
+	SuspectScaleItems map[int64]*ScaleEventEntry
+	CVData            []CVEventEntry
+	RFIDData          []RFIDEventEntry
+}
+
+// transactionID identifies the transaction of the POS event, the basket id is used when the POS
+// does not send a transaction id
+func transactionID(rttlogReading RTTLogEventEntry) string {
+	if len(rttlogReading.TransactionId) > 0 {
+		return rttlogReading.TransactionId
+	}
+	return rttlogReading.BasketId
+}
+
+// startNextBasket makes the CV and RFID items seen after the payment the items of the current basket,
+// the items of the basket being left are dropped instead of being kept as suspects
+func (eventsProcessing *EventsProcessor) startNextBasket() {
+	eventsProcessing.currentCVData = eventsProcessing.nextCVData
+	eventsProcessing.nextCVData = []CVEventEntry{}
+	eventsProcessing.currentRFIDData = eventsProcessing.nextRFIDData
+	eventsProcessing.nextRFIDData = []RFIDEventEntry{}
+	eventsProcessing.afterPaymentSuccess = false
+}
+
+// voidTransaction drops the RTT log along with the CV and RFID items of the basket
+func (eventsProcessing *EventsProcessor) voidTransaction() {
+	eventsProcessing.resetRTTLBasket()
+	eventsProcessing.startNextBasket()
+	eventsProcessing.hasPOSItems = false
+}
+
+// suspendTransaction parks the basket under its transaction id so that the lane is free for the next basket
+func (eventsProcessing *EventsProcessor) suspendTransaction(rttlogReading RTTLogEventEntry) error {
+	id := transactionID(rttlogReading)
+	if len(id) == 0 {
+		eventsProcessing.voidTransaction()
+		return fmt.Errorf("%s without transaction_id or basket_id, the basket is voided", suspendTransactionEvent)
+	}
+
+	eventsProcessing.dropExpiredSuspendedTransactions()
+	eventsProcessing.suspendedTransactions[id] = &SuspendedTransaction{
+		LaneId:            rttlogReading.LaneId,
+		SuspendedTime:     eventsProcessing.clock.Now(),
+		RTTLogData:        eventsProcessing.rttlogData,
+		ScaleData:         eventsProcessing.scaleData,
+		SuspectScaleItems: eventsProcessing.suspectScaleItems,
+		CVData:            eventsProcessing.currentCVData,
+		RFIDData:          eventsProcessing.currentRFIDData,
+	}
+
+	eventsProcessing.resetRTTLBasket()
+	eventsProcessing.startNextBasket()
+	eventsProcessing.hasPOSItems = false
+	return nil
+}
+
+// resumeTransaction restores the basket parked under the transaction id. On another lane only the RTT log
+// is restored, the scale, CV and RFID items being those seen by the sensors of the lane it was suspended on
+func (eventsProcessing *EventsProcessor) resumeTransaction(rttlogReading RTTLogEventEntry) error {
+	id := transactionID(rttlogReading)
+	eventsProcessing.dropExpiredSuspendedTransactions()
+	suspended, ok := eventsProcessing.suspendedTransactions[id]
+	if !ok {
+		return fmt.Errorf("no suspended transaction %q to resume", id)
+	}
+	delete(eventsProcessing.suspendedTransactions, id)
+
+	eventsProcessing.rttlogData = suspended.RTTLogData
+	if suspended.LaneId == rttlogReading.LaneId {
+		eventsProcessing.scaleData = suspended.ScaleData
+		eventsProcessing.suspectScaleItems = suspended.SuspectScaleItems
+		eventsProcessing.restoreSensorItems(suspended)
+	} else {
+		for index := range eventsProcessing.rttlogData {
+			eventsProcessing.rttlogData[index].LaneId = rttlogReading.LaneId
+		}
+	}
+
+	eventsProcessing.hasPOSItems = eventsProcessing.checkRTTLForPOSItems()
+	return nil
+}
+
+// restoreSensorItems puts the CV and RFID items of the resumed basket ahead of the items seen on the lane
+// since it was suspended. The items are copied to new slices, so the restored lines are pointed at the
+// copies and the copies back at their lines.
+func (eventsProcessing *EventsProcessor) restoreSensorItems(suspended *SuspendedTransaction) {
+	cvIndexes := make(map[*CVEventEntry]int, len(suspended.CVData))
+	for cvIndex := range suspended.CVData {
+		cvIndexes[&suspended.CVData[cvIndex]] = cvIndex
+	}
+	rfidIndexes := make(map[*RFIDEventEntry]int, len(suspended.RFIDData))
+	for rfidIndex := range suspended.RFIDData {
+		rfidIndexes[&suspended.RFIDData[rfidIndex]] = rfidIndex
+	}
+
+	cvData := make([]CVEventEntry, 0, len(suspended.CVData)+len(eventsProcessing.currentCVData))
+	cvData = append(append(cvData, suspended.CVData...), eventsProcessing.currentCVData...)
+	rfidData := make([]RFIDEventEntry, 0, len(suspended.RFIDData)+len(eventsProcessing.currentRFIDData))
+	rfidData = append(append(rfidData, suspended.RFIDData...), eventsProcessing.currentRFIDData...)
+
+	for rttlIndex := range eventsProcessing.rttlogData {
+		line := &eventsProcessing.rttlogData[rttlIndex]
+		for itemIndex, cvItem := range line.AssociatedCVItems {
+			if cvIndex, ok := cvIndexes[cvItem]; ok {
+				line.AssociatedCVItems[itemIndex] = &cvData[cvIndex]
+				cvData[cvIndex].AssociatedRTTLEntry = line
+			}
+		}
+		for itemIndex, rfidItem := range line.AssociatedRFIDItems {
+			if rfidIndex, ok := rfidIndexes[rfidItem]; ok {
+				line.AssociatedRFIDItems[itemIndex] = &rfidData[rfidIndex]
+				rfidData[rfidIndex].AssociatedRTTLEntry = line
+			}
+		}
+	}
+
+	eventsProcessing.currentCVData = cvData
+	eventsProcessing.currentRFIDData = rfidData
+}
+
+// dropExpiredSuspendedTransactions drops the transactions suspended for longer than SuspendedTransactionTTL,
+// they can no longer be resumed
+func (eventsProcessing *EventsProcessor) dropExpiredSuspendedTransactions() {
+	now := eventsProcessing.clock.Now()
+	ttl := suspendedTransactionTTL(eventsProcessing.processConfig)
+	for id, suspended := range eventsProcessing.suspendedTransactions {
+		if !suspended.SuspendedTime.Add(ttl).After(now) {
+			delete(eventsProcessing.suspendedTransactions, id)
+		}
+	}
+}
+
+// getSuspendedTransactionIDs returns the ids of the suspended transactions not expired yet, sorted
+func (eventsProcessing *EventsProcessor) getSuspendedTransactionIDs() []string {
+	eventsProcessing.dropExpiredSuspendedTransactions()
+	ids := []string{}
+	for id := range eventsProcessing.suspendedTransactions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,166 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"event-reconciler/clock"
+	"event-reconciler/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransactionTestProcessor() *EventsProcessor {
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{
+		DevicePos:   "pos",
+		DeviceScale: "scale",
+		DeviceCV:    "cv-roi",
+		DeviceRFID:  "rfid-roi",
+	})
+	eventsProcessor.ResetCheckoutState()
+	return eventsProcessor
+}
+
+func processPosEvent(t *testing.T, eventsProcessor *EventsProcessor, resourceName string, data map[string]interface{}) {
+	_, err := eventsProcessor.ProcessCheckoutEvents(context, testCheckoutEvent("pos-rest", resourceName, data))
+	assert.Nil(t, err)
+}
+
+func scannedBananas(laneID string, eventTime int64) map[string]interface{} {
+	return map[string]interface{}{
+		"lane_id":       laneID,
+		"basket_id":     "abc",
+		"product_id":    "4011",
+		"product_name":  "Bananas",
+		"quantity":      1.5,
+		"quantity_unit": "lbs",
+		"event_time":    eventTime,
+	}
+}
+
+func TestVoidTransaction(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+	eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, CVEventEntry{ObjectName: "bottle", ROIs: map[string]ROILocation{}})
+	eventsProcessor.nextCVData = append(eventsProcessor.nextCVData, CVEventEntry{ObjectName: "apple", ROIs: map[string]ROILocation{}})
+	require.Len(t, eventsProcessor.rttlogData, 2)
+
+	processPosEvent(t, eventsProcessor, voidTransactionEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 3000})
+
+	assert.Empty(t, eventsProcessor.rttlogData)
+	// the unscanned bottle of the voided basket is not a suspect of the next basket
+	require.Len(t, eventsProcessor.currentCVData, 1)
+	assert.Equal(t, "apple", eventsProcessor.currentCVData[0].ObjectName)
+	assert.Empty(t, eventsProcessor.nextCVData)
+	assert.Equal(t, "idle", eventsProcessor.GetCheckoutState().State)
+}
+
+func TestSuspendAndResumeTransaction(t *testing.T) {
+	tables := []struct {
+		name         string
+		resumeLaneID string
+		scaleRestore bool
+	}{
+		{name: "same lane", resumeLaneID: "1", scaleRestore: true},
+		{name: "other lane", resumeLaneID: "2", scaleRestore: false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := newTransactionTestProcessor()
+
+			processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+			processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+			eventsProcessor.scaleData = append(eventsProcessor.scaleData, ScaleEventEntry{LaneId: "1", Total: 1.5})
+			processPosEvent(t, eventsProcessor, suspendTransactionEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-1", "event_time": 3000})
+
+			assert.Empty(t, eventsProcessor.rttlogData)
+			assert.Empty(t, eventsProcessor.scaleData)
+			checkoutState := eventsProcessor.GetCheckoutState()
+			assert.Equal(t, "idle", checkoutState.State)
+			assert.Equal(t, []string{"t-1"}, checkoutState.SuspendedTransactions)
+
+			// the lane serves another basket in the meantime
+			processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "def", "event_time": 4000})
+			processPosEvent(t, eventsProcessor, basketCloseEvent, map[string]interface{}{"lane_id": "1", "basket_id": "def", "event_time": 5000})
+
+			processPosEvent(t, eventsProcessor, resumeTransactionEvent, map[string]interface{}{"lane_id": table.resumeLaneID, "transaction_id": "t-1", "event_time": 6000})
+
+			require.Len(t, eventsProcessor.rttlogData, 2)
+			assert.Equal(t, "Bananas", eventsProcessor.rttlogData[1].ProductName)
+			assert.Equal(t, table.resumeLaneID, eventsProcessor.rttlogData[1].LaneId)
+			assert.Equal(t, table.scaleRestore, len(eventsProcessor.scaleData) == 1)
+			checkoutState = eventsProcessor.GetCheckoutState()
+			assert.Equal(t, "open", checkoutState.State)
+			assert.Empty(t, checkoutState.SuspendedTransactions)
+
+			// the restored items can be paid
+			processPosEvent(t, eventsProcessor, paymentStartEvent, map[string]interface{}{"lane_id": table.resumeLaneID, "basket_id": "abc", "event_time": 7000})
+			assert.Equal(t, "payment", eventsProcessor.GetCheckoutState().State)
+		})
+	}
+}
+
+func TestResumeTransactionKeepsSensorAssociations(t *testing.T) {
+	eventsProcessor := newCVTrackingTestProcessor(t)
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processCVTrackEvent(t, eventsProcessor, "1", ScannerROI, ROIActionEnter, 2000)
+	processCVTrackEvent(t, eventsProcessor, "2", ScannerROI, ROIActionEnter, 3000)
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedSoda(2, 4000))
+	require.True(t, eventsProcessor.rttlogData[1].CVConfirmed)
+	processPosEvent(t, eventsProcessor, suspendTransactionEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-1", "event_time": 5000})
+
+	// another can is seen on the lane while the transaction is suspended
+	processCVTrackEvent(t, eventsProcessor, "3", ScannerROI, ROIActionEnter, 6000)
+	processPosEvent(t, eventsProcessor, resumeTransactionEvent, map[string]interface{}{"lane_id": "1", "transaction_id": "t-1", "event_time": 7000})
+
+	require.Len(t, eventsProcessor.currentCVData, 3)
+	line := &eventsProcessor.rttlogData[1]
+	require.Len(t, line.AssociatedCVItems, 2)
+	for itemIndex, cvItem := range line.AssociatedCVItems {
+		assert.Same(t, &eventsProcessor.currentCVData[itemIndex], cvItem)
+		assert.Same(t, line, cvItem.AssociatedRTTLEntry)
+	}
+	assert.Len(t, eventsProcessor.getSuspectCVItems(), 1)
+
+	// the can no longer paid for is released from the restored line and becomes a suspect
+	processPosEvent(t, eventsProcessor, quantityChangeEvent, map[string]interface{}{"lane_id": "1", "product_id": "00049000050158", "quantity": 1.0, "event_time": 8000})
+	assert.Len(t, eventsProcessor.rttlogData[1].AssociatedCVItems, 1)
+	assert.Len(t, eventsProcessor.getSuspectCVItems(), 2)
+}
+
+func TestSuspendedTransactionExpires(t *testing.T) {
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	eventsProcessor := newTransactionTestProcessor()
+	eventsProcessor.processConfig.SuspendedTransactionTTL = "30m"
+	eventsProcessor.SetClock(manualClock)
+
+	processPosEvent(t, eventsProcessor, basketOpenEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "event_time": 1000})
+	processPosEvent(t, eventsProcessor, posItemEvent, scannedBananas("1", 2000))
+	processPosEvent(t, eventsProcessor, suspendTransactionEvent, map[string]interface{}{"lane_id": "1", "basket_id": "abc", "transaction_id": "t-1", "event_time": 3000})
+
+	manualClock.Advance(29 * time.Minute)
+	assert.Equal(t, []string{"t-1"}, eventsProcessor.GetCheckoutState().SuspendedTransactions)
+
+	// the transaction is no longer listed nor resumed once suspended for longer than the TTL
+	manualClock.Advance(time.Minute)
+	assert.Empty(t, eventsProcessor.GetCheckoutState().SuspendedTransactions)
+	processPosEvent(t, eventsProcessor, resumeTransactionEvent, map[string]interface{}{"lane_id": "1", "transaction_id": "t-1", "event_time": 4000})
+	assert.Empty(t, eventsProcessor.rttlogData)
+	assert.Equal(t, "idle", eventsProcessor.GetCheckoutState().State)
+}
+
+func TestResumeUnknownTransaction(t *testing.T) {
+	eventsProcessor := newTransactionTestProcessor()
+
+	processPosEvent(t, eventsProcessor, resumeTransactionEvent, map[string]interface{}{"lane_id": "1", "transaction_id": "t-404", "event_time": 1000})
+
+	assert.Empty(t, eventsProcessor.rttlogData)
+	assert.Equal(t, "idle", eventsProcessor.GetCheckoutState().State)
+}
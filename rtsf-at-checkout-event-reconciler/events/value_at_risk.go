@@ -0,0 +1,160 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+)
+
+const (
+	SeverityLow    = "low"
+	SeverityMedium = "medium"
+	SeverityHigh   = "high"
+)
+
+// refreshCatalog fetches the product inventory the suspect items are priced with, the previous
+// inventory is kept when the product lookup fails
+func (eventsProcessing *EventsProcessor) refreshCatalog(lc logger.LoggingClient) {
+	catalog, err := catalogLookup(eventsProcessing.processConfig.ProductLookupEndpoint)
+	if err != nil {
+		lc.Warnf("Product catalog lookup failed, suspect items are priced with the previous catalog: %v", err)
+		return
+	}
+	eventsProcessing.catalog = catalog
+}
+
+func (eventsProcessing *EventsProcessor) catalogProductByGTIN(gtin string) (CatalogProduct, bool) {
+	for _, product := range eventsProcessing.catalog {
+		if product.Barcode == gtin {
+			return product, true
+		}
+	}
+	return CatalogProduct{}, false
+}
+
+func (eventsProcessing *EventsProcessor) catalogProductByName(name string) (CatalogProduct, bool) {
+	for _, product := range eventsProcessing.catalog {
+		if strings.EqualFold(product.Name, name) {
+			return product, true
+		}
+	}
+	return CatalogProduct{}, false
+}
+
+// catalogProductForCVItem looks the CV item up by its candidate GTINs, then by name
+func (eventsProcessing *EventsProcessor) catalogProductForCVItem(cvItem CVEventEntry) (CatalogProduct, bool) {
+	for _, gtin := range cvItem.CandidateGTINs {
+		if product, ok := eventsProcessing.catalogProductByGTIN(gtin); ok {
+			return product, true
+		}
+	}
+	return eventsProcessing.catalogProductByName(cvItem.ObjectName)
+}
+
+// catalogProductByWeight returns the most expensive product whose weight range holds the weight
+func (eventsProcessing *EventsProcessor) catalogProductByWeight(weight float64) (CatalogProduct, bool) {
+	var found CatalogProduct
+	ok := false
+	for _, product := range eventsProcessing.catalog {
+		if weight < product.MinWeight || weight > product.MaxWeight {
+			continue
+		}
+		if !ok || product.Price > found.Price {
+			found = product
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// severity ranks a value at risk against SeverityMediumValue and SeverityHighValue
+func (eventsProcessing *EventsProcessor) severity(value float64) string {
+	if eventsProcessing.processConfig == nil {
+		return SeverityLow
+	}
+	if value >= eventsProcessing.processConfig.SeverityHighValue && eventsProcessing.processConfig.SeverityHighValue > 0 {
+		return SeverityHigh
+	}
+	if value >= eventsProcessing.processConfig.SeverityMediumValue && eventsProcessing.processConfig.SeverityMediumValue > 0 {
+		return SeverityMedium
+	}
+	return SeverityLow
+}
+
+// priceSuspectItems fills in the product, estimated price and severity of the suspect items, and the value at
+// risk of the basket. An item seen by several sensors is only counted once, so the number of items of a product
+// at risk is the largest number of suspect items of that product reported by a single sensor.
+func (eventsProcessing *EventsProcessor) priceSuspectItems(suspectList *SuspectLists) {
+	cvCounts := make(map[string]int)
+	rfidCounts := make(map[string]int)
+	scaleCounts := make(map[string]int)
+	prices := make(map[string]float64)
+
+	for index := range suspectList.CVSuspect {
+		item := &suspectList.CVSuspect[index]
+		if product, ok := eventsProcessing.catalogProductForCVItem(*item); ok {
+			item.GTIN = product.Barcode
+			item.EstimatedPrice = product.Price
+			cvCounts[product.Barcode]++
+			prices[product.Barcode] = product.Price
+		}
+		item.Severity = eventsProcessing.severity(item.EstimatedPrice)
+	}
+
+	for index := range suspectList.RFIDSuspect {
+		item := &suspectList.RFIDSuspect[index]
+		item.GTIN = item.UPC
+		if product, ok := eventsProcessing.catalogProductByGTIN(item.UPC); ok {
+			item.EstimatedPrice = product.Price
+			rfidCounts[product.Barcode]++
+			prices[product.Barcode] = product.Price
+		}
+		item.Severity = eventsProcessing.severity(item.EstimatedPrice)
+	}
+
+	// the previously sold items are paid for, they are priced but are not at risk
+	for _, items := range [][]RFIDEventEntry{suspectList.RFIDSoldTwice, suspectList.RFIDPreviouslySold} {
+		for index := range items {
+			item := &items[index]
+			item.GTIN = item.UPC
+			if product, ok := eventsProcessing.catalogProductByGTIN(item.UPC); ok {
+				item.EstimatedPrice = product.Price
+			}
+			item.Severity = eventsProcessing.severity(item.EstimatedPrice)
+		}
+	}
+
+	for _, item := range suspectList.ScaleSuspect {
+		if product, ok := eventsProcessing.catalogProductByWeight(item.Delta); ok {
+			item.ProductName = product.Name
+			item.GTIN = product.Barcode
+			item.EstimatedPrice = product.Price
+			scaleCounts[product.Barcode]++
+			prices[product.Barcode] = product.Price
+		}
+		item.Severity = eventsProcessing.severity(item.EstimatedPrice)
+	}
+
+	suspectList.ValueAtRisk = 0
+	for gtin, price := range prices {
+		count := cvCounts[gtin]
+		if rfidCounts[gtin] > count {
+			count = rfidCounts[gtin]
+		}
+		if scaleCounts[gtin] > count {
+			count = scaleCounts[gtin]
+		}
+		suspectList.ValueAtRisk += float64(count) * price
+	}
+
+	for index := range suspectList.PriceOverride {
+		override := &suspectList.PriceOverride[index]
+		override.Severity = eventsProcessing.severity(override.ValueAtRisk)
+		suspectList.ValueAtRisk += override.ValueAtRisk
+	}
+
+	suspectList.Severity = eventsProcessing.severity(suspectList.ValueAtRisk)
+}
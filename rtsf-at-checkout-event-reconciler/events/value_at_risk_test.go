@@ -0,0 +1,163 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package events
+
+import (
+	"encoding/json"
+	"event-reconciler/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCatalog = []CatalogProduct{
+	{Barcode: "00000000324588", Name: "Red Apples", MinWeight: 1.0, MaxWeight: 1.1, Price: 0.99},
+	{Barcode: "00000000884389", Name: "Red Wine", MinWeight: 3.0, MaxWeight: 3.1, Price: 10.99},
+	{Barcode: "00000000735797", Name: "Steak", MinWeight: 2.1, MaxWeight: 2.2, Price: 8.99},
+	{Barcode: "00000000019293", Name: "HP MP9", MinWeight: 2.15, MaxWeight: 2.25, Price: 799.00},
+}
+
+func TestSeverity(t *testing.T) {
+	tables := []struct {
+		name             string
+		value            float64
+		expectedSeverity string
+	}{
+		{name: "no value", value: 0, expectedSeverity: SeverityLow},
+		{name: "below medium", value: 9.99, expectedSeverity: SeverityLow},
+		{name: "medium", value: 10, expectedSeverity: SeverityMedium},
+		{name: "high", value: 50, expectedSeverity: SeverityHigh},
+	}
+
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{SeverityMediumValue: 10, SeverityHighValue: 50})
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.Equal(t, table.expectedSeverity, eventsProcessor.severity(table.value))
+		})
+	}
+}
+
+func TestPriceSuspectItems(t *testing.T) {
+	tables := []struct {
+		name                string
+		cvItems             []string
+		rfidItems           []string
+		scaleDeltas         []float64
+		expectedValueAtRisk float64
+		expectedSeverity    string
+	}{
+		{
+			name:                "no suspects",
+			expectedValueAtRisk: 0,
+			expectedSeverity:    SeverityLow,
+		},
+		{
+			name:                "cv suspect",
+			cvItems:             []string{"red wine"},
+			expectedValueAtRisk: 10.99,
+			expectedSeverity:    SeverityMedium,
+		},
+		{
+			name:                "item seen by every sensor",
+			cvItems:             []string{"Red Wine"},
+			rfidItems:           []string{"00000000884389"},
+			scaleDeltas:         []float64{3.05},
+			expectedValueAtRisk: 10.99,
+			expectedSeverity:    SeverityMedium,
+		},
+		{
+			name:                "more items on the scale than seen by cv",
+			cvItems:             []string{"Red Apples"},
+			scaleDeltas:         []float64{1.05, 1.02},
+			expectedValueAtRisk: 2 * 0.99,
+			expectedSeverity:    SeverityLow,
+		},
+		{
+			name:                "scale weight matching several products",
+			scaleDeltas:         []float64{2.16},
+			expectedValueAtRisk: 799,
+			expectedSeverity:    SeverityHigh,
+		},
+		{
+			name:                "unknown items",
+			cvItems:             []string{"bottle"},
+			rfidItems:           []string{"00000000000001"},
+			scaleDeltas:         []float64{9.5},
+			expectedValueAtRisk: 0,
+			expectedSeverity:    SeverityLow,
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{SeverityMediumValue: 10, SeverityHighValue: 50})
+			eventsProcessor.catalog = testCatalog
+			for _, name := range table.cvItems {
+				eventsProcessor.currentCVData = append(eventsProcessor.currentCVData, CVEventEntry{ObjectName: name, ROIs: map[string]ROILocation{}})
+			}
+			for _, upc := range table.rfidItems {
+				eventsProcessor.currentRFIDData = append(eventsProcessor.currentRFIDData, RFIDEventEntry{UPC: upc, ROIs: map[string]ROILocation{}})
+			}
+			for index, delta := range table.scaleDeltas {
+				eventsProcessor.suspectScaleItems[int64(index)] = &ScaleEventEntry{Delta: delta, EventTime: int64(index)}
+			}
+
+			output, err := eventsProcessor.wrapSuspectItems(eventsProcessor.getSuspectLists())
+			require.NoError(t, err)
+
+			suspectList := SuspectLists{}
+			require.NoError(t, json.Unmarshal(output, &suspectList))
+			assert.InDelta(t, table.expectedValueAtRisk, suspectList.ValueAtRisk, floatingPointTolerance)
+			assert.Equal(t, table.expectedSeverity, suspectList.Severity)
+			for _, item := range suspectList.RFIDSuspect {
+				assert.Equal(t, item.UPC, item.GTIN)
+			}
+		})
+	}
+}
+
+func TestPriceScaleSuspectItem(t *testing.T) {
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{SeverityMediumValue: 5, SeverityHighValue: 50})
+	eventsProcessor.catalog = testCatalog
+	eventsProcessor.suspectScaleItems[1] = &ScaleEventEntry{Delta: 2.12, EventTime: 1}
+
+	suspectList := SuspectLists{ScaleSuspect: eventsProcessor.getSuspectScaleItems()}
+	eventsProcessor.priceSuspectItems(&suspectList)
+
+	require.Contains(t, suspectList.ScaleSuspect, int64(1))
+	scaleItem := suspectList.ScaleSuspect[1]
+	assert.Equal(t, "Steak", scaleItem.ProductName)
+	assert.Equal(t, "00000000735797", scaleItem.GTIN)
+	assert.InDelta(t, 8.99, scaleItem.EstimatedPrice, floatingPointTolerance)
+	assert.Equal(t, SeverityMedium, scaleItem.Severity)
+	// the scale suspects of the basket are left untouched
+	assert.Empty(t, eventsProcessor.suspectScaleItems[1].ProductName)
+}
+
+func TestRefreshCatalog(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/products" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(testCatalog)
+	}))
+	defer server.Close()
+
+	lc := logger.NewMockClient()
+	eventsProcessor := NewEventsProcessor(time.Second, &config.ReconcilerConfig{ProductLookupEndpoint: strings.TrimPrefix(server.URL, "http://")})
+	eventsProcessor.refreshCatalog(lc)
+	assert.Equal(t, testCatalog, eventsProcessor.catalog)
+
+	// the previous catalog is kept when the product lookup is down
+	server.Close()
+	eventsProcessor.refreshCatalog(lc)
+	assert.Equal(t, testCatalog, eventsProcessor.catalog)
+}
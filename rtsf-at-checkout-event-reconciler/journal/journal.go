@@ -0,0 +1,233 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// maxRecordSize is the size of the largest line read back from the journal file
+const maxRecordSize = 16 * 1024 * 1024
+
+// Entry holds the fields of a journaled record that it can be queried by, the record type being empty
+// for the transactions
+type Entry struct {
+	RecordType    string   `json:"record_type,omitempty"`
+	LaneId        string   `json:"lane_id"`
+	TransactionId string   `json:"transaction_id"`
+	StartTime     int64    `json:"start_time"`
+	EndTime       int64    `json:"end_time"`
+	ProductIds    []string `json:"product_ids"`
+	SoldSerials   []string `json:"sold_serials"`
+}
+
+// Query selects the journaled records of the record type, the transactions by default, the other empty
+// fields match every record. A record matches the From and To times when the transaction was open at
+// some point in between.
+type Query struct {
+	RecordType    string
+	LaneId        string
+	TransactionId string
+	ProductId     string
+	Serial        string
+	From          int64
+	To            int64
+}
+
+// ParseQuery reads the lane_id, transaction_id, product_id, serial, from and to parameters, the
+// times being epoch milliseconds like the event_time of the checkout events
+func ParseQuery(values url.Values) (Query, error) {
+	query := Query{
+		LaneId:        values.Get("lane_id"),
+		TransactionId: values.Get("transaction_id"),
+		ProductId:     values.Get("product_id"),
+		Serial:        values.Get("serial"),
+	}
+
+	var err error
+	if from := values.Get("from"); len(from) > 0 {
+		if query.From, err = strconv.ParseInt(from, 10, 64); err != nil {
+			return query, fmt.Errorf("from must be epoch milliseconds: %v", err)
+		}
+	}
+	if to := values.Get("to"); len(to) > 0 {
+		if query.To, err = strconv.ParseInt(to, 10, 64); err != nil {
+			return query, fmt.Errorf("to must be epoch milliseconds: %v", err)
+		}
+	}
+	if query.From > 0 && query.To > 0 && query.To < query.From {
+		return query, fmt.Errorf("to can not be before from")
+	}
+	return query, nil
+}
+
+// Matches tells whether the journaled record is selected by the query
+func (query Query) Matches(entry Entry) bool {
+	if entry.RecordType != query.RecordType {
+		return false
+	}
+	if len(query.LaneId) > 0 && entry.LaneId != query.LaneId {
+		return false
+	}
+	if len(query.TransactionId) > 0 && entry.TransactionId != query.TransactionId {
+		return false
+	}
+	if query.From > 0 && entry.EndTime < query.From {
+		return false
+	}
+	if query.To > 0 && entry.StartTime > query.To {
+		return false
+	}
+	if len(query.Serial) > 0 && !contains(entry.SoldSerials, query.Serial) {
+		return false
+	}
+	return len(query.ProductId) == 0 || contains(entry.ProductIds, query.ProductId)
+}
+
+func contains(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Journal appends records to an NDJSON file which is never truncated nor rotated
+type Journal struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	// skippedLines are the numbers of the lines the latest Find could not read
+	skippedLines []int
+}
+
+func NewJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := terminateLastLine(path, file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Journal{path: path, file: file}, nil
+}
+
+// terminateLastLine ends the last line of the journal file when a crash cut it short, so that the
+// next record is appended on a line of its own
+func terminateLastLine(path string, file *os.File) error {
+	reader, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	info, err := reader.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	lastByte := make([]byte, 1)
+	if _, err := reader.ReadAt(lastByte, info.Size()-1); err != nil {
+		return err
+	}
+	if lastByte[0] == '\n' {
+		return nil
+	}
+	_, err = file.Write([]byte{'\n'})
+	return err
+}
+
+// Append writes the record as a line of the journal file, the record must marshal the
+// fields of Entry to be found by Find
+func (journal *Journal) Append(record interface{}) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if journal.file == nil {
+		return fmt.Errorf("journal file %s is closed", journal.path)
+	}
+	_, err = journal.file.Write(line)
+	return err
+}
+
+// Find returns the journaled records matching the query, oldest first. The lines that can not be read,
+// i.e. a record cut short by a crash, are skipped and reported by SkippedLines. The records appended
+// while the file is read are left out, so that the appends are not held up by the queries.
+func (journal *Journal) Find(query Query) ([]json.RawMessage, error) {
+	// the appends write whole lines under the lock, so the size only covers complete records
+	journal.mu.Lock()
+	info, err := os.Stat(journal.path)
+	journal.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(journal.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records := []json.RawMessage{}
+	skippedLines := []int{}
+	scanner := bufio.NewScanner(io.LimitReader(file, info.Size()))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		entry := Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			skippedLines = append(skippedLines, lineNumber)
+			continue
+		}
+		if query.Matches(entry) {
+			records = append(records, append(json.RawMessage{}, scanner.Bytes()...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal file %s: %v", journal.path, err)
+	}
+
+	journal.mu.Lock()
+	journal.skippedLines = skippedLines
+	journal.mu.Unlock()
+	return records, nil
+}
+
+// SkippedLines returns the numbers of the lines of the journal file the latest Find could not read
+func (journal *Journal) SkippedLines() []int {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	return append([]int{}, journal.skippedLines...)
+}
+
+func (journal *Journal) Close() error {
+	journal.mu.Lock()
+	defer journal.mu.Unlock()
+
+	if journal.file == nil {
+		return nil
+	}
+	err := journal.file.Close()
+	journal.file = nil
+	return err
+}
@@ -0,0 +1,146 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package journal
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRecord struct {
+	Entry
+	Lines int `json:"lines"`
+}
+
+func TestJournal_Find(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal", "transactions.ndjson")
+	journal, err := NewJournal(path)
+	require.NoError(t, err)
+
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{LaneId: "1", TransactionId: "t-1", StartTime: 1000, EndTime: 2000, ProductIds: []string{"00000000004011"}}, Lines: 3}))
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{LaneId: "2", TransactionId: "t-2", StartTime: 1500, EndTime: 2500, ProductIds: []string{"00000000735797"}}, Lines: 1}))
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{LaneId: "1", TransactionId: "t-3", StartTime: 3000, EndTime: 4000, ProductIds: []string{"00000000004011", "00000000735797"},
+		SoldSerials: []string{"urn:epc:id:sgtin:0888446.067142.1"}}, Lines: 2}))
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{RecordType: "finding", LaneId: "1", TransactionId: "t-3", StartTime: 3500, EndTime: 3500}, Lines: 1}))
+	require.NoError(t, journal.Close())
+
+	// the records appended before a restart are kept
+	journal, err = NewJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+
+	tables := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{name: "all", query: Query{}, expected: []string{"t-1", "t-2", "t-3"}},
+		{name: "lane", query: Query{LaneId: "1"}, expected: []string{"t-1", "t-3"}},
+		{name: "transaction", query: Query{TransactionId: "t-2"}, expected: []string{"t-2"}},
+		{name: "product", query: Query{ProductId: "00000000735797"}, expected: []string{"t-2", "t-3"}},
+		{name: "from", query: Query{From: 2200}, expected: []string{"t-2", "t-3"}},
+		{name: "to", query: Query{To: 1200}, expected: []string{"t-1"}},
+		{name: "time range", query: Query{From: 2100, To: 2900}, expected: []string{"t-2"}},
+		{name: "lane and product", query: Query{LaneId: "1", ProductId: "00000000735797"}, expected: []string{"t-3"}},
+		{name: "sold serial", query: Query{Serial: "urn:epc:id:sgtin:0888446.067142.1"}, expected: []string{"t-3"}},
+		{name: "serial not sold", query: Query{Serial: "urn:epc:id:sgtin:0888446.067142.2"}, expected: []string{}},
+		{name: "no match", query: Query{LaneId: "3"}, expected: []string{}},
+		{name: "record type", query: Query{RecordType: "finding"}, expected: []string{"t-3"}},
+		{name: "record type and lane", query: Query{RecordType: "finding", LaneId: "2"}, expected: []string{}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			records, err := journal.Find(table.query)
+			require.NoError(t, err)
+
+			found := []string{}
+			for _, record := range records {
+				decoded := testRecord{}
+				require.NoError(t, json.Unmarshal(record, &decoded))
+				assert.NotZero(t, decoded.Lines)
+				found = append(found, decoded.TransactionId)
+			}
+			assert.Equal(t, table.expected, found)
+		})
+	}
+}
+
+func TestJournal_AppendClosed(t *testing.T) {
+	journal, err := NewJournal(filepath.Join(t.TempDir(), "transactions.ndjson"))
+	require.NoError(t, err)
+	require.NoError(t, journal.Close())
+
+	assert.Error(t, journal.Append(testRecord{}))
+	assert.NoError(t, journal.Close())
+}
+
+func TestParseQuery(t *testing.T) {
+	tables := []struct {
+		name          string
+		values        url.Values
+		expected      Query
+		expectedError bool
+	}{
+		{name: "empty", values: url.Values{}, expected: Query{}},
+		{
+			name:     "all parameters",
+			values:   url.Values{"lane_id": {"1"}, "transaction_id": {"t-1"}, "product_id": {"00000000004011"}, "serial": {"urn:epc:id:sgtin:0888446.067142.1"}, "from": {"1000"}, "to": {"2000"}},
+			expected: Query{LaneId: "1", TransactionId: "t-1", ProductId: "00000000004011", Serial: "urn:epc:id:sgtin:0888446.067142.1", From: 1000, To: 2000},
+		},
+		{name: "invalid from", values: url.Values{"from": {"yesterday"}}, expectedError: true},
+		{name: "invalid to", values: url.Values{"to": {"1.5"}}, expectedError: true},
+		{name: "to before from", values: url.Values{"from": {"2000"}, "to": {"1000"}}, expectedError: true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			query, err := ParseQuery(table.values)
+			if table.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, table.expected, query)
+		})
+	}
+}
+
+func TestJournal_FindTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transactions.ndjson")
+	journal, err := NewJournal(path)
+	require.NoError(t, err)
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{LaneId: "1", TransactionId: "t-1"}, Lines: 1}))
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{LaneId: "1", TransactionId: "t-2"}, Lines: 1}))
+	require.NoError(t, journal.Close())
+
+	// a crash cut the last record short
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"lane_id":"1","transaction_id":"t-3","sta`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	journal, err = NewJournal(path)
+	require.NoError(t, err)
+	defer journal.Close()
+
+	records, err := journal.Find(Query{})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, []int{3}, journal.SkippedLines())
+
+	// the next record is appended on a line of its own
+	require.NoError(t, journal.Append(testRecord{Entry: Entry{LaneId: "1", TransactionId: "t-4"}, Lines: 1}))
+	records, err = journal.Find(Query{TransactionId: "t-4"})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, []int{3}, journal.SkippedLines())
+}
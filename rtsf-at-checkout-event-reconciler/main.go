@@ -4,22 +4,33 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/interfaces"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/transforms"
 	"github.com/edgexfoundry/app-functions-sdk-go/v3/pkg/util"
 	"github.com/edgexfoundry/go-mod-core-contracts/v3/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/common"
+	"github.com/google/uuid"
 
+	"event-reconciler/capture"
 	"event-reconciler/config"
+	"event-reconciler/cvlabel"
 	"event-reconciler/events"
+	"event-reconciler/journal"
+	"event-reconciler/statemachine"
 )
 
 const (
-	serviceKey = "app-event-reconciler"
+	serviceKey              = "app-event-reconciler"
+	minReorderFlushInterval = 10 * time.Millisecond
 )
 
 type EventReconcilerAppService struct {
@@ -57,9 +68,63 @@ func (app *EventReconcilerAppService) CreateAndRunAppService(serviceKey string)
 	}
 
 	eventsProcessor := events.NewEventsProcessor(cvTimeAlignment, &app.serviceConfig.Reconciler)
-	eventsProcessor.ResetEventsOccurrence()
+	stateMachine, err := statemachine.LoadDefinition(app.serviceConfig.Reconciler.StateMachineFile)
+	if err != nil {
+		app.lc.Errorf("failed to load the checkout state machine: %v", err)
+		return 1
+	}
+	if err := eventsProcessor.SetStateMachineDefinition(stateMachine); err != nil {
+		app.lc.Errorf("failed to create the checkout state machine: %v", err)
+		return 1
+	}
+	cvLabelMapping, err := cvlabel.LoadMapping(app.serviceConfig.Reconciler.CVLabelMappingFile)
+	if err != nil {
+		app.lc.Errorf("failed to load the CV label mapping: %v", err)
+		return 1
+	}
+	eventsProcessor.SetCVLabelMapping(cvLabelMapping)
 	eventsProcessor.InitWebSocketConnection(app.service, app.lc)
 
+	if app.serviceConfig.Reconciler.CaptureEnabled {
+		recorder, err := capture.NewRecorder(app.serviceConfig.Reconciler.CaptureFile,
+			int64(app.serviceConfig.Reconciler.CaptureMaxSizeMB)*1024*1024, app.serviceConfig.Reconciler.CaptureMaxFiles)
+		if err != nil {
+			app.lc.Errorf("failed to create the checkout events capture file: %v", err)
+			return 1
+		}
+		defer recorder.Close()
+		eventsProcessor.SetRecorder(recorder)
+		app.lc.Infof("Recording checkout events to %s", app.serviceConfig.Reconciler.CaptureFile)
+	}
+
+	if app.serviceConfig.Reconciler.JournalEnabled {
+		transactionJournal, err := journal.NewJournal(app.serviceConfig.Reconciler.JournalFile)
+		if err != nil {
+			app.lc.Errorf("failed to open the transaction journal: %v", err)
+			return 1
+		}
+		defer transactionJournal.Close()
+		if err := eventsProcessor.SetJournal(transactionJournal); err != nil {
+			app.lc.Errorf("failed to read the transaction journal: %v", err)
+			return 1
+		}
+		if skippedLines := transactionJournal.SkippedLines(); len(skippedLines) > 0 {
+			app.lc.Warnf("skipped the unreadable lines %v of the transaction journal", skippedLines)
+		}
+		app.lc.Infof("Journaling completed transactions to %s", app.serviceConfig.Reconciler.JournalFile)
+	}
+
+	reorderLateness, err := app.serviceConfig.Reconciler.GetReorderLateness()
+	if err != nil {
+		app.lc.Errorf("failed to validate Reconciler configuration: %v", err)
+		return 1
+	}
+	if reorderLateness > 0 {
+		eventsProcessor.EnableReorderBuffer(reorderLateness)
+		go app.flushReorderBuffer(eventsProcessor, reorderLateness)
+		app.lc.Infof("Reordering checkout events within %v", reorderLateness)
+	}
+
 	deviceNames := util.DeleteEmptyAndTrim(strings.FieldsFunc(app.serviceConfig.Reconciler.DeviceNames, util.SplitComma))
 	app.lc.Infof("Running the application functions for %v devices...", deviceNames)
 
@@ -72,6 +137,129 @@ func (app *EventReconcilerAppService) CreateAndRunAppService(serviceKey string)
 		writer.WriteHeader(200)
 	}, "GET")
 
+	app.service.AddRoute("/checkout-state", func(writer http.ResponseWriter, req *http.Request) {
+		checkoutState, err := json.Marshal(eventsProcessor.GetCheckoutState())
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(checkoutState)
+	}, "GET")
+
+	app.service.AddRoute("/reorder-metrics", func(writer http.ResponseWriter, req *http.Request) {
+		metrics, err := json.Marshal(eventsProcessor.GetReorderMetrics())
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(metrics)
+	}, "GET")
+
+	app.service.AddRoute("/findings", func(writer http.ResponseWriter, req *http.Request) {
+		includeResolved := req.URL.Query().Get("all") == "true"
+		findings, err := json.Marshal(eventsProcessor.GetFindings(req.URL.Query().Get("lane_id"), includeResolved))
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(findings)
+	}, "GET")
+
+	app.service.AddRoute("/findings/acknowledge", func(writer http.ResponseWriter, req *http.Request) {
+		acknowledgement := events.FindingResolution{}
+		if err := json.NewDecoder(req.Body).Decode(&acknowledgement); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		finding, err := eventsProcessor.AcknowledgeFinding(acknowledgement.Id, acknowledgement.EmployeeId, app.lc)
+		app.writeFinding(writer, finding, err)
+	}, "POST")
+
+	app.service.AddRoute("/findings/resolve", func(writer http.ResponseWriter, req *http.Request) {
+		resolution := events.FindingResolution{}
+		if err := json.NewDecoder(req.Body).Decode(&resolution); err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		finding, err := eventsProcessor.ResolveFinding(resolution, app.service.BuildContext(uuid.NewString(), common.ContentTypeJSON))
+		app.writeFinding(writer, finding, err)
+	}, "POST")
+
+	app.service.AddRoute("/decision-trace", func(writer http.ResponseWriter, req *http.Request) {
+		trace, err := json.Marshal(eventsProcessor.GetDecisionTrace())
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(trace)
+	}, "GET")
+
+	app.service.AddRoute("/basket-confidence", func(writer http.ResponseWriter, req *http.Request) {
+		confidence, err := json.Marshal(eventsProcessor.GetBasketConfidence())
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(confidence)
+	}, "GET")
+
+	app.service.AddRoute("/transactions", func(writer http.ResponseWriter, req *http.Request) {
+		query, err := journal.ParseQuery(req.URL.Query())
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		records, err := eventsProcessor.GetTransactions(query)
+		if errors.Is(err, events.ErrJournalDisabled) {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			app.lc.Errorf("failed to query the transaction journal: %v", err)
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		transactions, err := json.Marshal(records)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(transactions)
+	}, "GET")
+
+	app.service.AddRoute("/sold-epcs", func(writer http.ResponseWriter, req *http.Request) {
+		epc := req.URL.Query().Get("epc")
+		if len(epc) == 0 {
+			http.Error(writer, "epc is required", http.StatusBadRequest)
+			return
+		}
+		sale, sold := eventsProcessor.LookupSoldEPC(epc)
+		if !sold {
+			http.Error(writer, fmt.Sprintf("%s is not in the sold registry", epc), http.StatusNotFound)
+			return
+		}
+		soldEPC, err := json.Marshal(sale)
+		if err != nil {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Write(soldEPC)
+	}, "GET")
+
 	app.service.SetDefaultFunctionsPipeline(
 		transforms.NewFilterFor(deviceNames).FilterByDeviceName,
 		eventsProcessor.ProcessCheckoutEvents,
@@ -81,3 +269,38 @@ func (app *EventReconcilerAppService) CreateAndRunAppService(serviceKey string)
 
 	return 0
 }
+
+// writeFinding writes the finding updated by an attendant, or why it could not be updated
+func (app *EventReconcilerAppService) writeFinding(writer http.ResponseWriter, finding events.Finding, err error) {
+	if errors.Is(err, events.ErrFindingNotFound) {
+		http.Error(writer, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := json.Marshal(finding)
+	if err != nil {
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Access-Control-Allow-Origin", "*")
+	writer.Write(body)
+}
+
+// flushReorderBuffer processes the readings held for the lateness window when no later reading releases them
+func (app *EventReconcilerAppService) flushReorderBuffer(eventsProcessor *events.EventsProcessor, lateness time.Duration) {
+	interval := lateness / 2
+	if interval < minReorderFlushInterval {
+		interval = minReorderFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		eventsProcessor.FlushReorderBuffer(app.service.BuildContext(uuid.NewString(), common.ContentTypeJSON))
+	}
+}
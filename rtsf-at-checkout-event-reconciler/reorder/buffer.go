@@ -0,0 +1,211 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package reorder holds the checkout event readings of each lane for a lateness window, so that
+// readings coming over transports with different latencies are processed in event_time order
+package reorder
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+
+	"event-reconciler/clock"
+)
+
+// Metrics counts the readings going through the buffer
+type Metrics struct {
+	// Buffered readings held for the lateness window
+	Buffered int64 `json:"buffered"`
+	// Released readings handed over for processing, including the late ones
+	Released int64 `json:"released"`
+	// Reordered readings released after readings of the lane that arrived later
+	Reordered int64 `json:"reordered"`
+	// Late readings that arrived after a later reading of the lane was released, released as they arrive
+	Late int64 `json:"late"`
+	// Dropped readings that arrived later than the lateness window behind the released readings of the lane
+	Dropped int64 `json:"dropped"`
+	// Pending readings currently held
+	Pending int `json:"pending"`
+}
+
+type pendingReading struct {
+	reading   dtos.BaseReading
+	eventTime int64
+	arrived   time.Time
+	sequence  uint64
+}
+
+type laneBuffer struct {
+	pending []pendingReading
+	// maxEventTime is the latest event time received on the lane
+	maxEventTime int64
+	// releasedEventTime is the latest event time released on the lane
+	releasedEventTime int64
+	releasedSequence  uint64
+}
+
+// Buffer releases a reading once a reading of the same lane with an event time later by the lateness
+// window arrives, or once it has been held for the lateness window by the clock. The lateness window
+// must be positive, without window the readings are processed as they arrive.
+type Buffer struct {
+	mu       sync.Mutex
+	lateness time.Duration
+	clock    clock.Clock
+	lanes    map[string]*laneBuffer
+	sequence uint64
+	metrics  Metrics
+}
+
+func NewBuffer(lateness time.Duration, clock clock.Clock) *Buffer {
+	return &Buffer{
+		lateness: lateness,
+		clock:    clock,
+		lanes:    make(map[string]*laneBuffer),
+	}
+}
+
+// readingDetails returns the lane and event time of the reading, readings without event time are not buffered
+func readingDetails(reading dtos.BaseReading) (string, int64, bool) {
+	jsonData, err := json.Marshal(reading.ObjectValue)
+	if err != nil {
+		return "", 0, false
+	}
+	details := map[string]interface{}{}
+	if err := json.Unmarshal(jsonData, &details); err != nil {
+		return "", 0, false
+	}
+
+	eventTime, ok := details["event_time"].(float64)
+	if !ok || eventTime <= 0 {
+		return "", 0, false
+	}
+	laneID := ""
+	if details["lane_id"] != nil {
+		laneID = fmt.Sprintf("%v", details["lane_id"])
+	}
+	return laneID, int64(eventTime), true
+}
+
+// Add buffers the reading and returns the readings of its lane ready to be processed, in event_time order
+func (buffer *Buffer) Add(reading dtos.BaseReading) []dtos.BaseReading {
+	laneID, eventTime, ok := readingDetails(reading)
+	if !ok {
+		buffer.mu.Lock()
+		buffer.metrics.Released++
+		buffer.mu.Unlock()
+		return []dtos.BaseReading{reading}
+	}
+
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	lane, ok := buffer.lanes[laneID]
+	if !ok {
+		lane = &laneBuffer{}
+		buffer.lanes[laneID] = lane
+	}
+
+	buffer.sequence++
+	if eventTime < lane.releasedEventTime {
+		if clock.FromEventTime(lane.releasedEventTime).Sub(clock.FromEventTime(eventTime)) > buffer.lateness {
+			buffer.metrics.Dropped++
+			return nil
+		}
+		buffer.metrics.Late++
+		buffer.metrics.Released++
+		return []dtos.BaseReading{reading}
+	}
+
+	buffer.metrics.Buffered++
+	lane.pending = append(lane.pending, pendingReading{
+		reading:   reading,
+		eventTime: eventTime,
+		arrived:   buffer.clock.Now(),
+		sequence:  buffer.sequence,
+	})
+	if eventTime > lane.maxEventTime {
+		lane.maxEventTime = eventTime
+	}
+
+	watermark := clock.ToEventTime(clock.FromEventTime(lane.maxEventTime).Add(-buffer.lateness))
+	return buffer.release(lane, watermark, buffer.clock.Now())
+}
+
+// Flush returns the readings of all lanes held for the lateness window, in event_time order per lane
+func (buffer *Buffer) Flush() []dtos.BaseReading {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	return buffer.releaseLanes(0, buffer.clock.Now())
+}
+
+// FlushAll returns all the readings held, in event_time order per lane
+func (buffer *Buffer) FlushAll() []dtos.BaseReading {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	return buffer.releaseLanes(math.MaxInt64, buffer.clock.Now())
+}
+
+func (buffer *Buffer) releaseLanes(watermark int64, now time.Time) []dtos.BaseReading {
+	laneIDs := make([]string, 0, len(buffer.lanes))
+	for laneID := range buffer.lanes {
+		laneIDs = append(laneIDs, laneID)
+	}
+	sort.Strings(laneIDs)
+
+	released := []dtos.BaseReading{}
+	for _, laneID := range laneIDs {
+		released = append(released, buffer.release(buffer.lanes[laneID], watermark, now)...)
+	}
+	return released
+}
+
+// release returns the pending readings of the lane up to the watermark, along with the readings before
+// them that have been held for the lateness window, and keeps the others pending
+func (buffer *Buffer) release(lane *laneBuffer, watermark int64, now time.Time) []dtos.BaseReading {
+	sort.SliceStable(lane.pending, func(i, j int) bool {
+		return lane.pending[i].eventTime < lane.pending[j].eventTime
+	})
+
+	// a reading held for too long also releases the readings with an earlier event time
+	releaseCount := 0
+	for index, pending := range lane.pending {
+		if pending.eventTime <= watermark || now.Sub(pending.arrived) >= buffer.lateness {
+			releaseCount = index + 1
+		}
+	}
+
+	released := make([]dtos.BaseReading, 0, releaseCount)
+	for _, pending := range lane.pending[:releaseCount] {
+		if pending.sequence < lane.releasedSequence {
+			buffer.metrics.Reordered++
+		}
+		if pending.sequence > lane.releasedSequence {
+			lane.releasedSequence = pending.sequence
+		}
+		lane.releasedEventTime = pending.eventTime
+		released = append(released, pending.reading)
+	}
+	buffer.metrics.Released += int64(releaseCount)
+	lane.pending = lane.pending[releaseCount:]
+	return released
+}
+
+// Metrics returns the counts of the readings that went through the buffer
+func (buffer *Buffer) Metrics() Metrics {
+	buffer.mu.Lock()
+	defer buffer.mu.Unlock()
+
+	metrics := buffer.metrics
+	for _, lane := range buffer.lanes {
+		metrics.Pending += len(lane.pending)
+	}
+	return metrics
+}
@@ -0,0 +1,100 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package reorder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/v3/dtos"
+	"github.com/stretchr/testify/assert"
+
+	"event-reconciler/clock"
+)
+
+func testReading(resourceName string, laneID string, eventTime int64) dtos.BaseReading {
+	return dtos.BaseReading{
+		ResourceName: resourceName,
+		ObjectReading: dtos.ObjectReading{
+			ObjectValue: map[string]interface{}{"lane_id": laneID, "event_time": eventTime},
+		},
+	}
+}
+
+func resourceNames(readings []dtos.BaseReading) []string {
+	names := []string{}
+	for _, reading := range readings {
+		names = append(names, reading.ResourceName)
+	}
+	return names
+}
+
+func TestBufferReordersByEventTime(t *testing.T) {
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	buffer := NewBuffer(500*time.Millisecond, manualClock)
+
+	assert.Empty(t, buffer.Add(testReading("scanned-item", "1", 1700000000000)))
+	// the CV reading took longer to arrive than the weight that followed the scan
+	assert.Empty(t, buffer.Add(testReading("weight", "1", 1700000000300)))
+	assert.Empty(t, buffer.Add(testReading("cv-roi-event", "1", 1700000000100)))
+	// another lane does not move the watermark of lane 1
+	assert.Empty(t, buffer.Add(testReading("weight", "2", 1700000002000)))
+
+	released := buffer.Add(testReading("payment-start", "1", 1700000000700))
+	assert.Equal(t, []string{"scanned-item", "cv-roi-event"}, resourceNames(released))
+
+	metrics := buffer.Metrics()
+	assert.Equal(t, int64(5), metrics.Buffered)
+	assert.Equal(t, int64(2), metrics.Released)
+	assert.Equal(t, 3, metrics.Pending)
+
+	assert.Equal(t, []string{"weight", "payment-start", "weight"}, resourceNames(buffer.FlushAll()))
+	metrics = buffer.Metrics()
+	assert.Equal(t, int64(1), metrics.Reordered)
+	assert.Equal(t, 0, metrics.Pending)
+}
+
+func TestBufferFlushAfterLateness(t *testing.T) {
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	buffer := NewBuffer(500*time.Millisecond, manualClock)
+
+	assert.Empty(t, buffer.Add(testReading("weight", "1", 1700000000300)))
+	manualClock.Advance(200 * time.Millisecond)
+	assert.Empty(t, buffer.Add(testReading("cv-roi-event", "1", 1700000000100)))
+
+	manualClock.Advance(299 * time.Millisecond)
+	assert.Empty(t, buffer.Flush())
+
+	// the weight held for the lateness window also releases the earlier cv reading
+	manualClock.Advance(time.Millisecond)
+	assert.Equal(t, []string{"cv-roi-event", "weight"}, resourceNames(buffer.Flush()))
+}
+
+func TestBufferLateAndDropped(t *testing.T) {
+	manualClock := clock.NewManualClock(time.Unix(1700000000, 0))
+	buffer := NewBuffer(500*time.Millisecond, manualClock)
+
+	buffer.Add(testReading("weight", "1", 1700000001000))
+	assert.Equal(t, []string{"weight"}, resourceNames(buffer.Add(testReading("scanned-item", "1", 1700000002000))))
+	manualClock.Advance(500 * time.Millisecond)
+	assert.Equal(t, []string{"scanned-item"}, resourceNames(buffer.Flush()))
+
+	// behind the released readings but within the lateness window
+	assert.Equal(t, []string{"cv-roi-event"}, resourceNames(buffer.Add(testReading("cv-roi-event", "1", 1700000001700))))
+	// behind the released readings by more than the lateness window
+	assert.Empty(t, buffer.Add(testReading("rfid-roi-event", "1", 1700000001000)))
+
+	metrics := buffer.Metrics()
+	assert.Equal(t, int64(1), metrics.Late)
+	assert.Equal(t, int64(1), metrics.Dropped)
+	assert.Equal(t, int64(3), metrics.Released)
+}
+
+func TestBufferPassesReadingsWithoutEventTime(t *testing.T) {
+	buffer := NewBuffer(500*time.Millisecond, clock.NewManualClock(time.Unix(1700000000, 0)))
+
+	reading := dtos.BaseReading{ResourceName: "weight", SimpleReading: dtos.SimpleReading{Value: "1"}}
+	assert.Equal(t, []dtos.BaseReading{reading}, buffer.Add(reading))
+	assert.Equal(t, int64(1), buffer.Metrics().Released)
+}
@@ -0,0 +1,76 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package soldepc keeps the RFID tags sold at the checkout lanes for a time to live, so that the
+// tags read at the exit of the store can be told paid or unpaid
+package soldepc
+
+import (
+	"sync"
+	"time"
+
+	"event-reconciler/clock"
+)
+
+// Sale is an RFID tagged item paid at a checkout lane, keyed by the serial of its tag
+type Sale struct {
+	EPC           string `json:"epc"`
+	Serial        string `json:"serial"`
+	ProductId     string `json:"product_id"`
+	ProductName   string `json:"product_name"`
+	LaneId        string `json:"lane_id"`
+	TransactionId string `json:"transaction_id"`
+	SoldTime      int64  `json:"sold_time"`
+	ExpiresTime   int64  `json:"expires_time"`
+}
+
+// Registry holds the sales for the time to live after they were added, the ttl must be positive
+type Registry struct {
+	mu    sync.Mutex
+	ttl   time.Duration
+	sales map[string]Sale
+}
+
+func NewRegistry(ttl time.Duration) *Registry {
+	return &Registry{ttl: ttl, sales: make(map[string]Sale)}
+}
+
+// Add registers the sale until the time to live elapsed from now, a serial sold again is kept for
+// the time to live of its latest sale
+func (registry *Registry) Add(sale Sale, now time.Time) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.expire(now)
+	sale.ExpiresTime = clock.ToEventTime(now.Add(registry.ttl))
+	registry.sales[sale.Serial] = sale
+}
+
+// Lookup returns the sale of the serial, if it did not expire
+func (registry *Registry) Lookup(serial string, now time.Time) (Sale, bool) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	sale, ok := registry.sales[serial]
+	if !ok || sale.ExpiresTime <= clock.ToEventTime(now) {
+		return Sale{}, false
+	}
+	return sale, true
+}
+
+// Len returns the number of sales held, including the expired sales not dropped yet
+func (registry *Registry) Len() int {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	return len(registry.sales)
+}
+
+func (registry *Registry) expire(now time.Time) {
+	nowEventTime := clock.ToEventTime(now)
+	for serial, sale := range registry.sales {
+		if sale.ExpiresTime <= nowEventTime {
+			delete(registry.sales, serial)
+		}
+	}
+}
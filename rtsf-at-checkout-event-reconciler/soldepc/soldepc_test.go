@@ -0,0 +1,50 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package soldepc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	registry := NewRegistry(time.Hour)
+	registry.Add(Sale{Serial: "urn:epc:id:sgtin:0000000.088438.1", TransactionId: "t-1"}, start)
+	registry.Add(Sale{Serial: "urn:epc:id:sgtin:0000000.088438.2", TransactionId: "t-2"}, start.Add(30*time.Minute))
+
+	tables := []struct {
+		name                string
+		serial              string
+		at                  time.Time
+		expectedSold        bool
+		expectedTransaction string
+	}{
+		{name: "sold", serial: "urn:epc:id:sgtin:0000000.088438.1", at: start.Add(59 * time.Minute), expectedSold: true, expectedTransaction: "t-1"},
+		{name: "expired", serial: "urn:epc:id:sgtin:0000000.088438.1", at: start.Add(time.Hour)},
+		{name: "sold later", serial: "urn:epc:id:sgtin:0000000.088438.2", at: start.Add(time.Hour), expectedSold: true, expectedTransaction: "t-2"},
+		{name: "never sold", serial: "urn:epc:id:sgtin:0000000.088438.3", at: start},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			sale, sold := registry.Lookup(table.serial, table.at)
+			require.Equal(t, table.expectedSold, sold)
+			assert.Equal(t, table.expectedTransaction, sale.TransactionId)
+		})
+	}
+
+	// the expired sales are dropped as new sales are added
+	registry.Add(Sale{Serial: "urn:epc:id:sgtin:0000000.088438.3", TransactionId: "t-3"}, start.Add(time.Hour))
+	assert.Equal(t, 2, registry.Len())
+
+	// a serial sold again is kept for its latest sale
+	registry.Add(Sale{Serial: "urn:epc:id:sgtin:0000000.088438.2", TransactionId: "t-4"}, start.Add(2*time.Hour))
+	sale, sold := registry.Lookup("urn:epc:id:sgtin:0000000.088438.2", start.Add(150*time.Minute))
+	require.True(t, sold)
+	assert.Equal(t, "t-4", sale.TransactionId)
+}
@@ -0,0 +1,234 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+// Package statemachine runs the lifecycle of a checkout transaction as a state machine whose
+// states, transitions, guards and actions are defined in configuration
+package statemachine
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnyState matches every state in the from states of a transition
+const AnyState = "*"
+
+//go:embed checkout.yaml
+var checkoutDefinition []byte
+
+// Transition moves the checkout from one of the from states to the to state when the event occurs
+// and all the guards pass, the actions are run once the transition is taken
+type Transition struct {
+	Event   string   `yaml:"event"`
+	From    []string `yaml:"from"`
+	To      string   `yaml:"to"`
+	Guards  []string `yaml:"guards"`
+	Actions []string `yaml:"actions"`
+}
+
+// Definition describes the states of the checkout and the transitions between them
+type Definition struct {
+	Initial        string       `yaml:"initial"`
+	States         []string     `yaml:"states"`
+	AnyStateEvents []string     `yaml:"anyStateEvents"`
+	Transitions    []Transition `yaml:"transitions"`
+}
+
+// Guard tells whether a transition can be taken
+type Guard func() bool
+
+// Action is run when a transition is taken, it must not call the machine
+type Action func()
+
+// RejectedError reports a transition that was not taken and the reason why
+type RejectedError struct {
+	Event  string `json:"event"`
+	State  string `json:"state"`
+	Reason string `json:"reason"`
+	// Guard is the guard that failed, if any
+	Guard string `json:"guard,omitempty"`
+}
+
+func (rejected *RejectedError) Error() string {
+	return fmt.Sprintf("%s rejected in state %s: %s", rejected.Event, rejected.State, rejected.Reason)
+}
+
+// CheckoutDefinition returns the default lifecycle of a checkout transaction
+func CheckoutDefinition() Definition {
+	definition, err := ParseDefinition(checkoutDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded checkout state machine: %v", err))
+	}
+	return definition
+}
+
+// LoadDefinition reads the definition of the state machine from a YAML file overriding the embedded
+// checkout lifecycle, the embedded definition is returned when the path is empty
+func LoadDefinition(path string) (Definition, error) {
+	if len(path) == 0 {
+		return CheckoutDefinition(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Definition{}, fmt.Errorf("failed to read the state machine %s: %v", path, err)
+	}
+	definition, err := ParseDefinition(data)
+	if err != nil {
+		return Definition{}, fmt.Errorf("invalid state machine %s: %v", path, err)
+	}
+	return definition, nil
+}
+
+// ParseDefinition reads the definition of the state machine from YAML and checks its states are defined
+func ParseDefinition(data []byte) (Definition, error) {
+	definition := Definition{}
+	if err := yaml.Unmarshal(data, &definition); err != nil {
+		return definition, err
+	}
+
+	states := make(map[string]bool)
+	for _, state := range definition.States {
+		states[state] = true
+	}
+	if !states[definition.Initial] {
+		return definition, fmt.Errorf("initial state %q is not a state", definition.Initial)
+	}
+	for _, transition := range definition.Transitions {
+		if len(transition.Event) == 0 {
+			return definition, fmt.Errorf("transition without event")
+		}
+		if len(transition.From) == 0 {
+			return definition, fmt.Errorf("transition of %s without from states", transition.Event)
+		}
+		for _, state := range transition.From {
+			if state != AnyState && !states[state] {
+				return definition, fmt.Errorf("transition of %s from unknown state %q", transition.Event, state)
+			}
+		}
+		if len(transition.To) > 0 && !states[transition.To] {
+			return definition, fmt.Errorf("transition of %s to unknown state %q", transition.Event, transition.To)
+		}
+	}
+	return definition, nil
+}
+
+// Machine keeps the current state of the checkout
+type Machine struct {
+	mu             sync.Mutex
+	definition     Definition
+	state          string
+	anyStateEvents map[string]bool
+	guards         map[string]Guard
+	actions        map[string]Action
+}
+
+// New creates the state machine in its initial state, every guard and action of the definition
+// must be provided
+func New(definition Definition, guards map[string]Guard, actions map[string]Action) (*Machine, error) {
+	for _, transition := range definition.Transitions {
+		for _, guard := range transition.Guards {
+			if _, ok := guards[guard]; !ok {
+				return nil, fmt.Errorf("transition of %s uses unknown guard %q", transition.Event, guard)
+			}
+		}
+		for _, action := range transition.Actions {
+			if _, ok := actions[action]; !ok {
+				return nil, fmt.Errorf("transition of %s uses unknown action %q", transition.Event, action)
+			}
+		}
+	}
+
+	anyStateEvents := make(map[string]bool)
+	for _, event := range definition.AnyStateEvents {
+		anyStateEvents[event] = true
+	}
+
+	return &Machine{
+		definition:     definition,
+		state:          definition.Initial,
+		anyStateEvents: anyStateEvents,
+		guards:         guards,
+		actions:        actions,
+	}, nil
+}
+
+// State returns the current state
+func (machine *Machine) State() string {
+	machine.mu.Lock()
+	defer machine.mu.Unlock()
+	return machine.state
+}
+
+// Reset moves the machine back to its initial state
+func (machine *Machine) Reset() {
+	machine.mu.Lock()
+	defer machine.mu.Unlock()
+	machine.state = machine.definition.Initial
+}
+
+func (transition Transition) allowedFrom(state string) bool {
+	for _, from := range transition.From {
+		if from == state || from == AnyState {
+			return true
+		}
+	}
+	return false
+}
+
+// Fire takes the first transition of the event allowed from the current state whose guards pass,
+// and returns a RejectedError when there is none
+func (machine *Machine) Fire(event string) error {
+	machine.mu.Lock()
+	defer machine.mu.Unlock()
+
+	if machine.anyStateEvents[event] {
+		return nil
+	}
+
+	known := false
+	var failedGuard string
+	for _, transition := range machine.definition.Transitions {
+		if transition.Event != event {
+			continue
+		}
+		known = true
+		if !transition.allowedFrom(machine.state) {
+			continue
+		}
+
+		failedGuard = ""
+		for _, guard := range transition.Guards {
+			if !machine.guards[guard]() {
+				failedGuard = guard
+				break
+			}
+		}
+		if len(failedGuard) > 0 {
+			continue
+		}
+
+		if len(transition.To) > 0 {
+			machine.state = transition.To
+		}
+		for _, action := range transition.Actions {
+			machine.actions[action]()
+		}
+		return nil
+	}
+
+	rejected := &RejectedError{Event: event, State: machine.state}
+	switch {
+	case !known:
+		rejected.Reason = "unknown event"
+	case len(failedGuard) > 0:
+		rejected.Reason = "guard " + failedGuard + " failed"
+		rejected.Guard = failedGuard
+	default:
+		rejected.Reason = "no transition from this state"
+	}
+	return rejected
+}
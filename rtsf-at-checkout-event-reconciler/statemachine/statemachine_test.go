@@ -0,0 +1,117 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package statemachine
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefinition(t *testing.T) {
+	definition, err := LoadDefinition("")
+	require.NoError(t, err)
+	assert.Equal(t, CheckoutDefinition(), definition)
+
+	overridePath := filepath.Join(t.TempDir(), "checkout-state-machine.yaml")
+	require.NoError(t, os.WriteFile(overridePath, []byte("initial: idle\nstates: [idle, open]\ntransitions:\n  - event: basket-open\n    from: [idle]\n    to: open"), 0644))
+	definition, err = LoadDefinition(overridePath)
+	require.NoError(t, err)
+	assert.Equal(t, Definition{
+		Initial:     "idle",
+		States:      []string{"idle", "open"},
+		Transitions: []Transition{{Event: "basket-open", From: []string{"idle"}, To: "open"}},
+	}, definition)
+
+	_, err = LoadDefinition(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestParseDefinitionErrors(t *testing.T) {
+	tables := []struct {
+		name       string
+		definition string
+		err        string
+	}{
+		{
+			name:       "unknown initial state",
+			definition: "initial: start\nstates: [idle]",
+			err:        `initial state "start" is not a state`,
+		},
+		{
+			name:       "unknown from state",
+			definition: "initial: idle\nstates: [idle]\ntransitions:\n  - event: basket-open\n    from: [closed]",
+			err:        `transition of basket-open from unknown state "closed"`,
+		},
+		{
+			name:       "unknown to state",
+			definition: "initial: idle\nstates: [idle]\ntransitions:\n  - event: basket-open\n    from: [idle]\n    to: open",
+			err:        `transition of basket-open to unknown state "open"`,
+		},
+		{
+			name:       "no from state",
+			definition: "initial: idle\nstates: [idle]\ntransitions:\n  - event: basket-open",
+			err:        "transition of basket-open without from states",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := ParseDefinition([]byte(table.definition))
+			assert.EqualError(t, err, table.err)
+		})
+	}
+}
+
+func TestMachineFire(t *testing.T) {
+	definition, err := ParseDefinition([]byte(`
+initial: idle
+states: [idle, open, locked]
+anyStateEvents: [heartbeat]
+transitions:
+  - event: open
+    from: [idle]
+    to: open
+    actions: [count]
+  - event: lock
+    from: [open]
+    to: locked
+    guards: [allowed]
+  - event: override
+    from: ["*"]
+    to: idle
+`))
+	require.NoError(t, err)
+
+	allowed := false
+	count := 0
+	_, err = New(definition, map[string]Guard{}, map[string]Action{"count": func() { count++ }})
+	assert.EqualError(t, err, `transition of lock uses unknown guard "allowed"`)
+
+	machine, err := New(definition, map[string]Guard{"allowed": func() bool { return allowed }}, map[string]Action{"count": func() { count++ }})
+	require.NoError(t, err)
+
+	assert.NoError(t, machine.Fire("heartbeat"))
+	assert.NoError(t, machine.Fire("open"))
+	assert.Equal(t, "open", machine.State())
+	assert.Equal(t, 1, count)
+
+	assert.Equal(t, &RejectedError{Event: "lock", State: "open", Reason: "guard allowed failed", Guard: "allowed"}, machine.Fire("lock"))
+	allowed = true
+	assert.NoError(t, machine.Fire("lock"))
+	assert.Equal(t, "locked", machine.State())
+
+	assert.Equal(t, &RejectedError{Event: "open", State: "locked", Reason: "no transition from this state"}, machine.Fire("open"))
+	assert.Equal(t, &RejectedError{Event: "close", State: "locked", Reason: "unknown event"}, machine.Fire("close"))
+
+	assert.NoError(t, machine.Fire("override"))
+	assert.Equal(t, "idle", machine.State())
+
+	assert.NoError(t, machine.Fire("open"))
+	machine.Reset()
+	assert.Equal(t, "idle", machine.State())
+}
@@ -0,0 +1,53 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// simulatorClock tells the time the events are stamped with and waits between the events
+type simulatorClock interface {
+	Now() time.Time
+	Sleep(duration time.Duration)
+}
+
+// clock is replaced by a manualClock in tests, so that scripts run instantly with deterministic event times
+var clock simulatorClock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(duration time.Duration) {
+	time.Sleep(duration)
+}
+
+// manualClock only moves when it sleeps, it moves forward by the sleep duration without waiting
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newManualClock(start time.Time) *manualClock {
+	return &manualClock{now: start}
+}
+
+func (manual *manualClock) Now() time.Time {
+	manual.mu.Lock()
+	defer manual.mu.Unlock()
+	return manual.now
+}
+
+func (manual *manualClock) Sleep(duration time.Duration) {
+	if duration <= 0 {
+		return
+	}
+	manual.mu.Lock()
+	defer manual.mu.Unlock()
+	manual.now = manual.now.Add(duration)
+}
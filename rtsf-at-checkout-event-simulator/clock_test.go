@@ -0,0 +1,67 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTimePublisher reports the event time of every published event
+type eventTimePublisher struct {
+	eventTimes chan int64
+}
+
+func (timePublisher *eventTimePublisher) publish(chkoutEvt *CheckoutEvent, payload []byte) error {
+	data := struct {
+		EventTime int64 `json:"event_time"`
+	}{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return err
+	}
+	timePublisher.eventTimes <- data.EventTime
+	return nil
+}
+
+func (timePublisher *eventTimePublisher) close() {}
+
+func TestRunCheckoutEventsManualClock(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	timePublisher := &eventTimePublisher{eventTimes: make(chan int64, 16)}
+
+	originalClock, originalPublisher := clock, publisher
+	defer func() {
+		clock, publisher = originalClock, originalPublisher
+	}()
+	manual := newManualClock(start)
+	clock, publisher = manual, timePublisher
+
+	checkoutEvents, err := loadCheckoutEvents("tests/rttl_scale_reconciliation/DemoTestCase_1.json")
+	require.NoError(t, err)
+
+	diffs, err := runCheckoutEvents(checkoutEvents, false)
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+
+	// the wait times of the script moved the clock without waiting
+	assert.Equal(t, start.Add(13*time.Second), manual.Now())
+
+	// events are published concurrently, their event times follow the wait times of the script
+	eventTimes := map[int64]bool{}
+	for range checkoutEvents.Events {
+		select {
+		case eventTime := <-timePublisher.eventTimes:
+			eventTimes[eventTime] = true
+		case <-time.After(time.Second):
+			require.Fail(t, "event not published")
+		}
+	}
+	for _, offset := range []time.Duration{0, 2, 3, 5, 6, 7, 9, 11} {
+		assert.True(t, eventTimes[start.Add(offset*time.Second).UnixNano()/int64(time.Millisecond)], "missing event at %ds", offset)
+	}
+}
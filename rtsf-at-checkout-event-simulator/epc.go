@@ -0,0 +1,51 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+const (
+	sgtin96Header = 0x30
+	// the product barcodes use a 7 digit company prefix, SGTIN-96 partition 5
+	sgtinPartition         = 5
+	sgtinCompanyPrefixBits = 24
+	sgtinItemReferenceBits = 20
+	sgtinSerialBits        = 38
+	gtin14Length           = 14
+)
+
+// encodeSGTIN96 builds the SGTIN-96 EPC hex string of a tagged item from its GTIN-14 barcode,
+// the reverse of what the reconciler does to look up the product of an RFID tag
+func encodeSGTIN96(gtin14 string, serial int64) (string, error) {
+	if len(gtin14) != gtin14Length {
+		return "", fmt.Errorf("barcode %s is not a GTIN-14", gtin14)
+	}
+
+	// GTIN-14 is indicator digit, company prefix, item reference and check digit,
+	// the indicator digit is stored in front of the item reference
+	companyPrefix, err := strconv.ParseInt(gtin14[1:8], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("barcode %s is not numeric", gtin14)
+	}
+	itemReference, err := strconv.ParseInt(gtin14[0:1]+gtin14[8:13], 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("barcode %s is not numeric", gtin14)
+	}
+	if serial < 0 || serial >= 1<<sgtinSerialBits {
+		return "", fmt.Errorf("serial number %d does not fit in %d bits", serial, sgtinSerialBits)
+	}
+
+	epc := big.NewInt(sgtin96Header)
+	// filter value 0 followed by the partition
+	epc.Lsh(epc, 6).Or(epc, big.NewInt(sgtinPartition))
+	epc.Lsh(epc, sgtinCompanyPrefixBits).Or(epc, big.NewInt(companyPrefix))
+	epc.Lsh(epc, sgtinItemReferenceBits).Or(epc, big.NewInt(itemReference))
+	epc.Lsh(epc, sgtinSerialBits).Or(epc, big.NewInt(serial))
+
+	return fmt.Sprintf("%024X", epc), nil
+}
@@ -0,0 +1,375 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	skipScanLoss      = "skip-scan"
+	ticketSwitchLoss  = "ticket-switch"
+	unscannedRFIDLoss = "unscanned-rfid"
+
+	roiActionEntered = "ENTERED"
+	roiActionExited  = "EXITED"
+
+	generatedCustomerID = "joe5"
+	generatedEmployeeID = "mary1"
+	generatedLaneID     = "1"
+	firstEPCSerial      = 12345
+	truthFileSuffix     = "_truth"
+)
+
+// GeneratorSettings is the struct for the settings of the randomized basket generator
+type GeneratorSettings struct {
+	Catalog           string  `json:"catalog"`
+	Output            string  `json:"output"`
+	Seed              int64   `json:"seed"`
+	Baskets           int     `json:"baskets"`
+	MinItems          int     `json:"min_items"`
+	MaxItems          int     `json:"max_items"`
+	MaxQuantity       int     `json:"max_quantity"`
+	MinPrice          float64 `json:"min_price"`
+	MaxPrice          float64 `json:"max_price"`
+	WaitTime          string  `json:"wait_time"`
+	SkipScanRate      float64 `json:"skip_scan_rate"`
+	TicketSwitchRate  float64 `json:"ticket_switch_rate"`
+	UnscannedRFIDRate float64 `json:"unscanned_rfid_rate"`
+}
+
+// CatalogProduct is the struct for a product of the product lookup catalog
+type CatalogProduct struct {
+	Barcode      string  `json:"barcode"`
+	Name         string  `json:"name"`
+	MinWeight    float64 `json:"min_weight"`
+	MaxWeight    float64 `json:"max_weight"`
+	RFIDEligible bool    `json:"rfid_eligible"`
+}
+
+// GroundTruth is the struct for what really happened in the generated baskets
+type GroundTruth struct {
+	Seed    int64         `json:"seed"`
+	Baskets []BasketTruth `json:"baskets"`
+}
+
+// BasketTruth is the struct for the items that really went through the lane for a basket
+type BasketTruth struct {
+	BasketID string      `json:"basket_id"`
+	Items    []ItemTruth `json:"items"`
+}
+
+// ItemTruth is the struct for an item of a basket, ScannedAs is empty when the item was not scanned
+type ItemTruth struct {
+	ProductID   string    `json:"product_id"`
+	ProductName string    `json:"product_name"`
+	Quantity    int       `json:"quantity"`
+	UnitPrice   float64   `json:"unit_price"`
+	Weights     []float64 `json:"weights"`
+	EPCs        []string  `json:"epcs,omitempty"`
+	ScannedAs   string    `json:"scanned_as,omitempty"`
+	Loss        string    `json:"loss,omitempty"`
+}
+
+type basketGenerator struct {
+	settings  GeneratorSettings
+	catalog   []CatalogProduct
+	rng       *rand.Rand
+	prices    map[string]float64
+	epcSerial int64
+	events    []CheckoutEvent
+}
+
+func loadGeneratorSettings(filePath string) (GeneratorSettings, error) {
+	settings := GeneratorSettings{
+		Baskets:     1,
+		MinItems:    1,
+		MaxItems:    5,
+		MaxQuantity: 3,
+		MinPrice:    0.99,
+		MaxPrice:    19.99,
+		WaitTime:    "1s",
+	}
+
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return settings, err
+	}
+	if err := json.Unmarshal(contents, &settings); err != nil {
+		return settings, err
+	}
+
+	if len(settings.Catalog) == 0 || len(settings.Output) == 0 {
+		return settings, fmt.Errorf("generator settings require a catalog and an output file")
+	}
+	if settings.Baskets < 1 || settings.MinItems < 1 || settings.MaxItems < settings.MinItems || settings.MaxQuantity < 1 {
+		return settings, fmt.Errorf("generator settings require at least one basket, item and quantity")
+	}
+	for _, rate := range []float64{settings.SkipScanRate, settings.TicketSwitchRate, settings.UnscannedRFIDRate} {
+		if rate < 0 || rate > 1 {
+			return settings, fmt.Errorf("loss rates must be between 0 and 1")
+		}
+	}
+	if _, err := time.ParseDuration(settings.WaitTime); err != nil {
+		return settings, err
+	}
+	if settings.Seed == 0 {
+		settings.Seed = clock.Now().UnixNano()
+	}
+
+	return settings, nil
+}
+
+func loadCatalog(filePath string) ([]CatalogProduct, error) {
+	var catalog []CatalogProduct
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &catalog); err != nil {
+		return nil, err
+	}
+	if len(catalog) == 0 {
+		return nil, fmt.Errorf("catalog %s has no products", filePath)
+	}
+	return catalog, nil
+}
+
+// generateBaskets builds the checkout events of random baskets taken from the catalog along with
+// the ground truth, the same seed always generates the same baskets
+func generateBaskets(settings GeneratorSettings, catalog []CatalogProduct) (CheckoutEvents, GroundTruth, error) {
+	generator := basketGenerator{
+		settings:  settings,
+		catalog:   catalog,
+		rng:       rand.New(rand.NewSource(settings.Seed)),
+		prices:    make(map[string]float64),
+		epcSerial: firstEPCSerial,
+	}
+	truth := GroundTruth{Seed: settings.Seed}
+
+	for basketIndex := 0; basketIndex < settings.Baskets; basketIndex++ {
+		basket, err := generator.generateBasket(fmt.Sprintf("gen-%d-%03d", settings.Seed, basketIndex))
+		if err != nil {
+			return CheckoutEvents{}, GroundTruth{}, err
+		}
+		truth.Baskets = append(truth.Baskets, basket)
+	}
+
+	return CheckoutEvents{Events: generator.events}, truth, nil
+}
+
+func (generator *basketGenerator) generateBasket(basketID string) (BasketTruth, error) {
+	basket := BasketTruth{BasketID: basketID}
+	generator.addPOSEvent(basketOpenEvent, basketID)
+
+	scaleTotal := 0.0
+	itemCount := generator.settings.MinItems + generator.rng.Intn(generator.settings.MaxItems-generator.settings.MinItems+1)
+	for itemIndex := 0; itemIndex < itemCount; itemIndex++ {
+		product := generator.catalog[generator.rng.Intn(len(generator.catalog))]
+		item, err := generator.newItem(product)
+		if err != nil {
+			return basket, err
+		}
+
+		item.Loss = generator.pickLoss(product)
+		switch item.Loss {
+		case unscannedRFIDLoss:
+			// the tagged item stays hidden in the cart, only the RFID reader sees it leave
+			generator.addRFIDEvents(item.EPCs, "Bagging")
+		case skipScanLoss:
+			generator.addCVEvent(item.ProductName, item.Quantity, roiActionEntered)
+			generator.addRFIDEvents(item.EPCs, "Bagging")
+			scaleTotal = generator.addScaleEvent(scaleTotal, item.Weights)
+			generator.addCVEvent(item.ProductName, item.Quantity, roiActionExited)
+		default:
+			scanned := product
+			if item.Loss == ticketSwitchLoss {
+				scanned = generator.pickOtherProduct(product)
+			}
+			item.ScannedAs = scanned.Barcode
+
+			generator.addCVEvent(item.ProductName, item.Quantity, roiActionEntered)
+			generator.addScannedItemEvent(basketID, scanned, item.Quantity)
+			generator.addRFIDEvents(item.EPCs, "Bagging")
+			scaleTotal = generator.addScaleEvent(scaleTotal, item.Weights)
+			generator.addCVEvent(item.ProductName, item.Quantity, roiActionExited)
+		}
+
+		basket.Items = append(basket.Items, item)
+	}
+
+	generator.addPOSEvent("payment-start", basketID)
+	generator.addPOSEvent("payment-success", basketID)
+	generator.addPOSEvent(basketCloseEvent, basketID)
+	return basket, nil
+}
+
+func (generator *basketGenerator) newItem(product CatalogProduct) (ItemTruth, error) {
+	item := ItemTruth{
+		ProductID:   product.Barcode,
+		ProductName: product.Name,
+		Quantity:    1 + generator.rng.Intn(generator.settings.MaxQuantity),
+		UnitPrice:   generator.priceOf(product),
+	}
+
+	for unit := 0; unit < item.Quantity; unit++ {
+		weight := product.MinWeight + generator.rng.Float64()*(product.MaxWeight-product.MinWeight)
+		item.Weights = append(item.Weights, roundTo(weight, 3))
+
+		if product.RFIDEligible {
+			epc, err := encodeSGTIN96(product.Barcode, generator.epcSerial)
+			if err != nil {
+				return item, err
+			}
+			generator.epcSerial++
+			item.EPCs = append(item.EPCs, epc)
+		}
+	}
+	return item, nil
+}
+
+// pickLoss draws the loss behaviour of an item, an unscanned RFID item requires a tagged product
+func (generator *basketGenerator) pickLoss(product CatalogProduct) string {
+	draw := generator.rng.Float64()
+	settings := generator.settings
+
+	if draw < settings.SkipScanRate {
+		return skipScanLoss
+	}
+	draw -= settings.SkipScanRate
+
+	if draw < settings.TicketSwitchRate && len(generator.catalog) > 1 {
+		return ticketSwitchLoss
+	}
+	draw -= settings.TicketSwitchRate
+
+	if draw < settings.UnscannedRFIDRate && product.RFIDEligible {
+		return unscannedRFIDLoss
+	}
+	return ""
+}
+
+func (generator *basketGenerator) pickOtherProduct(product CatalogProduct) CatalogProduct {
+	for {
+		other := generator.catalog[generator.rng.Intn(len(generator.catalog))]
+		if other.Barcode != product.Barcode {
+			return other
+		}
+	}
+}
+
+// priceOf keeps the same price for a product across all the baskets
+func (generator *basketGenerator) priceOf(product CatalogProduct) float64 {
+	price, ok := generator.prices[product.Barcode]
+	if !ok {
+		price = roundTo(generator.settings.MinPrice+generator.rng.Float64()*(generator.settings.MaxPrice-generator.settings.MinPrice), 2)
+		generator.prices[product.Barcode] = price
+	}
+	return price
+}
+
+func (generator *basketGenerator) addEvent(device string, event string, data map[string]interface{}) {
+	generator.events = append(generator.events, CheckoutEvent{
+		Device:   device,
+		Event:    event,
+		Data:     data,
+		WaitTime: generator.settings.WaitTime,
+	})
+}
+
+func (generator *basketGenerator) addPOSEvent(event string, basketID string) {
+	generator.addEvent(posName, event, map[string]interface{}{
+		"lane_id":     generatedLaneID,
+		"basket_id":   basketID,
+		"customer_id": generatedCustomerID,
+		"employee_id": generatedEmployeeID,
+	})
+}
+
+func (generator *basketGenerator) addScannedItemEvent(basketID string, product CatalogProduct, quantity int) {
+	generator.addEvent(posName, "scanned-item", map[string]interface{}{
+		"lane_id":         generatedLaneID,
+		"basket_id":       basketID,
+		"product_id":      product.Barcode,
+		"product_id_type": "UPC",
+		"product_name":    product.Name,
+		"quantity":        float64(quantity),
+		"quantity_unit":   "EA",
+		"unit_price":      generator.priceOf(product),
+		"customer_id":     generatedCustomerID,
+		"employee_id":     generatedEmployeeID,
+	})
+}
+
+func (generator *basketGenerator) addCVEvent(productName string, quantity int, action string) {
+	generator.addEvent(cvName, "cv-roi-event", map[string]interface{}{
+		"lane_id":      generatedLaneID,
+		"object_count": quantity,
+		"product_name": productName,
+		"roi_action":   action,
+		"roi_name":     "Scanner",
+	})
+}
+
+func (generator *basketGenerator) addRFIDEvents(epcs []string, roiName string) {
+	for _, epc := range epcs {
+		generator.addEvent(rfidName, "rfid-roi-event", map[string]interface{}{
+			"lane_id":    generatedLaneID,
+			"epc":        epc,
+			"roi_name":   roiName,
+			"roi_action": roiActionEntered,
+		})
+	}
+}
+
+// addScaleEvent puts the item on the bagging scale and returns the new scale total
+func (generator *basketGenerator) addScaleEvent(total float64, weights []float64) float64 {
+	for _, weight := range weights {
+		total += weight
+	}
+	total = roundTo(total, 3)
+	generator.addEvent(scaleName, "weight", map[string]interface{}{
+		"lane_id": generatedLaneID,
+		"total":   total,
+		"units":   "lbs",
+	})
+	return total
+}
+
+// writeGeneratedBaskets writes the generated events to the output file and the ground truth
+// alongside it, i.e. baskets.json and baskets_truth.json
+func writeGeneratedBaskets(output string, checkoutEvents CheckoutEvents, truth GroundTruth) (string, error) {
+	if err := writeJSONFile(output, checkoutEvents); err != nil {
+		return "", err
+	}
+
+	truthFile := strings.TrimSuffix(output, filepath.Ext(output)) + truthFileSuffix + jsonFileExtension
+	if err := writeJSONFile(truthFile, truth); err != nil {
+		return "", err
+	}
+	return truthFile, nil
+}
+
+func writeJSONFile(filePath string, value interface{}) error {
+	contents, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filePath, contents, 0644)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
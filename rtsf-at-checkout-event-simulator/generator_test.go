@@ -0,0 +1,147 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCatalog = "../rtsf-at-checkout-product-lookup/db_initialization/all-products.json"
+
+func TestEncodeSGTIN96(t *testing.T) {
+	// values from the product lookup upc-to-epc table
+	tests := []struct {
+		gtin14 string
+		epc    string
+	}{
+		{gtin14: "00000000324588", epc: "30140000001FB28000003039"},
+		{gtin14: "00000000571111", epc: "301400000037C5C000003039"},
+		{gtin14: "00000000941969", epc: "30140000005BFD0000003039"},
+		{gtin14: "00000000019293", epc: "301400000001E24000003039"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.gtin14, func(t *testing.T) {
+			epc, err := encodeSGTIN96(tt.gtin14, firstEPCSerial)
+			require.NoError(t, err)
+			assert.Equal(t, tt.epc, epc)
+		})
+	}
+
+	_, err := encodeSGTIN96("324588", firstEPCSerial)
+	assert.Error(t, err)
+}
+
+func getTestGeneratorSettings(seed int64) GeneratorSettings {
+	return GeneratorSettings{
+		Catalog:     testCatalog,
+		Seed:        seed,
+		Baskets:     3,
+		MinItems:    2,
+		MaxItems:    6,
+		MaxQuantity: 3,
+		MinPrice:    0.99,
+		MaxPrice:    19.99,
+		WaitTime:    "1s",
+	}
+}
+
+func TestGenerateBaskets(t *testing.T) {
+	catalog, err := loadCatalog(testCatalog)
+	require.NoError(t, err)
+
+	events, truth, err := generateBaskets(getTestGeneratorSettings(7), catalog)
+	require.NoError(t, err)
+	sameEvents, sameTruth, err := generateBaskets(getTestGeneratorSettings(7), catalog)
+	require.NoError(t, err)
+	assert.Equal(t, events, sameEvents, "same seed must generate the same events")
+	assert.Equal(t, truth, sameTruth, "same seed must generate the same ground truth")
+
+	otherEvents, _, err := generateBaskets(getTestGeneratorSettings(8), catalog)
+	require.NoError(t, err)
+	assert.NotEqual(t, events, otherEvents)
+
+	require.Len(t, truth.Baskets, 3)
+	products := make(map[string]CatalogProduct)
+	for _, product := range catalog {
+		products[product.Barcode] = product
+	}
+	for _, basket := range truth.Baskets {
+		for _, item := range basket.Items {
+			product := products[item.ProductID]
+			assert.Equal(t, item.ProductID, item.ScannedAs, "no loss injected")
+			require.Len(t, item.Weights, item.Quantity)
+			for _, weight := range item.Weights {
+				assert.GreaterOrEqual(t, weight, product.MinWeight-0.001)
+				assert.LessOrEqual(t, weight, product.MaxWeight+0.001)
+			}
+			if product.RFIDEligible {
+				assert.Len(t, item.EPCs, item.Quantity)
+			} else {
+				assert.Empty(t, item.EPCs)
+			}
+		}
+	}
+}
+
+func TestGenerateBaskets_Losses(t *testing.T) {
+	catalog, err := loadCatalog(testCatalog)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		settings     func(*GeneratorSettings)
+		expectedLoss string
+	}{
+		{name: "skip scan", settings: func(s *GeneratorSettings) { s.SkipScanRate = 1 }, expectedLoss: skipScanLoss},
+		{name: "ticket switch", settings: func(s *GeneratorSettings) { s.TicketSwitchRate = 1 }, expectedLoss: ticketSwitchLoss},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := getTestGeneratorSettings(11)
+			tt.settings(&settings)
+			events, truth, err := generateBaskets(settings, catalog)
+			require.NoError(t, err)
+
+			scanned := 0
+			for _, event := range events.Events {
+				if event.Event == "scanned-item" {
+					scanned++
+				}
+			}
+
+			items := 0
+			for _, basket := range truth.Baskets {
+				for _, item := range basket.Items {
+					items++
+					assert.Equal(t, tt.expectedLoss, item.Loss)
+					if tt.expectedLoss == ticketSwitchLoss {
+						assert.NotEqual(t, item.ProductID, item.ScannedAs)
+					} else {
+						assert.Empty(t, item.ScannedAs)
+					}
+				}
+			}
+			if tt.expectedLoss == skipScanLoss {
+				assert.Zero(t, scanned)
+			} else {
+				assert.Equal(t, items, scanned)
+			}
+		})
+	}
+}
+
+func TestWriteGeneratedBaskets(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "baskets.json")
+	truthFile, err := writeGeneratedBaskets(output, CheckoutEvents{}, GroundTruth{Seed: 1})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(filepath.Dir(output), "baskets_truth.json"), truthFile)
+	assert.FileExists(t, truthFile)
+
+	_, err = loadCheckoutEvents(output)
+	require.NoError(t, err)
+}
@@ -1,3 +1,17 @@
 module event-simulator
 
 go 1.21
+
+require (
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/gorilla/websocket v1.5.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	golang.org/x/net v0.23.0 // indirect
+	golang.org/x/sync v0.2.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,310 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// laneEventKey identifies an event sent on a lane, the event time is unique per lane
+type laneEventKey struct {
+	laneID    string
+	resource  string
+	eventTime int64
+}
+
+// loadStats collects the send times and end-to-end latencies of a load run
+type loadStats struct {
+	mu        sync.Mutex
+	sent      map[laneEventKey]time.Time
+	latencies []time.Duration
+	sendCount int
+	errCount  int
+	unmatched int
+	// rejected is the number of events the reconciler rejected during the run, -1 when unknown
+	rejected int64
+}
+
+func newLoadStats() *loadStats {
+	return &loadStats{sent: make(map[laneEventKey]time.Time), rejected: -1}
+}
+
+// recordSent is called before the event is published, its reconciler output may arrive before
+// the publish returns
+func (stats *loadStats) recordSent(key laneEventKey, sentTime time.Time) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	stats.sendCount++
+	stats.sent[key] = sentTime
+}
+
+func (stats *loadStats) recordSendError(key laneEventKey) {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	stats.errCount++
+	delete(stats.sent, key)
+}
+
+// recordOutput matches a reconciler state message with the event that produced it
+func (stats *loadStats) recordOutput(message []byte, received time.Time) {
+	state := struct {
+		LastEvent struct {
+			Resource  string `json:"resource"`
+			LaneID    string `json:"lane_id"`
+			EventTime int64  `json:"event_time"`
+		} `json:"last_event"`
+	}{}
+	if err := json.Unmarshal(message, &state); err != nil {
+		return
+	}
+	key := laneEventKey{laneID: state.LastEvent.LaneID, resource: state.LastEvent.Resource, eventTime: state.LastEvent.EventTime}
+
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	sentTime, ok := stats.sent[key]
+	if !ok {
+		stats.unmatched++
+		return
+	}
+	delete(stats.sent, key)
+	stats.latencies = append(stats.latencies, received.Sub(sentTime))
+}
+
+// awaitOutput waits for the reconciler output of the event, up to the timeout whatever the clock
+func (stats *loadStats) awaitOutput(key laneEventKey, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		stats.mu.Lock()
+		_, pending := stats.sent[key]
+		stats.mu.Unlock()
+		if !pending {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// percentile returns the nearest-rank percentile of the sorted latencies
+func percentile(sorted []time.Duration, rank float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(math.Ceil(rank/100*float64(len(sorted)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return sorted[index]
+}
+
+func (stats *loadStats) report(lanes int, speed float64, elapsed time.Duration) string {
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	sorted := append([]time.Duration{}, stats.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	report := fmt.Sprintf("Load: %d lanes at %gx speed in %v\n", lanes, speed, elapsed.Round(time.Millisecond))
+	report += fmt.Sprintf("  events sent: %d, send errors: %d, throughput: %.1f events/s\n",
+		stats.sendCount, stats.errCount, float64(stats.sendCount)/elapsed.Seconds())
+	report += fmt.Sprintf("  reconciler outputs matched: %d, unmatched: %d, missing: %d\n",
+		len(stats.latencies), stats.unmatched, len(stats.sent))
+	if stats.rejected >= 0 {
+		report += fmt.Sprintf("  events rejected by the reconciler: %d\n", stats.rejected)
+	}
+	if len(sorted) > 0 {
+		report += fmt.Sprintf("  latency of the matched outputs p50: %v, p90: %v, p95: %v, p99: %v, max: %v\n",
+			percentile(sorted, 50), percentile(sorted, 90), percentile(sorted, 95), percentile(sorted, 99), sorted[len(sorted)-1])
+	}
+	return report
+}
+
+// rejectedCount reads the number of events rejected by the reconciler from its checkout state endpoint
+func rejectedCount(checkoutStateEndpoint string) (int64, error) {
+	client := http.Client{Timeout: stateRequestTimeout}
+	resp, err := client.Get(checkoutStateEndpoint)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read the reconciler checkout state: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("reconciler checkout state returns status of %d", resp.StatusCode)
+	}
+	checkoutState := struct {
+		RejectedCount int64 `json:"rejected_count"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&checkoutState); err != nil {
+		return 0, err
+	}
+	return checkoutState.RejectedCount, nil
+}
+
+// laneOverrides gives each lane its own lane_id and basket ids
+func laneOverrides(chkoutEvt CheckoutEvent, laneID string) map[string]interface{} {
+	overrides := map[string]interface{}{"lane_id": laneID}
+	if data, ok := chkoutEvt.Data.(map[string]interface{}); ok {
+		if basketID, ok := data["basket_id"].(string); ok {
+			overrides["basket_id"] = basketID + "-lane-" + laneID
+		}
+	}
+	return overrides
+}
+
+// runLane sends the events of a lane one after the other so that they arrive in order,
+// the wait times are divided by the speed. The reconciler keeps a single checkout state, so a lane
+// holds the basket turn from its basket-open until the reconciler output of its basket-close.
+func runLane(laneID string, events []CheckoutEvent, speed float64, stats *loadStats, basketTurn *sync.Mutex, outputTimeout time.Duration) error {
+	holdingTurn := false
+	defer func() {
+		if holdingTurn {
+			basketTurn.Unlock()
+		}
+	}()
+
+	lastEventTime := time.Time{}
+	for _, chkoutEvt := range events {
+		if chkoutEvt.Device == posName && chkoutEvt.Event == basketOpenEvent && !holdingTurn {
+			basketTurn.Lock()
+			holdingTurn = true
+		}
+
+		waitTime, err := time.ParseDuration(chkoutEvt.WaitTime)
+		if err != nil {
+			return err
+		}
+
+		// event times have a millisecond resolution and identify the event on the lane
+		eventTime := clock.Now().Truncate(time.Millisecond)
+		if !eventTime.After(lastEventTime) {
+			eventTime = lastEventTime.Add(time.Millisecond)
+		}
+		lastEventTime = eventTime
+
+		laneEvent := chkoutEvt
+		payload, err := laneEvent.buildPayload(eventTime, laneOverrides(chkoutEvt, laneID))
+		if err != nil {
+			return err
+		}
+
+		key := laneEventKey{laneID: laneID, resource: chkoutEvt.Event, eventTime: eventTime.UnixNano() / int64(time.Millisecond)}
+		sentTime := clock.Now()
+		stats.recordSent(key, sentTime)
+		if err := publisher.publish(&laneEvent, payload); err != nil {
+			stats.recordSendError(key)
+		}
+
+		if chkoutEvt.Device == posName && chkoutEvt.Event == basketCloseEvent && holdingTurn {
+			stats.awaitOutput(key, outputTimeout)
+			basketTurn.Unlock()
+			holdingTurn = false
+		}
+
+		clock.Sleep(time.Duration(float64(waitTime)/speed) - clock.Now().Sub(sentTime))
+	}
+	return nil
+}
+
+// checkBasketsClosed makes sure every basket-open of the events is followed by a basket-close, the lanes
+// take turns between the two and the next lane could not open its basket on the reconciler otherwise
+func checkBasketsClosed(events []CheckoutEvent) error {
+	open := false
+	for _, chkoutEvt := range events {
+		if chkoutEvt.Device != posName {
+			continue
+		}
+		switch chkoutEvt.Event {
+		case basketOpenEvent:
+			open = true
+		case basketCloseEvent:
+			open = false
+		}
+	}
+	if open {
+		return fmt.Errorf("load mode requires every basket-open to be followed by a basket-close, the reconciler keeps a single checkout state")
+	}
+	return nil
+}
+
+// runLoad runs the events of the scripts on several lanes at the same time, the baskets of the lanes
+// taking turns, and reports the throughput, the end-to-end latency measured from the reconciler output
+// and the events the reconciler rejected
+func runLoad(files []string, lanes int, speed float64, outputGrace time.Duration) (string, error) {
+	if lanes < 1 || speed <= 0 {
+		return "", fmt.Errorf("load mode requires at least one lane and a positive speed")
+	}
+
+	events := []CheckoutEvent{}
+	for _, filePath := range files {
+		checkoutEvents, err := loadCheckoutEvents(filePath)
+		if err != nil {
+			return "", err
+		}
+		events = append(events, checkoutEvents.Events...)
+	}
+	if err := checkBasketsClosed(events); err != nil {
+		return "", err
+	}
+
+	stats := newLoadStats()
+	output, err := subscribeReconcilerOutput(configuration.ReconcilerWebsocketEndpoint, "", stats.recordOutput)
+	if err != nil {
+		return "", err
+	}
+	defer output.close()
+
+	rejectedBefore := int64(0)
+	checkoutStateEndpoint := configuration.ReconcilerCheckoutStateEndpoint
+	if len(checkoutStateEndpoint) > 0 {
+		if rejectedBefore, err = rejectedCount(checkoutStateEndpoint); err != nil {
+			return "", err
+		}
+	}
+
+	start := clock.Now()
+	var wg sync.WaitGroup
+	var basketTurn sync.Mutex
+	laneErrors := make(chan error, lanes)
+	for lane := 1; lane <= lanes; lane++ {
+		wg.Add(1)
+		go func(laneID string) {
+			defer wg.Done()
+			if err := runLane(laneID, events, speed, stats, &basketTurn, outputGrace); err != nil {
+				laneErrors <- fmt.Errorf("lane %s: %v", laneID, err)
+			}
+		}(strconv.Itoa(lane))
+	}
+	wg.Wait()
+	elapsed := clock.Now().Sub(start)
+	close(laneErrors)
+
+	if err := <-laneErrors; err != nil {
+		return "", err
+	}
+
+	// leave time for the last reconciler outputs to arrive over the network, whatever the clock
+	time.Sleep(outputGrace)
+	if len(checkoutStateEndpoint) > 0 {
+		rejectedAfter, err := rejectedCount(checkoutStateEndpoint)
+		if err != nil {
+			return "", err
+		}
+		stats.mu.Lock()
+		stats.rejected = rejectedAfter - rejectedBefore
+		stats.mu.Unlock()
+	}
+	return stats.report(lanes, speed, elapsed), nil
+}
@@ -0,0 +1,142 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoReconciler stands in for the reconciler, it answers every published event
+// with a state message identifying that event on its websocket
+type echoReconciler struct {
+	mu     sync.Mutex
+	conns  chan *websocket.Conn
+	conn   *websocket.Conn
+	events map[string][]string
+	// order lists the lanes of the events in the order they were published
+	order []string
+}
+
+func (reconciler *echoReconciler) publish(chkoutEvt *CheckoutEvent, payload []byte) error {
+	data := struct {
+		LaneID    string `json:"lane_id"`
+		BasketID  string `json:"basket_id"`
+		EventTime int64  `json:"event_time"`
+	}{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return err
+	}
+
+	reconciler.mu.Lock()
+	defer reconciler.mu.Unlock()
+	if reconciler.conn == nil {
+		reconciler.conn = <-reconciler.conns
+	}
+	reconciler.events[data.LaneID] = append(reconciler.events[data.LaneID], chkoutEvt.Event+" "+data.BasketID)
+	reconciler.order = append(reconciler.order, data.LaneID)
+
+	message := fmt.Sprintf(`{"last_event": {"resource": "%s", "lane_id": "%s", "event_time": %d}}`, chkoutEvt.Event, data.LaneID, data.EventTime)
+	return reconciler.conn.WriteMessage(websocket.TextMessage, []byte(message))
+}
+
+func (reconciler *echoReconciler) close() {}
+
+func TestRunLoad(t *testing.T) {
+	reconciler := &echoReconciler{conns: make(chan *websocket.Conn, 1), events: make(map[string][]string)}
+	upgrader := websocket.Upgrader{}
+	// the reconciler rejected 2 events before the run and 1 during the run
+	rejectedCounts := []int{2, 3}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/checkout-state" {
+			fmt.Fprintf(w, `{"state": "idle", "rejected_count": %d}`, rejectedCounts[0])
+			rejectedCounts = rejectedCounts[1:]
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		reconciler.conns <- conn
+	}))
+	defer server.Close()
+
+	originalConfiguration, originalPublisher := configuration, publisher
+	defer func() {
+		configuration, publisher = originalConfiguration, originalPublisher
+	}()
+	configuration.ReconcilerWebsocketEndpoint = "ws" + strings.TrimPrefix(server.URL, "http")
+	configuration.ReconcilerCheckoutStateEndpoint = server.URL + "/checkout-state"
+	publisher = reconciler
+
+	// 100x speed turns the wait times of the script into a few milliseconds
+	report, err := runLoad([]string{"tests/rttl_scale_reconciliation/DemoTestCase_1.json"}, 3, 100, 200*time.Millisecond)
+	require.NoError(t, err)
+
+	assert.Contains(t, report, "Load: 3 lanes at 100x speed")
+	assert.Contains(t, report, "events sent: 24, send errors: 0")
+	assert.Contains(t, report, "reconciler outputs matched: 24, unmatched: 0, missing: 0")
+	assert.Contains(t, report, "events rejected by the reconciler: 1")
+	assert.Contains(t, report, "latency of the matched outputs p50:")
+
+	require.Len(t, reconciler.events, 3)
+	for _, laneID := range []string{"1", "2", "3"} {
+		events := reconciler.events[laneID]
+		require.Len(t, events, 8)
+		assert.Equal(t, "basket-open abc-012345-def-lane-"+laneID, events[0])
+		assert.Equal(t, "basket-close abc-012345-def-lane-"+laneID, events[7])
+	}
+
+	// the baskets of the lanes take turns, the events of a basket are not interleaved with other lanes
+	require.Len(t, reconciler.order, 24)
+	for basket := 0; basket < 3; basket++ {
+		for _, laneID := range reconciler.order[basket*8+1 : basket*8+8] {
+			assert.Equal(t, reconciler.order[basket*8], laneID)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(latencies, 50))
+	assert.Equal(t, 99*time.Millisecond, percentile(latencies, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(latencies, 100))
+	assert.Equal(t, time.Duration(0), percentile([]time.Duration{}, 50))
+}
+
+func TestCheckBasketsClosed(t *testing.T) {
+	tests := []struct {
+		name          string
+		file          string
+		expectedError bool
+	}{
+		{name: "basket closed", file: "tests/rttl_scale_reconciliation/DemoTestCase_1.json"},
+		{name: "basket left open", file: "tests/all_events.json", expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkoutEvents, err := loadCheckoutEvents(test.file)
+			require.NoError(t, err)
+			err = checkBasketsClosed(checkoutEvents.Events)
+			if test.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
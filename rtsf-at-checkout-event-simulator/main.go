@@ -4,14 +4,16 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -24,9 +26,11 @@ const (
 	configFilename    = "config.json"
 	eventTimeLayout   = "2006-01-02 15:04:05 MST"
 	jsonFileExtension = ".json"
+	loadOutputGrace   = 2 * time.Second
 )
 
 var configuration Configuration
+var publisher eventPublisher
 
 // Configuration is the struct for all the peripheral settings/configuration
 type Configuration struct {
@@ -35,12 +39,27 @@ type Configuration struct {
 	CvRoiEndpoint   string `json:"cv_roi_endpoint"`
 	RfidRoiEndpoint string `json:"rfid_roi_endpoint"`
 
+	Transport        string `json:"transport"`
+	MQTTBroker       string `json:"mqtt_broker"`
+	MQTTClientID     string `json:"mqtt_client_id"`
+	MQTTQoS          byte   `json:"mqtt_qos"`
+	PosMQTTTopic     string `json:"pos_mqtt_topic"`
+	ScaleMQTTTopic   string `json:"scale_mqtt_topic"`
+	CvRoiMQTTTopic   string `json:"cv_roi_mqtt_topic"`
+	RfidRoiMQTTTopic string `json:"rfid_roi_mqtt_topic"`
+
+	ReconcilerWebsocketEndpoint string `json:"reconciler_websocket_endpoint"`
+	ReconcilerStateEndpoint     string `json:"reconciler_state_endpoint"`
+	// ReconcilerCheckoutStateEndpoint is read by the load mode for the number of events the reconciler rejected
+	ReconcilerCheckoutStateEndpoint string `json:"reconciler_checkout_state_endpoint"`
+
 	httpEndpoints map[string]url.URL
 }
 
 // CheckoutEvents is the struct for the collection of CheckoutEvents
 type CheckoutEvents struct {
-	Events []CheckoutEvent `json:"checkout_events"`
+	Events   []CheckoutEvent       `json:"checkout_events"`
+	Expected *ScenarioExpectations `json:"expected"`
 }
 
 // CheckoutEvent is the struct for a checkout event at point of sale system
@@ -49,11 +68,20 @@ type CheckoutEvent struct {
 	Event     string      `json:"event"`
 	Data      interface{} `json:"data"`
 	WaitTime  string      `json:"wait_time"`
-	EventTime *time.Time
+	EventTime *time.Time  `json:"-"`
 }
 
 type commandlineFlags struct {
 	eventJSONFileFlag *string
+	scenarioDirFlag   *string
+	transportFlag     *string
+	generatorFlag     *string
+	lanesFlag         *int
+	speedFlag         *float64
+	replayFlag        *string
+	laneFlag          *string
+	fromFlag          *string
+	toFlag            *string
 }
 
 func init() {
@@ -106,58 +134,72 @@ func (chkoutEvt *CheckoutEvent) wait() time.Duration {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
-	time.Sleep(duration)
+	clock.Sleep(duration)
 	return duration
 }
 
-func (chkoutEvt *CheckoutEvent) send(eventTime time.Time) (waitTime time.Duration, err error) {
+// send publishes the event and waits for the wait time of the event. The event is published in the
+// background unless inOrder is set, in which case it is published before the wait so that the events
+// reach the reconciler in the order they are sent.
+func (chkoutEvt *CheckoutEvent) send(eventTime time.Time, inOrder bool) (waitTime time.Duration, err error) {
 	fmt.Printf("Checkout event: %v\n", chkoutEvt)
 	fmt.Printf("Event time: [%v]\n", eventTime.Format(eventTimeLayout))
 
-	chkoutEvt.EventTime = &eventTime
-
-	var chkoutEvtData map[string]interface{}
-	payload, err := json.Marshal(chkoutEvt.Data)
+	payload, err := chkoutEvt.buildPayload(eventTime, nil)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
 
-	err = json.Unmarshal(payload, &chkoutEvtData)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
-	}
-
-	chkoutEvtData["event_time"] = eventTime.UnixNano() / int64(time.Millisecond)
-
-	payload, err = json.Marshal(chkoutEvtData)
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
-	}
+	publish := func() {
+		publishErr := publisher.publish(chkoutEvt, payload)
 
-	go func() {
-		resp, postErr := http.Post(chkoutEvt.buildHTTPEndpoint(), "application/json", bytes.NewBuffer(payload))
+		if errors.Is(publishErr, errUnexpectedStatus) {
+			fmt.Println(publishErr)
+			os.Exit(-1)
+		}
 
-		if postErr != nil {
-			fmt.Printf("Warning - %s data not sent: %v\n", chkoutEvt.Device, postErr)
+		if publishErr != nil {
+			fmt.Printf("Warning - %s data not sent: %v\n", chkoutEvt.Device, publishErr)
 			// ignore the error for now to keep going
 			return
 		}
 
-		if resp.StatusCode != http.StatusOK {
-			fmt.Printf("Post returns status of %d\n", resp.StatusCode)
-			os.Exit(-1)
-		}
-
 		fmt.Printf("Event %s - %s sent\n", chkoutEvt.Device, chkoutEvt.Event)
-	}()
+	}
+	if inOrder {
+		publish()
+	} else {
+		go publish()
+	}
 
 	waitTime = chkoutEvt.wait()
 	return waitTime, nil
 }
 
+// buildPayload adds the event time to the event data, along with the fields to override
+func (chkoutEvt *CheckoutEvent) buildPayload(eventTime time.Time, overrides map[string]interface{}) ([]byte, error) {
+	chkoutEvt.EventTime = &eventTime
+
+	var chkoutEvtData map[string]interface{}
+	payload, err := json.Marshal(chkoutEvt.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(payload, &chkoutEvtData)
+	if err != nil {
+		return nil, err
+	}
+
+	for field, value := range overrides {
+		chkoutEvtData[field] = value
+	}
+	chkoutEvtData["event_time"] = eventTime.UnixNano() / int64(time.Millisecond)
+
+	return json.Marshal(chkoutEvtData)
+}
+
 func (chkoutEvt *CheckoutEvent) buildHTTPEndpoint() string {
 	url := configuration.httpEndpoints[chkoutEvt.Device]
 	url.Path = path.Join(url.Path, chkoutEvt.Event)
@@ -183,53 +225,238 @@ func loadCheckoutEvents(filePath string) (CheckoutEvents, error) {
 	return events, nil
 }
 
-func processCommandLineFlags() (cmdlineFlags commandlineFlags) {
-	cmdlineFlags.eventJSONFileFlag = flag.String("f", "tests/all_events.json", "Specify the JSON script file path for events; it will use the default value if omitted.")
-	flag.Bool("h", false, "Print the usage of flags")
-	flag.Bool("help", false, "Print the usage of flags")
-	flag.Parse()
-
-	usagePrint := false
-	if len(os.Args) > 1 {
-		flags := os.Args[1]
-		switch strings.ToLower(flags) {
-		case "-f":
-			if !strings.HasSuffix(strings.ToLower(*cmdlineFlags.eventJSONFileFlag), jsonFileExtension) {
-				fmt.Printf("Events script file should end with %s as the file extension\n", jsonFileExtension)
-				os.Exit(-1)
-			}
-		case "-h":
-			usagePrint = true
-		case "--help":
-			usagePrint = true
-		default:
-			usagePrint = true
+// parseCommandLineFlags parses the flags of the arguments, in any order
+func parseCommandLineFlags(args []string, output io.Writer) (cmdlineFlags commandlineFlags, err error) {
+	flagSet := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	flagSet.SetOutput(output)
+	cmdlineFlags.eventJSONFileFlag = flagSet.String("f", "tests/all_events.json", "Specify the JSON script file path for events; it will use the default value if omitted.")
+	cmdlineFlags.scenarioDirFlag = flagSet.String("d", "", "Specify a directory of JSON scenario files to run one after the other as a regression suite.")
+	cmdlineFlags.transportFlag = flagSet.String("t", "", "Specify the transport used to send the events, rest or mqtt; the transport of config.json is used if omitted.")
+	cmdlineFlags.generatorFlag = flagSet.String("g", "", "Specify the JSON settings file of the randomized basket generator; the generated events are written with their ground truth and then sent.")
+	cmdlineFlags.lanesFlag = flagSet.Int("l", 0, "Specify the number of lanes to run the events on concurrently in load mode; the throughput and latency are reported instead of checking scenarios.")
+	cmdlineFlags.speedFlag = flagSet.Float64("x", 1, "Specify the speed of the load and replay modes, i.e. 10 divides the wait times by 10.")
+	cmdlineFlags.replayFlag = flagSet.String("r", "", "Specify an NDJSON capture file recorded by the reconciler to replay with its original timing, accelerated by the -x speed.")
+	cmdlineFlags.laneFlag = flagSet.String("lane", "", "Specify the lane_id of the captured events to replay; all lanes are replayed if omitted.")
+	cmdlineFlags.fromFlag = flagSet.String("from", "", "Specify the RFC3339 time of the first captured event to replay.")
+	cmdlineFlags.toFlag = flagSet.String("to", "", "Specify the RFC3339 time of the last captured event to replay.")
+
+	// the flag set reports the parse errors along with the usage of the flags
+	if err := flagSet.Parse(args); err != nil {
+		return cmdlineFlags, err
+	}
+	switch {
+	case flagSet.NArg() > 0:
+		err = fmt.Errorf("unexpected argument %s", flagSet.Arg(0))
+	case !strings.HasSuffix(strings.ToLower(*cmdlineFlags.eventJSONFileFlag), jsonFileExtension):
+		err = fmt.Errorf("events script file should end with %s as the file extension", jsonFileExtension)
+	}
+	if err != nil {
+		fmt.Fprintln(output, err)
+		flagSet.Usage()
+	}
+	return cmdlineFlags, err
+}
+
+func processCommandLineFlags() commandlineFlags {
+	cmdlineFlags, err := parseCommandLineFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		os.Exit(-1)
+	}
+	return cmdlineFlags
+}
+
+// scenarioFiles returns the scenario files to run, either the single file or all the JSON files of the directory
+func scenarioFiles(cmdlineFlags commandlineFlags) ([]string, error) {
+	if len(*cmdlineFlags.generatorFlag) > 0 {
+		return generateScenario(*cmdlineFlags.generatorFlag)
+	}
+
+	if len(*cmdlineFlags.scenarioDirFlag) == 0 {
+		return []string{*cmdlineFlags.eventJSONFileFlag}, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(*cmdlineFlags.scenarioDirFlag, "*"+jsonFileExtension))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no scenario files found in %s", *cmdlineFlags.scenarioDirFlag)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// generateScenario generates random baskets and returns the file the events were written to
+func generateScenario(settingsFile string) ([]string, error) {
+	settings, err := loadGeneratorSettings(settingsFile)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := loadCatalog(settings.Catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	checkoutEvents, truth, err := generateBaskets(settings, catalog)
+	if err != nil {
+		return nil, err
+	}
+	truthFile, err := writeGeneratedBaskets(settings.Output, checkoutEvents, truth)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Generated %d baskets with seed %d into [%s], ground truth in [%s]\n", len(truth.Baskets), truth.Seed, settings.Output, truthFile)
+	return []string{settings.Output}, nil
+}
+
+// runScenario sends the events of the scenario and returns the differences between
+// the expected and the actual reconciler state
+func runScenario(filePath string) ([]string, error) {
+	checkoutEvents, err := loadCheckoutEvents(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return runCheckoutEvents(checkoutEvents, false)
+}
+
+// runCheckoutEvents sends the events, one after the other when inOrder is set, and returns the
+// differences between the expected and the actual reconciler state
+func runCheckoutEvents(checkoutEvents CheckoutEvents, inOrder bool) ([]string, error) {
+	var output *reconcilerOutput
+	var err error
+	if !checkoutEvents.Expected.isEmpty() {
+		output, err = subscribeReconcilerOutput(configuration.ReconcilerWebsocketEndpoint, configuration.ReconcilerStateEndpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer output.close()
+	}
+
+	diffs := []string{}
+	// set the start time clock and use that as base to calculate event time
+	eventTime := clock.Now()
+	for _, checkoutEvent := range checkoutEvents.Events {
+		waitTime, err := checkoutEvent.send(eventTime, inOrder)
+		if err != nil {
+			return nil, err
 		}
+		eventTime = eventTime.Add(waitTime)
 
-		if usagePrint {
-			flag.PrintDefaults()
-			os.Exit(0)
+		if output == nil || checkoutEvents.Expected.PaymentStart == nil ||
+			checkoutEvent.Device != posName || checkoutEvent.Event != paymentStartEvent {
+			continue
+		}
+		state, err := output.latestState()
+		if err != nil {
+			return nil, err
+		}
+		for _, diff := range compareState(checkoutEvents.Expected.PaymentStart, state) {
+			diffs = append(diffs, "payment_start "+diff)
 		}
 	}
-	return cmdlineFlags
+
+	if output != nil && checkoutEvents.Expected.FinalState != nil {
+		state, err := output.currentState()
+		if err != nil {
+			return nil, err
+		}
+		for _, diff := range compareState(checkoutEvents.Expected.FinalState, state) {
+			diffs = append(diffs, "final_state "+diff)
+		}
+	}
+
+	return diffs, nil
+}
+
+// replayCapture sends the captured events selected by the command line flags
+func replayCapture(cmdlineFlags commandlineFlags) error {
+	filter := ReplayFilter{LaneID: *cmdlineFlags.laneFlag}
+	var err error
+	if len(*cmdlineFlags.fromFlag) > 0 {
+		if filter.From, err = time.Parse(time.RFC3339, *cmdlineFlags.fromFlag); err != nil {
+			return err
+		}
+	}
+	if len(*cmdlineFlags.toFlag) > 0 {
+		if filter.To, err = time.Parse(time.RFC3339, *cmdlineFlags.toFlag); err != nil {
+			return err
+		}
+	}
+
+	checkoutEvents, err := loadCapture(*cmdlineFlags.replayFlag, filter, *cmdlineFlags.speedFlag)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replaying %d captured events\n", len(checkoutEvents.Events))
+
+	// the captured events often follow each other closely, they are published in the captured order
+	_, err = runCheckoutEvents(checkoutEvents, true)
+	return err
 }
 
 func main() {
 	cmdlineFlags := processCommandLineFlags()
-	checkoutEvents, err := loadCheckoutEvents(*cmdlineFlags.eventJSONFileFlag)
+	files, err := scenarioFiles(cmdlineFlags)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
-	// set the start time clock and use that as base to calculate event time
-	eventTime := time.Now()
-	for _, checkoutEvent := range checkoutEvents.Events {
-		waitTime, err := checkoutEvent.send(eventTime)
+
+	if len(*cmdlineFlags.transportFlag) > 0 {
+		configuration.Transport = *cmdlineFlags.transportFlag
+	}
+	publisher, err = newEventPublisher(configuration)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(-1)
+	}
+
+	if len(*cmdlineFlags.replayFlag) > 0 {
+		err := replayCapture(cmdlineFlags)
+		publisher.close()
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(-1)
 		}
-		eventTime = eventTime.Add(waitTime)
+		return
 	}
 
+	if *cmdlineFlags.lanesFlag > 0 {
+		report, err := runLoad(files, *cmdlineFlags.lanesFlag, *cmdlineFlags.speedFlag, loadOutputGrace)
+		publisher.close()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+		fmt.Print(report)
+		return
+	}
+
+	failed := 0
+	for _, filePath := range files {
+		diffs, err := runScenario(filePath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		if len(diffs) > 0 {
+			failed++
+			fmt.Printf("Scenario [%s] FAILED:\n", filePath)
+			for _, diff := range diffs {
+				fmt.Printf("  %s\n", diff)
+			}
+		}
+	}
+
+	publisher.close()
+
+	if failed > 0 {
+		fmt.Printf("%d of %d scenarios failed\n", failed, len(files))
+		os.Exit(1)
+	}
 }
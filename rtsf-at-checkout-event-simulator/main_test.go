@@ -0,0 +1,63 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommandLineFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectedError bool
+		expectedFile  string
+		expectedLane  string
+		expectedFrom  string
+		expectedTo    string
+		expectedSpeed float64
+	}{
+		{name: "no flags", args: []string{}, expectedFile: "tests/all_events.json", expectedSpeed: 1},
+		{name: "events file", args: []string{"-f", "tests/other.json"}, expectedFile: "tests/other.json", expectedSpeed: 1},
+		{name: "lane first", args: []string{"-lane", "2", "-r", "capture.ndjson"}, expectedFile: "tests/all_events.json", expectedLane: "2", expectedSpeed: 1},
+		{name: "time range first", args: []string{"-from", "2023-06-01T10:00:00Z", "-to", "2023-06-01T11:00:00Z", "-r", "capture.ndjson", "-x", "10"},
+			expectedFile: "tests/all_events.json", expectedFrom: "2023-06-01T10:00:00Z", expectedTo: "2023-06-01T11:00:00Z", expectedSpeed: 10},
+		{name: "events file not json", args: []string{"-f", "events.txt"}, expectedError: true},
+		{name: "unknown flag", args: []string{"-unknown"}, expectedError: true},
+		{name: "unexpected argument", args: []string{"-r", "capture.ndjson", "extra"}, expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := &bytes.Buffer{}
+			cmdlineFlags, err := parseCommandLineFlags(test.args, output)
+			if test.expectedError {
+				require.Error(t, err)
+				assert.Contains(t, output.String(), "-lane")
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, output.String())
+			assert.Equal(t, test.expectedFile, *cmdlineFlags.eventJSONFileFlag)
+			assert.Equal(t, test.expectedLane, *cmdlineFlags.laneFlag)
+			assert.Equal(t, test.expectedFrom, *cmdlineFlags.fromFlag)
+			assert.Equal(t, test.expectedTo, *cmdlineFlags.toFlag)
+			assert.Equal(t, test.expectedSpeed, *cmdlineFlags.speedFlag)
+		})
+	}
+}
+
+func TestParseCommandLineFlagsHelp(t *testing.T) {
+	for _, arg := range []string{"-h", "-help", "--help"} {
+		output := &bytes.Buffer{}
+		_, err := parseCommandLineFlags([]string{arg}, output)
+		assert.ErrorIs(t, err, flag.ErrHelp, arg)
+		assert.Contains(t, output.String(), "-lane", arg)
+	}
+}
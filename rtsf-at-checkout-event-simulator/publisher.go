@@ -0,0 +1,139 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	restTransport = "rest"
+	mqttTransport = "mqtt"
+
+	defaultMQTTClientID = "event-simulator"
+	mqttTimeout         = 5 * time.Second
+)
+
+var errUnexpectedStatus = errors.New("unexpected status")
+
+// mqttDeviceNames are the device names defined for the EdgeX MQTT device service
+var mqttDeviceNames = map[string]string{
+	posName:   "pos-mqtt",
+	scaleName: "scale-mqtt",
+	cvName:    "cv-roi-mqtt",
+	rfidName:  "rfid-roi-mqtt",
+}
+
+// eventPublisher sends the checkout event data to the EdgeX device services
+type eventPublisher interface {
+	publish(chkoutEvt *CheckoutEvent, payload []byte) error
+	close()
+}
+
+func newEventPublisher(config Configuration) (eventPublisher, error) {
+	switch config.Transport {
+	case "", restTransport:
+		return &restPublisher{}, nil
+	case mqttTransport:
+		return newMQTTPublisher(config)
+	default:
+		return nil, fmt.Errorf("transport %q is not supported, use %s or %s", config.Transport, restTransport, mqttTransport)
+	}
+}
+
+// restPublisher POSTs the events to the EdgeX REST device service
+type restPublisher struct{}
+
+func (publisher *restPublisher) publish(chkoutEvt *CheckoutEvent, payload []byte) error {
+	resp, err := http.Post(chkoutEvt.buildHTTPEndpoint(), "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: post returns status of %d", errUnexpectedStatus, resp.StatusCode)
+	}
+	return nil
+}
+
+func (publisher *restPublisher) close() {}
+
+// mqttPublisher publishes the events to the topics the EdgeX MQTT device service subscribes to
+type mqttPublisher struct {
+	client mqtt.Client
+	qos    byte
+	topics map[string]string
+}
+
+func newMQTTPublisher(config Configuration) (*mqttPublisher, error) {
+	if config.MQTTQoS > 2 {
+		return nil, fmt.Errorf("mqtt_qos must be 0, 1 or 2, got %d", config.MQTTQoS)
+	}
+
+	topics := map[string]string{
+		posName:   config.PosMQTTTopic,
+		scaleName: config.ScaleMQTTTopic,
+		cvName:    config.CvRoiMQTTTopic,
+		rfidName:  config.RfidRoiMQTTTopic,
+	}
+	for device, topic := range topics {
+		if len(topic) == 0 {
+			return nil, fmt.Errorf("mqtt topic for %s is not configured", device)
+		}
+	}
+
+	clientID := config.MQTTClientID
+	if len(clientID) == 0 {
+		clientID = defaultMQTTClientID
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(config.MQTTBroker).
+		SetClientID(clientID).
+		SetConnectTimeout(mqttTimeout).
+		SetAutoReconnect(true)
+
+	client := mqtt.NewClient(options)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttTimeout) {
+		return nil, fmt.Errorf("timed out connecting to MQTT broker %s", config.MQTTBroker)
+	}
+	if token.Error() != nil {
+		return nil, fmt.Errorf("unable to connect to MQTT broker %s: %v", config.MQTTBroker, token.Error())
+	}
+
+	return &mqttPublisher{client: client, qos: config.MQTTQoS, topics: topics}, nil
+}
+
+// publish sends the event in the format expected by the EdgeX MQTT device service,
+// where the event data is a JSON string set on the field named after the command
+func (publisher *mqttPublisher) publish(chkoutEvt *CheckoutEvent, payload []byte) error {
+	message, err := json.Marshal(map[string]string{
+		"name":          mqttDeviceNames[chkoutEvt.Device],
+		"cmd":           chkoutEvt.Event,
+		chkoutEvt.Event: string(payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	topic := publisher.topics[chkoutEvt.Device]
+	token := publisher.client.Publish(topic, publisher.qos, false, message)
+	if !token.WaitTimeout(mqttTimeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+func (publisher *mqttPublisher) close() {
+	publisher.client.Disconnect(uint(mqttTimeout.Milliseconds()))
+}
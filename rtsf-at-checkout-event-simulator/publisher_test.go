@@ -0,0 +1,153 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestBroker starts a minimal local MQTT broker which acknowledges the publish flows
+// of every QoS level and hands the published packets to the test
+func startTestBroker(t *testing.T) (string, chan *packets.PublishPacket) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+	})
+
+	received := make(chan *packets.PublishPacket, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestBrokerConn(conn, received)
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String(), received
+}
+
+func serveTestBrokerConn(conn net.Conn, received chan *packets.PublishPacket) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		var response packets.ControlPacket
+		switch p := packet.(type) {
+		case *packets.ConnectPacket:
+			response = packets.NewControlPacket(packets.Connack)
+		case *packets.PublishPacket:
+			received <- p
+			switch p.Qos {
+			case 1:
+				puback := packets.NewControlPacket(packets.Puback).(*packets.PubackPacket)
+				puback.MessageID = p.MessageID
+				response = puback
+			case 2:
+				pubrec := packets.NewControlPacket(packets.Pubrec).(*packets.PubrecPacket)
+				pubrec.MessageID = p.MessageID
+				response = pubrec
+			}
+		case *packets.PubrelPacket:
+			pubcomp := packets.NewControlPacket(packets.Pubcomp).(*packets.PubcompPacket)
+			pubcomp.MessageID = p.MessageID
+			response = pubcomp
+		case *packets.PingreqPacket:
+			response = packets.NewControlPacket(packets.Pingresp)
+		case *packets.DisconnectPacket:
+			return
+		}
+
+		if response != nil {
+			if err := response.Write(conn); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func getTestMQTTConfiguration(broker string, qos byte) Configuration {
+	return Configuration{
+		Transport:        mqttTransport,
+		MQTTBroker:       broker,
+		MQTTQoS:          qos,
+		PosMQTTTopic:     "edgex/pos",
+		ScaleMQTTTopic:   "edgex/scale",
+		CvRoiMQTTTopic:   "edgex/cv-roi",
+		RfidRoiMQTTTopic: "edgex/rfid-roi",
+	}
+}
+
+func TestMQTTPublisher_publish(t *testing.T) {
+	tests := []struct {
+		name string
+		qos  byte
+	}{
+		{name: "at most once", qos: 0},
+		{name: "at least once", qos: 1},
+		{name: "exactly once", qos: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			broker, received := startTestBroker(t)
+			publisher, err := newEventPublisher(getTestMQTTConfiguration(broker, tt.qos))
+			require.NoError(t, err)
+			defer publisher.close()
+
+			payload := []byte(`{"total":3.25,"units":"lbs","event_time":15736013940000}`)
+			err = publisher.publish(&CheckoutEvent{Device: scaleName, Event: "weight"}, payload)
+			require.NoError(t, err)
+
+			select {
+			case packet := <-received:
+				assert.Equal(t, "edgex/scale", packet.TopicName)
+				assert.Equal(t, tt.qos, packet.Qos)
+
+				message := map[string]string{}
+				require.NoError(t, json.Unmarshal(packet.Payload, &message))
+				assert.Equal(t, "scale-mqtt", message["name"])
+				assert.Equal(t, "weight", message["cmd"])
+				assert.Equal(t, string(payload), message["weight"])
+			case <-time.After(mqttTimeout):
+				t.Fatal("no message received by the broker")
+			}
+		})
+	}
+}
+
+func TestNewEventPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Configuration
+		wantErr bool
+	}{
+		{name: "default to rest", config: Configuration{}},
+		{name: "unsupported transport", config: Configuration{Transport: "amqp"}, wantErr: true},
+		{name: "invalid QoS", config: getTestMQTTConfiguration("tcp://127.0.0.1:1883", 3), wantErr: true},
+		{name: "missing topic", config: Configuration{Transport: mqttTransport, MQTTBroker: "tcp://127.0.0.1:1883"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher, err := newEventPublisher(tt.config)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			publisher.close()
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const stateRequestTimeout = 5 * time.Second
+
+// outputHandler is called for every state message received from the reconciler websocket
+type outputHandler func(message []byte, received time.Time)
+
+// reconcilerOutput keeps the latest state message the reconciler sent over its websocket
+type reconcilerOutput struct {
+	stateEndpoint string
+	conn          *websocket.Conn
+	mu            sync.Mutex
+	latest        []byte
+}
+
+// subscribeReconcilerOutput connects to the reconciler websocket, without websocket endpoint
+// the state is only read from the current state endpoint
+func subscribeReconcilerOutput(websocketEndpoint string, stateEndpoint string, handler outputHandler) (*reconcilerOutput, error) {
+	output := &reconcilerOutput{stateEndpoint: stateEndpoint}
+	if len(websocketEndpoint) == 0 {
+		return output, nil
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(websocketEndpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to the reconciler websocket %s: %v", websocketEndpoint, err)
+	}
+	output.conn = conn
+
+	go func() {
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if handler != nil {
+				handler(message, clock.Now())
+			}
+			output.mu.Lock()
+			output.latest = message
+			output.mu.Unlock()
+		}
+	}()
+
+	return output, nil
+}
+
+// latestState returns the last state message received over the websocket and
+// falls back to the current state endpoint when none was received
+func (output *reconcilerOutput) latestState() ([]byte, error) {
+	output.mu.Lock()
+	latest := output.latest
+	output.mu.Unlock()
+
+	if latest != nil {
+		return latest, nil
+	}
+	return output.currentState()
+}
+
+// currentState reads the state message from the reconciler current state endpoint
+func (output *reconcilerOutput) currentState() ([]byte, error) {
+	if len(output.stateEndpoint) == 0 {
+		return nil, fmt.Errorf("reconciler_state_endpoint is not configured")
+	}
+
+	client := http.Client{Timeout: stateRequestTimeout}
+	resp, err := client.Get(output.stateEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the reconciler current state: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("reconciler current state returns status of %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (output *reconcilerOutput) close() {
+	if output.conn != nil {
+		output.conn.Close()
+	}
+}
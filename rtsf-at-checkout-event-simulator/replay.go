@@ -0,0 +1,138 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxCaptureLineSize is the size of the largest captured event the replay accepts
+const maxCaptureLineSize = 1024 * 1024
+
+// CaptureEntry is the struct for a line of a checkout events capture file recorded by the reconciler
+type CaptureEntry struct {
+	Received time.Time    `json:"received"`
+	Event    CaptureEvent `json:"event"`
+}
+
+// CaptureEvent is the struct for the part of the captured EdgeX event needed to replay it
+type CaptureEvent struct {
+	DeviceName string           `json:"deviceName"`
+	Readings   []CaptureReading `json:"readings"`
+}
+
+// CaptureReading is the struct for the part of the captured EdgeX reading needed to replay it
+type CaptureReading struct {
+	DeviceName   string      `json:"deviceName"`
+	ResourceName string      `json:"resourceName"`
+	ObjectValue  interface{} `json:"objectValue"`
+}
+
+// ReplayFilter is the struct for the captured events to replay, empty fields do not filter
+type ReplayFilter struct {
+	LaneID string
+	From   time.Time
+	To     time.Time
+}
+
+func (filter ReplayFilter) matches(received time.Time, data map[string]interface{}) bool {
+	if !filter.From.IsZero() && received.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && received.After(filter.To) {
+		return false
+	}
+	if len(filter.LaneID) > 0 && fmt.Sprintf("%v", data["lane_id"]) != filter.LaneID {
+		return false
+	}
+	return true
+}
+
+// captureDevice maps the name of the EdgeX device of a captured reading to the simulator device
+func captureDevice(deviceName string) (string, bool) {
+	name := strings.ToLower(deviceName)
+	switch {
+	case strings.HasPrefix(name, "pos"):
+		return posName, true
+	case strings.HasPrefix(name, "cv-roi"):
+		return cvName, true
+	case strings.HasPrefix(name, "rfid-roi"):
+		return rfidName, true
+	case strings.Contains(name, "scale"):
+		return scaleName, true
+	default:
+		return "", false
+	}
+}
+
+// loadCapture turns the captured events matching the filter into checkout events, the wait times
+// reproduce the time between the events as received by the reconciler, divided by the speed
+func loadCapture(filePath string, filter ReplayFilter, speed float64) (CheckoutEvents, error) {
+	var events CheckoutEvents
+	if speed <= 0 {
+		return events, fmt.Errorf("replay speed must be positive")
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return events, err
+	}
+	defer file.Close()
+
+	fmt.Printf("Loading captured events from [%s]\n", filePath)
+
+	receivedTimes := []time.Time{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCaptureLineSize)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		entry := CaptureEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return events, fmt.Errorf("line %d of %s is not a captured event: %v", lineNumber, filePath, err)
+		}
+
+		for _, reading := range entry.Event.Readings {
+			data, ok := reading.ObjectValue.(map[string]interface{})
+			if !ok {
+				fmt.Printf("Warning - skipping %s reading of %s which is not an object\n", reading.ResourceName, reading.DeviceName)
+				continue
+			}
+			device, ok := captureDevice(reading.DeviceName)
+			if !ok {
+				fmt.Printf("Warning - skipping reading of unknown device %s\n", reading.DeviceName)
+				continue
+			}
+			if !filter.matches(entry.Received, data) {
+				continue
+			}
+
+			events.Events = append(events.Events, CheckoutEvent{Device: device, Event: reading.ResourceName, Data: data})
+			receivedTimes = append(receivedTimes, entry.Received)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return events, err
+	}
+
+	for index := range events.Events {
+		waitTime := time.Duration(0)
+		if index+1 < len(events.Events) {
+			waitTime = time.Duration(float64(receivedTimes[index+1].Sub(receivedTimes[index])) / speed)
+		}
+		if waitTime < 0 {
+			waitTime = 0
+		}
+		events.Events[index].WaitTime = waitTime.String()
+	}
+
+	return events, nil
+}
@@ -0,0 +1,132 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCapture = `{"received":"2023-06-01T10:00:00Z","event":{"deviceName":"pos-rest","readings":[{"deviceName":"pos-rest","resourceName":"basket-open","objectValue":{"lane_id":"1","basket_id":"abc-1","event_time":1}}]}}
+{"received":"2023-06-01T10:00:01Z","event":{"deviceName":"pos-rest","readings":[{"deviceName":"pos-rest","resourceName":"basket-open","objectValue":{"lane_id":"2","basket_id":"abc-2","event_time":2}}]}}
+{"received":"2023-06-01T10:00:02Z","event":{"deviceName":"scale-rest","readings":[{"deviceName":"scale-rest","resourceName":"weight","objectValue":{"lane_id":"1","total":1.5,"event_time":3}}]}}
+
+{"received":"2023-06-01T10:00:02.500Z","event":{"deviceName":"scale-rest","readings":[{"deviceName":"scale-rest","resourceName":"scale-health","value":"ok"}]}}
+{"received":"2023-06-01T10:00:04Z","event":{"deviceName":"cv-roi-mqtt","readings":[{"deviceName":"cv-roi-mqtt","resourceName":"cv-roi-event","objectValue":{"lane_id":"1","roi_action":"ENTERED","event_time":4}}]}}
+{"received":"2023-06-01T10:00:05Z","event":{"deviceName":"unknown","readings":[{"deviceName":"unknown","resourceName":"other","objectValue":{"lane_id":"1"}}]}}
+{"received":"2023-06-01T10:00:08Z","event":{"deviceName":"rfid-roi-rest","readings":[{"deviceName":"rfid-roi-rest","resourceName":"rfid-roi-event","objectValue":{"lane_id":"1","epc":"30140","event_time":5}}]}}
+`
+
+func TestLoadCapture(t *testing.T) {
+	capturePath := filepath.Join(t.TempDir(), "capture.ndjson")
+	require.NoError(t, os.WriteFile(capturePath, []byte(testCapture), 0644))
+
+	tests := []struct {
+		name      string
+		filter    ReplayFilter
+		speed     float64
+		devices   []string
+		waitTimes []string
+	}{
+		{
+			name:      "all lanes",
+			speed:     1,
+			devices:   []string{posName, posName, scaleName, cvName, rfidName},
+			waitTimes: []string{"1s", "1s", "2s", "4s", "0s"},
+		},
+		{
+			name:      "lane filter accelerated",
+			filter:    ReplayFilter{LaneID: "1"},
+			speed:     2,
+			devices:   []string{posName, scaleName, cvName, rfidName},
+			waitTimes: []string{"1s", "1s", "2s", "0s"},
+		},
+		{
+			name: "time range",
+			filter: ReplayFilter{
+				From: time.Date(2023, 6, 1, 10, 0, 1, 0, time.UTC),
+				To:   time.Date(2023, 6, 1, 10, 0, 4, 0, time.UTC),
+			},
+			speed:     1,
+			devices:   []string{posName, scaleName, cvName},
+			waitTimes: []string{"1s", "2s", "0s"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			events, err := loadCapture(capturePath, test.filter, test.speed)
+			require.NoError(t, err)
+			require.Len(t, events.Events, len(test.devices))
+			for index, chkoutEvt := range events.Events {
+				assert.Equal(t, test.devices[index], chkoutEvt.Device)
+				assert.Equal(t, test.waitTimes[index], chkoutEvt.WaitTime)
+			}
+		})
+	}
+}
+
+func TestLoadCaptureErrors(t *testing.T) {
+	capturePath := filepath.Join(t.TempDir(), "capture.ndjson")
+	require.NoError(t, os.WriteFile(capturePath, []byte("not json\n"), 0644))
+
+	_, err := loadCapture(capturePath, ReplayFilter{}, 1)
+	assert.Error(t, err)
+
+	_, err = loadCapture(capturePath, ReplayFilter{}, 0)
+	assert.Error(t, err)
+
+	_, err = loadCapture(filepath.Join(t.TempDir(), "missing.ndjson"), ReplayFilter{}, 1)
+	assert.Error(t, err)
+}
+
+// slowFirstPublisher records the devices of the published events, the first event taking longer to publish
+type slowFirstPublisher struct {
+	mu      sync.Mutex
+	calls   int
+	devices []string
+}
+
+func (slowPublisher *slowFirstPublisher) publish(chkoutEvt *CheckoutEvent, payload []byte) error {
+	slowPublisher.mu.Lock()
+	slowPublisher.calls++
+	first := slowPublisher.calls == 1
+	slowPublisher.mu.Unlock()
+	if first {
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	slowPublisher.mu.Lock()
+	defer slowPublisher.mu.Unlock()
+	slowPublisher.devices = append(slowPublisher.devices, chkoutEvt.Device)
+	return nil
+}
+
+func (slowPublisher *slowFirstPublisher) close() {}
+
+func TestReplayInOrder(t *testing.T) {
+	capturePath := filepath.Join(t.TempDir(), "capture.ndjson")
+	require.NoError(t, os.WriteFile(capturePath, []byte(testCapture), 0644))
+	checkoutEvents, err := loadCapture(capturePath, ReplayFilter{LaneID: "1"}, 1)
+	require.NoError(t, err)
+
+	slowPublisher := &slowFirstPublisher{}
+	originalClock, originalPublisher := clock, publisher
+	defer func() {
+		clock, publisher = originalClock, originalPublisher
+	}()
+	clock, publisher = newManualClock(time.Unix(1700000000, 0)), slowPublisher
+
+	_, err = runCheckoutEvents(checkoutEvents, true)
+	require.NoError(t, err)
+
+	// the slow first event is still published first
+	assert.Equal(t, []string{posName, scaleName, cvName, rfidName}, slowPublisher.devices)
+}
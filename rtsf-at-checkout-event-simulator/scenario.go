@@ -0,0 +1,139 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	basketOpenEvent   = "basket-open"
+	paymentStartEvent = "payment-start"
+	basketCloseEvent  = "basket-close"
+	floatTolerance    = 0.001
+)
+
+// ScenarioExpectations is the struct for the expected reconciler state of a scenario. Both states are
+// partial reconciler state messages, only the fields listed are compared and list items are matched
+// regardless of their order
+type ScenarioExpectations struct {
+	PaymentStart map[string]interface{} `json:"payment_start"`
+	FinalState   map[string]interface{} `json:"final_state"`
+}
+
+func (expected *ScenarioExpectations) isEmpty() bool {
+	return expected == nil || (expected.PaymentStart == nil && expected.FinalState == nil)
+}
+
+// compareState compares the expected partial state with the state message received from the reconciler
+// and returns the differences found, one per line
+func compareState(expected map[string]interface{}, message []byte) []string {
+	var actual interface{}
+	if err := json.Unmarshal(message, &actual); err != nil {
+		return []string{fmt.Sprintf("$: unable to parse reconciler state: %v", err)}
+	}
+	return compareValue("$", expected, actual)
+}
+
+func compareValue(path string, expected interface{}, actual interface{}) []string {
+	switch expectedValue := expected.(type) {
+	case map[string]interface{}:
+		actualValue, ok := actual.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %s", path, toJSON(actual))}
+		}
+		keys := make([]string, 0, len(expectedValue))
+		for key := range expectedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		diffs := []string{}
+		for _, key := range keys {
+			value, ok := actualValue[key]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("%s.%s: missing, expected %s", path, key, toJSON(expectedValue[key])))
+				continue
+			}
+			diffs = append(diffs, compareValue(path+"."+key, expectedValue[key], value)...)
+		}
+		return diffs
+
+	case []interface{}:
+		actualValue, ok := actual.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected a list, got %s", path, toJSON(actual))}
+		}
+		if len(expectedValue) != len(actualValue) {
+			return []string{fmt.Sprintf("%s: expected %d items, got %d\n    expected: %s\n    actual:   %s",
+				path, len(expectedValue), len(actualValue), toJSON(expectedValue), toJSON(actualValue))}
+		}
+		return compareUnorderedList(path, expectedValue, actualValue)
+
+	default:
+		if !equalScalar(expected, actual) {
+			return []string{fmt.Sprintf("%s: expected %s, got %s", path, toJSON(expected), toJSON(actual))}
+		}
+		return []string{}
+	}
+}
+
+// compareUnorderedList matches every expected item with a distinct actual item,
+// as the reconciler does not guarantee the order of the suspect lists
+func compareUnorderedList(path string, expected []interface{}, actual []interface{}) []string {
+	matched := make([]bool, len(actual))
+	diffs := []string{}
+	for expectedIndex, expectedItem := range expected {
+		found := false
+		for actualIndex, actualItem := range actual {
+			if matched[actualIndex] || len(compareValue(path, expectedItem, actualItem)) > 0 {
+				continue
+			}
+			matched[actualIndex] = true
+			found = true
+			break
+		}
+		if !found {
+			diffs = append(diffs, fmt.Sprintf("%s[%d]: no matching item for %s\n    actual:   %s",
+				path, expectedIndex, toJSON(expectedItem), toJSON(actual)))
+		}
+	}
+	return diffs
+}
+
+// equalScalar compares JSON values, numbers are compared with a tolerance and may be sent
+// as strings by the reconciler
+func equalScalar(expected interface{}, actual interface{}) bool {
+	expectedNumber, expectedIsNumber := toNumber(expected)
+	actualNumber, actualIsNumber := toNumber(actual)
+	if expectedIsNumber && actualIsNumber {
+		return math.Abs(expectedNumber-actualNumber) <= floatTolerance
+	}
+	return expected == actual
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+		return parsed, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func toJSON(value interface{}) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(content)
+}
@@ -0,0 +1,71 @@
+// Copyright © 2023 Intel Corporation. All rights reserved.
+// SPDX-License-Identifier: BSD-3-Clause
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStateMessage = `{
+	"positems": [{"product_name": "Red Apples", "quantity": 1.000000}],
+	"scalesuspectitems": [{"scale_id": "abc123", "total": 10.000000, "delta": 9.500000}],
+	"cvsuspectitems": [{"product_name": "Steak", "event_time": "1"}, {"product_name": "Trail Mix", "event_time": "2"}],
+	"rfidsuspectitems": [],
+	"stats": {"cv_count": "2", "rfid_count": "0", "scale_count": "1"}
+}`
+
+func TestCompareState(t *testing.T) {
+	tests := []struct {
+		name          string
+		expected      map[string]interface{}
+		expectedDiffs int
+	}{
+		{
+			name: "match in any order",
+			expected: map[string]interface{}{
+				"cvsuspectitems":   []interface{}{map[string]interface{}{"product_name": "Trail Mix"}, map[string]interface{}{"product_name": "Steak"}},
+				"rfidsuspectitems": []interface{}{},
+			},
+		},
+		{
+			name: "numbers within tolerance and sent as strings",
+			expected: map[string]interface{}{
+				"scalesuspectitems": []interface{}{map[string]interface{}{"delta": 9.5}},
+				"stats":             map[string]interface{}{"cv_count": 2.0},
+			},
+		},
+		{
+			name: "wrong number of items",
+			expected: map[string]interface{}{
+				"cvsuspectitems": []interface{}{map[string]interface{}{"product_name": "Steak"}},
+			},
+			expectedDiffs: 1,
+		},
+		{
+			name: "wrong values and missing field",
+			expected: map[string]interface{}{
+				"positems": []interface{}{map[string]interface{}{"product_name": "Red Apples", "quantity": 2.0}},
+				"missing":  true,
+			},
+			expectedDiffs: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := compareState(tt.expected, []byte(testStateMessage))
+			assert.Len(t, diffs, tt.expectedDiffs, diffs)
+		})
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	checkoutEvents, err := loadCheckoutEvents("tests/scenarios/all_suspect_scenario.json")
+	require.NoError(t, err)
+	require.False(t, checkoutEvents.Expected.isEmpty())
+	assert.NotNil(t, checkoutEvents.Expected.PaymentStart)
+	assert.NotNil(t, checkoutEvents.Expected.FinalState)
+}
@@ -12,6 +12,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 
 	"github.com/gorilla/mux"
 )
@@ -24,6 +25,7 @@ type ProductInfo struct {
 	MinWeight    float64 `json:"min_weight"`
 	MaxWeight    float64 `json:"max_weight"`
 	RfidEligible bool    `json:"rfid_eligible"`
+	Price        float64 `json:"price"`
 }
 
 func main() {
@@ -68,6 +70,19 @@ func weightLookupHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(productInfo)
 }
 
+func productsHandler(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+
+	productInfos := make([]ProductInfo, 0, len(products))
+	for barcode, productInfo := range products {
+		productInfo.Barcode = barcode
+		productInfos = append(productInfos, productInfo)
+	}
+	sort.Slice(productInfos, func(i, j int) bool { return productInfos[i].Barcode < productInfos[j].Barcode })
+
+	json.NewEncoder(w).Encode(productInfos)
+}
+
 func initializeServer() {
 
 	port := os.Getenv("APP_PORT")
@@ -79,6 +94,7 @@ func initializeServer() {
 	router := mux.NewRouter()
 
 	router.HandleFunc("/weight/{product_id}", weightLookupHandler).Methods("GET")
+	router.HandleFunc("/products", productsHandler).Methods("GET")
 
 	log.Printf("Product Lookup started listening on port: %s\n", port)
 
@@ -124,6 +140,6 @@ func setUpLocalDatabase(localDatabase string) {
 
 	//convert slice of productInfos to map[productID] = WeightInfo
 	for _, product := range productInfos {
-		products[product.Barcode] = ProductInfo{Name: product.Name, MinWeight: product.MinWeight, MaxWeight: product.MaxWeight, RfidEligible: product.RfidEligible}
+		products[product.Barcode] = ProductInfo{Name: product.Name, MinWeight: product.MinWeight, MaxWeight: product.MaxWeight, RfidEligible: product.RfidEligible, Price: product.Price}
 	}
 }